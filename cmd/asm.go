@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/spf13/cobra"
+)
+
+// asmCmd assembles CHIP-8 assembly into a ROM, the other way around from disasm
+var asmCmd = &cobra.Command{
+	Use:   "asm `path/to/input.asm` `path/to/output.ch8`",
+	Short: "Assemble CHIP-8 assembly into a ROM",
+	Long:  "Run `chippy asm game.asm game.ch8` to assemble game.asm, written with mnemonics like `LD V0, 0x10` and `DRW V0, V1, 5`, into a ROM",
+	Args:  cobra.ExactArgs(2),
+	Run:   runAsm,
+}
+
+func runAsm(cmd *cobra.Command, args []string) {
+	m, err := chip8.ParseMode(mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalf("\nerror assembling: %v\n", err)
+	}
+	defer src.Close()
+
+	rom, err := chip8.Assemble(src, m)
+	if err != nil {
+		// Every line with an error is printed, each on its own line, like a compiler would
+		fmt.Fprintf(os.Stderr, "%s:\n%v\n", args[0], err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(args[1], rom, 0o644); err != nil {
+		log.Fatalf("\nerror writing rom: %v\n", err)
+	}
+	fmt.Printf("assembled %d bytes to %s\n", len(rom), args[1])
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/spf13/cobra"
+)
+
+// capabilitiesCmd reports what this build of chippy supports, for frontends building their settings
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities",
+	Short: "List the modes, quirks, display backends, and audio this chippy supports",
+	Long:  "Run `chippy capabilities --json` to get chippy's supported features in a machine readable form",
+	Args:  cobra.NoArgs,
+	Run:   runCapabilities,
+}
+
+// capabilities is the report printed by the capabilities command
+type capabilities struct {
+	Version   string   `json:"version"`
+	Modes     []string `json:"modes"`
+	Quirks    []string `json:"quirks"`
+	Backends  []string `json:"backends"`
+	Waveforms []string `json:"waveforms"`
+}
+
+func runCapabilities(cmd *cobra.Command, args []string) {
+	if err := writeCapabilities(os.Stdout, capabilitiesJSON); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeCapabilities writes the capabilities report to w, as JSON or for people to read
+func writeCapabilities(w io.Writer, asJSON bool) error {
+	c := capabilities{
+		Version:   currentReleaseVersion,
+		Quirks:    quirkNames(),
+		Backends:  backends,
+		Waveforms: []string{"sine"},
+	}
+	for _, m := range chip8.Modes {
+		c.Modes = append(c.Modes, m.String())
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+
+	fmt.Fprintf(w, "version:   %s\n", c.Version)
+	fmt.Fprintf(w, "modes:     %s\n", strings.Join(c.Modes, ", "))
+	fmt.Fprintf(w, "quirks:    %s\n", strings.Join(c.Quirks, ", "))
+	fmt.Fprintf(w, "backends:  %s\n", strings.Join(c.Backends, ", "))
+	_, err := fmt.Fprintf(w, "waveforms: %s\n", strings.Join(c.Waveforms, ", "))
+	return err
+}
+
+// quirkNames lists the --quirk-* flags, by the names frontends pass them to chippy with
+func quirkNames() []string {
+	names := make([]string, 0, len(quirkFlags))
+	for _, qf := range quirkFlags {
+		names = append(names, qf.name)
+	}
+	return names
+}
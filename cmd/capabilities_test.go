@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+)
+
+func TestCapabilitiesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCapabilities(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &top); err != nil {
+		t.Fatalf("capabilities aren't JSON: %v", err)
+	}
+	keys := make([]string, 0, len(top))
+	for k := range top {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if want := []string{"backends", "modes", "quirks", "version", "waveforms"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("top level keys = %v, want %v", keys, want)
+	}
+
+	var c capabilities
+	if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"chip8", "schip", "xochip"}; !reflect.DeepEqual(c.Modes, want) {
+		t.Errorf("modes = %v, want %v", c.Modes, want)
+	}
+	if want := []string{"window", "terminal"}; !reflect.DeepEqual(c.Backends, want) {
+		t.Errorf("backends = %v, want %v", c.Backends, want)
+	}
+	if len(c.Quirks) != len(quirkFlags) {
+		t.Errorf("%d quirks listed, but there are %d quirk flags", len(c.Quirks), len(quirkFlags))
+	}
+	for _, q := range c.Quirks {
+		if runCmd.Flags().Lookup(q) == nil {
+			t.Errorf("quirk %q isn't a flag run takes", q)
+		}
+	}
+
+	// Every mode listed is one the VM decodes, with the opcodes its dialect added and no more
+	exclusive := map[chip8.Mode][]uint16{
+		chip8.ModeSChip:  {0x00FF, 0xF330},
+		chip8.ModeXOChip: {0xF000, 0xF002},
+	}
+	for i, name := range c.Modes {
+		m, err := chip8.ParseMode(name)
+		if err != nil {
+			t.Errorf("mode %q doesn't parse: %v", name, err)
+			continue
+		}
+		for added, ops := range exclusive {
+			for _, op := range ops {
+				if _, ok := chip8.Mnemonic(op, m); ok != (added <= m) {
+					t.Errorf("%s decodes %04X = %v, want %v", name, op, ok, added <= m)
+				}
+			}
+		}
+		if m != chip8.Modes[i] {
+			t.Errorf("mode %q parses as %v, want %v", name, m, chip8.Modes[i])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+	"github.com/bradford-hamilton/chippy/roms"
+	"github.com/spf13/cobra"
+)
+
+// demosCmd lists the ROMs bundled with chippy
+var demosCmd = &cobra.Command{
+	Use:   "demos",
+	Short: "List the ROMs bundled with chippy, for run --demo",
+	Long:  "Run `chippy demos` to see the ROMs built into chippy, then `chippy run --demo ibm` to play one",
+	Args:  cobra.NoArgs,
+	Run:   runDemos,
+}
+
+func runDemos(cmd *cobra.Command, args []string) {
+	for _, name := range roms.Names() {
+		rom, _, err := roms.Open(name)
+		if err != nil {
+			log.Fatalf("\nerror loading demo: %v\n", err)
+		}
+		title := ""
+		if e, ok := romdb.Lookup(rom); ok {
+			title = e.Title
+		}
+		fmt.Printf("%-16s %s\n", name, title)
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/spf13/cobra"
+)
+
+// disasmCmd disassembles a ROM, or every ROM under a directory
+var disasmCmd = &cobra.Command{
+	Use:   "disasm `path/to/rom|path/to/roms`",
+	Short: "Disassemble a ROM, or a whole directory of ROMs",
+	Long:  "Run `chippy disasm rom.ch8` to print a ROM's disassembly, or `chippy disasm ./roms -o ./disasm` to write an .asm file for every .ch8 under ./roms",
+	Args:  cobra.ExactArgs(1),
+	Run:   runDisasm,
+}
+
+func runDisasm(cmd *cobra.Command, args []string) {
+	m, err := chip8.ParseMode(mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info, err := os.Stat(args[0])
+	if err != nil {
+		log.Fatalf("\nerror disassembling: %v\n", err)
+	}
+	if !info.IsDir() {
+		rom, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("\nerror loading rom: %v\n", err)
+		}
+		if _, err := writeDisasm(os.Stdout, rom, m); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if !disasmBatch(args[0], disasmOut, m) {
+		os.Exit(1)
+	}
+}
+
+// disasmBatch writes the disassembly of every ROM under dir to an .asm file of the same name, mirroring
+// the directory layout under out (or next to the ROM when out is empty). It reports ROMs that didn't decode
+// cleanly as it goes, and returns false if any couldn't be disassembled at all.
+func disasmBatch(dir, out string, m chip8.Mode) bool {
+	ok := true
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), romExt) {
+			return nil
+		}
+
+		dst := strings.TrimSuffix(path, filepath.Ext(path)) + ".asm"
+		if out != "" {
+			rel, err := filepath.Rel(dir, dst)
+			if err != nil {
+				return err
+			}
+			dst = filepath.Join(out, rel)
+		}
+
+		unknown, err := disasmFile(path, dst, m)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			ok = false
+		case unknown > 0:
+			fmt.Printf("%s: %d words didn't decode as instructions\n", path, unknown)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error walking %s: %v\n", dir, err)
+		return false
+	}
+
+	fmt.Printf("disassembled %d ROMs\n", count)
+	return ok
+}
+
+// disasmFile disassembles the ROM at src into dst and returns how many words didn't decode
+func disasmFile(src, dst string, m chip8.Mode) (int, error) {
+	rom, err := os.ReadFile(src)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+
+	unknown, err := writeDisasm(f, rom, m)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return unknown, err
+}
+
+// writeDisasm writes a ROM's disassembly to w, one instruction per line, and returns how many words didn't decode
+func writeDisasm(w io.Writer, rom []byte, m chip8.Mode) (int, error) {
+	bw := bufio.NewWriter(w)
+	unknown := 0
+	for _, in := range chip8.Disassemble(rom, m) {
+		if !in.Known {
+			unknown++
+		}
+		fmt.Fprintln(bw, in)
+	}
+	return unknown, bw.Flush()
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+)
+
+func TestDisasmBatch(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	files := map[string][]byte{
+		"clear.ch8":    {0x00, 0xE0, 0x12, 0x02},
+		"games/hi.CH8": {0x60, 0x01, 0xFF},
+		"games/notes":  []byte("not a rom"),
+		"readme.txt":   []byte("hello"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !disasmBatch(dir, out, chip8.ModeChip8) {
+		t.Fatal("disasmBatch failed")
+	}
+
+	want := map[string]string{
+		"clear.asm":    "0x200: 00E0 clear\n0x202: 1NNN jump 0x202\n",
+		"games/hi.asm": "0x200: 6XNN V0=0x01\n0x202: 0xFF\n",
+	}
+	var got []string
+	err := filepath.WalkDir(out, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(out, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("wrote %v, want %d .asm files", got, len(want))
+	}
+	for name, asm := range want {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Errorf("%s wasn't written: %v", name, err)
+			continue
+		}
+		if string(data) != asm {
+			t.Errorf("%s = %q, want %q", name, data, asm)
+		}
+	}
+}
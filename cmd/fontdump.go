@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+	"github.com/spf13/cobra"
+)
+
+// fontdumpCmd prints a font set as sprite art so the glyphs can be checked by eye
+var fontdumpCmd = &cobra.Command{
+	Use:   "fontdump",
+	Short: "Print a font set as sprite art",
+	Long:  "Run `chippy fontdump` to see the 0-F glyphs ROMs draw with FX29, or `chippy fontdump --font vip` to see another font's",
+	Args:  cobra.NoArgs,
+	Run:   runFontdump,
+}
+
+// glyphsPerLine is how many glyphs fontdump prints side by side
+const glyphsPerLine = 8
+
+func runFontdump(cmd *cobra.Command, args []string) {
+	font, _, err := loadFont(fontName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if font == nil {
+		font = &pixel.FontSet
+	}
+	writeFont(os.Stdout, *font)
+}
+
+// loadFont resolves --font, the name of a built in font set or the path to a font file, into the glyphs to
+// replace the standard ones with. A glyph set the font doesn't give is nil, as are both when none is picked.
+func loadFont(nameOrPath string) (*[80]byte, *[160]byte, error) {
+	if nameOrPath == "" {
+		return nil, nil, nil
+	}
+	font, err := pixel.LookupFont(nameOrPath)
+	if err == nil {
+		return font, nil, nil
+	}
+	data, rerr := os.ReadFile(nameOrPath)
+	if rerr != nil {
+		// A bare word is more likely a misspelled font name than a missing file
+		if errors.Is(rerr, fs.ErrNotExist) && filepath.Base(nameOrPath) == nameOrPath {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("error reading font: %v", rerr)
+	}
+	small, large, err := pixel.ParseFont(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", nameOrPath, err)
+	}
+	return small, large, nil
+}
+
+// writeFont draws every glyph of the font set to w, labeled with its hex digit
+func writeFont(w io.Writer, font [80]byte) {
+	for first := 0; first < 16; first += glyphsPerLine {
+		labels := make([]string, 0, glyphsPerLine)
+		for g := first; g < first+glyphsPerLine; g++ {
+			labels = append(labels, fmt.Sprintf("%-4X", g))
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(labels, "  "), " "))
+
+		for row := 0; row < 5; row++ {
+			art := make([]string, 0, glyphsPerLine)
+			for g := first; g < first+glyphsPerLine; g++ {
+				art = append(art, glyphRow(font[g*5+row]))
+			}
+			fmt.Fprintln(w, strings.Join(art, "  "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// glyphRow draws one row of a glyph. Glyphs are 4 pixels wide, stored in the high nibble of each byte.
+func glyphRow(b byte) string {
+	var sb strings.Builder
+	for bit := 7; bit >= 4; bit-- {
+		if b&(1<<bit) != 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
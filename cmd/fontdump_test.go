@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+)
+
+func TestWriteFont(t *testing.T) {
+	want := `0     1     2     3     4     5     6     7
+####  ..#.  ####  ####  #..#  ####  ####  ####
+#..#  .##.  ...#  ...#  #..#  #...  #...  ...#
+#..#  ..#.  ####  ####  ####  ####  ####  ..#.
+#..#  ..#.  #...  ...#  ...#  ...#  #..#  .#..
+####  .###  ####  ####  ...#  ####  ####  .#..
+
+8     9     A     B     C     D     E     F
+####  ####  ####  ###.  ####  ###.  ####  ####
+#..#  #..#  #..#  #..#  #...  #..#  #...  #...
+####  ####  ####  ###.  #...  #..#  ####  ####
+#..#  ...#  #..#  #..#  #...  #..#  #...  #...
+####  ####  #..#  ###.  ####  ###.  ####  #...
+
+`
+	var sb strings.Builder
+	writeFont(&sb, pixel.FontSet)
+	if got := sb.String(); got != want {
+		t.Errorf("writeFont(FontSet) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLoadFont(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	small, large, both, bad := write("small.font", 80), write("large.font", 160), write("both.font", 240), write("bad.font", 81)
+
+	tests := []struct {
+		name      string
+		font      string
+		wantSmall bool
+		wantLarge bool
+		wantErr   string
+	}{
+		{name: "none picked", font: ""},
+		{name: "built in", font: "vip", wantSmall: true},
+		{name: "small glyphs file", font: small, wantSmall: true},
+		{name: "large glyphs file", font: large, wantLarge: true},
+		{name: "both glyph sets file", font: both, wantSmall: true, wantLarge: true},
+		{name: "wrong size file", font: bad, wantErr: "font is 81 bytes"},
+		{name: "misspelled name", font: "vipp", wantErr: `no font named "vipp"`},
+		{name: "missing file", font: filepath.Join(dir, "missing.font"), wantErr: "error reading font"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, l, err := loadFont(tt.font)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadFont(%q) error = %v, want one containing %q", tt.font, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadFont(%q): %v", tt.font, err)
+			}
+			if (s != nil) != tt.wantSmall || (l != nil) != tt.wantLarge {
+				t.Errorf("loadFont(%q) gave small %v, large %v, want %v, %v", tt.font, s != nil, l != nil, tt.wantSmall, tt.wantLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+	"github.com/spf13/cobra"
+)
+
+// infoCmd prints facts about a ROM without running it
+var infoCmd = &cobra.Command{
+	Use:   "info `path/to/rom`",
+	Short: "Print a ROM's size, hash, opcodes, and the dialect it looks written for",
+	Long:  "Run `chippy info rom.ch8` to see whether a ROM fits in memory, its SHA-1, how often each opcode appears in it, and a guess at whether it needs --mode=schip or --mode=xochip",
+	Args:  cobra.ExactArgs(1),
+	Run:   runInfo,
+}
+
+// The standard 4K of memory, and the most a VM can have
+const (
+	standardMemorySize = 0x1000
+	largestMemorySize  = 0x10000
+)
+
+func runInfo(cmd *cobra.Command, args []string) {
+	rom, name, err := openROM(args[0])
+	if err != nil {
+		log.Fatalf("\nerror loading rom: %v\n", err)
+	}
+	writeInfo(os.Stdout, rom, name)
+}
+
+// writeInfo writes one fact about the ROM a line, the name of the fact followed by its value, so the output
+// can be picked apart with awk or grep. Counts of the opcodes the ROM can run come last, one
+// "opcode PATTERN COUNT" line each.
+func writeInfo(w io.Writer, rom []byte, name string) {
+	field := func(key, format string, a ...any) {
+		fmt.Fprintf(w, "%-8s %s\n", key, fmt.Sprintf(format, a...))
+	}
+
+	field("name", "%s", filepath.Base(name))
+	if e, ok := romdb.Lookup(rom); ok {
+		field("title", "%s", e.Title)
+	}
+	field("size", "%d bytes", len(rom))
+	field("sha1", "%s", romdb.Hash(rom))
+	switch {
+	case len(rom) <= chip8.MaxROMSize(standardMemorySize, chip8.DefaultStartAddress):
+		field("fits", "yes, in the standard %d bytes of memory", standardMemorySize)
+	case len(rom) <= chip8.MaxROMSize(largestMemorySize, chip8.DefaultStartAddress):
+		// Memory also holds everything below the ROM, which takes the same space whatever the memory size
+		reserved := standardMemorySize - chip8.MaxROMSize(standardMemorySize, chip8.DefaultStartAddress)
+		field("fits", "with --memory-size=%d or more", len(rom)+reserved)
+	default:
+		field("fits", "no, the most a ROM can be is %d bytes", chip8.MaxROMSize(largestMemorySize, chip8.DefaultStartAddress))
+	}
+
+	m, extended := guessMode(rom)
+	if len(extended) == 0 {
+		field("mode", "%s", m)
+	} else {
+		field("mode", "%s, it uses %s", m, strings.Join(extended, " "))
+	}
+
+	counts := map[string]int{}
+	for _, opcode := range reachableOpcodes(rom, m) {
+		counts[opcodePattern(opcode, m)]++
+	}
+	if q := quirkSensitive(counts); len(q) > 0 {
+		field("quirks", "%s", strings.Join(q, " "))
+	}
+
+	patterns := make([]string, 0, len(counts))
+	for p := range counts {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	for _, p := range patterns {
+		fmt.Fprintf(w, "opcode   %-7s %d\n", p, counts[p])
+	}
+}
+
+// quirkOpcodes are the opcodes whose behavior a quirk flag changes
+var quirkOpcodes = []struct{ flag, pattern string }{
+	{"--quirk-shift", "8XY6"},
+	{"--quirk-shift", "8XYE"},
+	{"--quirk-load-store", "FX55"},
+	{"--quirk-load-store", "FX65"},
+	{"--quirk-jump", "BNNN"},
+	{"--quirk-vf-reset", "8XY1"},
+	{"--quirk-vf-reset", "8XY2"},
+	{"--quirk-vf-reset", "8XY3"},
+	{"--quirk-wrap", "DXYN"},
+	{"--quirk-wrap-x", "DXYN"},
+	{"--quirk-display-wait", "DXYN"},
+}
+
+// quirkSensitive lists the quirk flags that could change how a ROM with the given opcode counts runs
+func quirkSensitive(counts map[string]int) []string {
+	var flags []string
+	for _, q := range quirkOpcodes {
+		if counts[q.pattern] > 0 && (len(flags) == 0 || flags[len(flags)-1] != q.flag) {
+			flags = append(flags, q.flag)
+		}
+	}
+	return flags
+}
+
+// guessMode picks the narrowest dialect that decodes every opcode the ROM runs, along with the extended
+// opcodes that pushed it past plain CHIP-8. It's a guess, since code reached through BNNN is never seen.
+func guessMode(rom []byte) (chip8.Mode, []string) {
+	m := chip8.ModeChip8
+	seen := map[string]bool{}
+	var extended []string
+	for _, opcode := range reachableOpcodes(rom, chip8.ModeXOChip) {
+		p := opcodePattern(opcode, chip8.ModeXOChip)
+		if p == "unknown" || p == opcodePattern(opcode, chip8.ModeChip8) {
+			continue
+		}
+		if opcodePattern(opcode, chip8.ModeSChip) == p {
+			m = max(m, chip8.ModeSChip)
+		} else {
+			m = chip8.ModeXOChip
+		}
+		if !seen[p] {
+			seen[p] = true
+			extended = append(extended, p)
+		}
+	}
+	sort.Strings(extended)
+	return m, extended
+}
+
+// reachableOpcodes follows the ROM's control flow from 0x200 the way the VM would run it, through jumps,
+// calls, and both outcomes of skips, and returns every opcode it reaches once. Sprite data is never run, so
+// unlike a straight disassembly it isn't mistaken for code. A path ends at a return, an exit, an unknown
+// opcode, or BNNN, whose target depends on V0.
+func reachableOpcodes(rom []byte, m chip8.Mode) []uint16 {
+	var opcodes []uint16
+	visited := map[int]bool{}
+	paths := []int{0x200}
+	for len(paths) > 0 {
+		addr := paths[len(paths)-1]
+		paths = paths[:len(paths)-1]
+
+		for !visited[addr] && addr >= 0x200 && addr-0x200+1 < len(rom) {
+			visited[addr] = true
+			opcode := uint16(rom[addr-0x200])<<8 | uint16(rom[addr-0x200+1])
+			opcodes = append(opcodes, opcode)
+			if _, ok := chip8.Mnemonic(opcode, m); !ok {
+				break
+			}
+
+			next := addr + 2
+			if opcode == 0xF000 && m == chip8.ModeXOChip {
+				next += 2
+			}
+			switch {
+			case opcode&0xF000 == 0x1000:
+				next = int(opcode & 0x0FFF)
+			case opcode&0xF000 == 0x2000:
+				paths = append(paths, int(opcode&0x0FFF))
+			case opcode == 0x00EE, opcode == 0x00FD, opcode&0xF000 == 0xB000:
+				next = -1
+			case isSkip(opcode):
+				paths = append(paths, next+2)
+			}
+			addr = next
+		}
+	}
+	return opcodes
+}
+
+// isSkip reports whether an opcode conditionally skips the instruction after it
+func isSkip(opcode uint16) bool {
+	switch opcode & 0xF000 {
+	case 0x3000, 0x4000, 0x5000, 0x9000:
+		return true
+	case 0xE000:
+		return opcode&0x00FF == 0x9E || opcode&0x00FF == 0xA1
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+)
+
+// inspection is the JSON served at /state, with memory and the screen hex encoded, one byte to a pixel
+type inspection struct {
+	PC         uint16   `json:"pc"`
+	I          uint16   `json:"i"`
+	SP         uint16   `json:"sp"`
+	V          [16]byte `json:"v"`
+	Stack      []uint16 `json:"stack"`
+	DelayTimer byte     `json:"delayTimer"`
+	SoundTimer byte     `json:"soundTimer"`
+	Cycles     uint64   `json:"cycles"`
+	Memory     string   `json:"memory"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	Gfx        string   `json:"gfx"`
+}
+
+// serveInspect serves the VM's live state as JSON at addr/state until chippy exits
+func serveInspect(addr string, vm *chip8.VM) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(newInspection(vm.Snapshot())); err != nil {
+			log.Printf("error serving state: %v\n", err)
+		}
+	})
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("error serving state: %v\n", err)
+		}
+	}()
+}
+
+func newInspection(s chip8.State) inspection {
+	return inspection{
+		PC:         s.PC,
+		I:          s.I,
+		SP:         s.SP,
+		V:          s.V,
+		Stack:      s.Stack,
+		DelayTimer: s.DelayTimer,
+		SoundTimer: s.SoundTimer,
+		Cycles:     s.Cycles,
+		Memory:     hex.EncodeToString(s.Memory),
+		Width:      s.Width,
+		Height:     s.Height,
+		Gfx:        hex.EncodeToString(s.Gfx),
+	}
+}
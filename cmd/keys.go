@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/spf13/cobra"
+)
+
+// keysCmd prints the keymap as the CHIP-8 keypad, or renders it to a PNG for documentation
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Show which keyboard keys are bound to the CHIP-8 keypad",
+	Long:  "Run `chippy keys` to print the keymap, or `chippy keys --image keys.png` to render it as an image",
+	Args:  cobra.NoArgs,
+	Run:   runKeys,
+}
+
+func runKeys(cmd *cobra.Command, args []string) {
+	km, err := loadKeyMap(keymapPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if keysImage == "" {
+		for _, row := range pixel.KeypadLayout {
+			for _, key := range row {
+				fmt.Printf("%X: %-6s", key, km[key])
+			}
+			fmt.Println()
+		}
+		return
+	}
+
+	f, err := os.Create(keysImage)
+	if err != nil {
+		log.Fatalf("\nerror creating keypad image: %v\n", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, pixel.KeypadImage(km)); err != nil {
+		log.Fatalf("\nerror writing keypad image: %v\n", err)
+	}
+}
+
+// loadKeyMap loads the keymap at path, or returns the default keymap when path is empty
+func loadKeyMap(path string) (map[uint16]pixelgl.Button, error) {
+	if path == "" {
+		return pixel.DefaultKeyMap(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening keymap: %v", err)
+	}
+	defer f.Close()
+	return pixel.LoadKeyMap(f)
+}
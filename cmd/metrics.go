@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+)
+
+// metricsHandler serves a VM's stats in the Prometheus text exposition format
+type metricsHandler struct {
+	stats *chip8.Stats
+}
+
+func newMetricsHandler(stats *chip8.Stats) *metricsHandler {
+	return &metricsHandler{stats: stats}
+}
+
+// serveMetrics serves the VM's metrics at addr/metrics until chippy exits
+func serveMetrics(addr string, stats *chip8.Stats) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", newMetricsHandler(stats))
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("error serving metrics: %v\n", err)
+		}
+	}()
+}
+
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	h.write(w, time.Now())
+}
+
+// write renders every metric as of now
+func (h *metricsHandler) write(w io.Writer, now time.Time) {
+	writeMetric(w, "chippy_cycles_total", "counter", "Clock cycles run, idle ones included.", h.stats.Cycles.Load())
+	writeMetric(w, "chippy_instructions_total", "counter", "Instructions executed.", h.stats.Instructions.Load())
+	writeMetric(w, "chippy_instructions_per_second", "gauge", "Instructions executed per second over the last few seconds.", h.stats.IPS(now))
+	writeMetric(w, "chippy_frames_total", "counter", "Frames drawn.", h.stats.Frames.Load())
+	writeMetric(w, "chippy_draws_total", "counter", "Sprites drawn.", h.stats.Draws.Load())
+	writeMetric(w, "chippy_beeps_total", "counter", "Beeps played.", h.stats.Beeps.Load())
+	writeMetric(w, "chippy_uptime_seconds", "gauge", "Seconds since the VM started.", now.Sub(h.stats.Started).Seconds())
+}
+
+func writeMetric(w io.Writer, name, kind, help string, value any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+)
+
+// scrapeMetrics fetches the metrics from url, by name
+func scrapeMetrics(t *testing.T, url string) map[string]float64 {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+
+	metrics := make(map[string]float64)
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			t.Fatalf("malformed metric line %q", line)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("metric %s: %v", name, err)
+		}
+		metrics[name] = v
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return metrics
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	stats := &chip8.Stats{Started: time.Now()}
+	srv := httptest.NewServer(newMetricsHandler(stats))
+	defer srv.Close()
+
+	before := scrapeMetrics(t, srv.URL)
+	names := []string{
+		"chippy_cycles_total",
+		"chippy_instructions_total",
+		"chippy_instructions_per_second",
+		"chippy_frames_total",
+		"chippy_draws_total",
+		"chippy_beeps_total",
+		"chippy_uptime_seconds",
+	}
+	for _, name := range names {
+		if _, ok := before[name]; !ok {
+			t.Errorf("missing metric %s", name)
+		}
+	}
+	if len(before) != len(names) {
+		t.Errorf("got %d metrics, want %d: %v", len(before), len(names), before)
+	}
+
+	stats.Cycles.Add(10)
+	stats.Instructions.Add(8)
+	stats.Frames.Add(1)
+	stats.Draws.Add(2)
+	stats.Beeps.Add(1)
+
+	after := scrapeMetrics(t, srv.URL)
+	for _, name := range names {
+		if !strings.HasSuffix(name, "_total") {
+			continue
+		}
+		if after[name] <= before[name] {
+			t.Errorf("%s went from %v to %v, want it to increase", name, before[name], after[name])
+		}
+	}
+	if after["chippy_uptime_seconds"] < before["chippy_uptime_seconds"] {
+		t.Errorf("chippy_uptime_seconds went back from %v to %v", before["chippy_uptime_seconds"], after["chippy_uptime_seconds"])
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+)
+
+// readPlaylist reads a playlist file: a ROM on each line, with blank lines and lines starting with # skipped.
+// Relative paths are relative to the playlist, not the current directory.
+func readPlaylist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var paths []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !isURL(line) && !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+		paths = append(paths, line)
+	}
+	return paths, s.Err()
+}
+
+// playlistNext hands the VM the ROMs at paths one at a time, opening each as it's asked for. ROMs that
+// can't be opened are returned as errors for the VM to log and skip. Each is titled after its name unless
+// --title is set.
+func playlistNext(paths []string) func() (chip8.PlaylistROM, bool, error) {
+	return func() (chip8.PlaylistROM, bool, error) {
+		if len(paths) == 0 {
+			return chip8.PlaylistROM{}, false, nil
+		}
+		pathToROM := paths[0]
+		paths = paths[1:]
+		rom, name, err := openROM(pathToROM)
+		if err != nil {
+			return chip8.PlaylistROM{}, true, fmt.Errorf("%s: %v", pathToROM, err)
+		}
+		t := title
+		if t == "" {
+			t = pixel.WindowTitle(name)
+		}
+		return chip8.PlaylistROM{ROM: rom, Title: t, StatePath: statePath(pathToROM, name)}, true, nil
+	}
+}
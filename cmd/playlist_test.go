@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlaylistNextReportsUnopenableROMs(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.ch8")
+	if err := os.WriteFile(good, []byte{0x00, 0xE0}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.ch8")
+
+	next := playlistNext([]string{missing, good})
+	if _, ok, err := next(); !ok || err == nil || !strings.Contains(err.Error(), missing) {
+		t.Fatalf("missing rom: got ok=%v err=%v, want an error naming %s", ok, err, missing)
+	}
+	rom, ok, err := next()
+	if !ok || err != nil {
+		t.Fatalf("good rom: got ok=%v err=%v", ok, err)
+	}
+	if string(rom.ROM) != "\x00\xE0" {
+		t.Errorf("good rom: got % X", rom.ROM)
+	}
+	if _, ok, _ := next(); ok {
+		t.Error("expected the playlist to be finished")
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+	"github.com/spf13/cobra"
+)
+
+// A profiles file is a JSON object of named profiles. Each profile sets run flags by name, and the optional
+// sha1 picks the profile automatically for the ROM with that SHA-1:
+//
+//	{
+//	  "invaders": {
+//	    "sha1": "f100197f0f2f05b4f3c8c31ab9c2c3930d3e9571",
+//	    "ips": 1000,
+//	    "quirk-shift": true,
+//	    "fg": "#33FF33"
+//	  }
+//	}
+type profile map[string]any
+
+// profileHashKey is the profile setting matched against the ROM's SHA-1 rather than set as a flag
+const profileHashKey = "sha1"
+
+// defaultProfilesPath is where profiles are read from without --profiles, empty when there's no config directory
+func defaultProfilesPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chippy", "profiles.json")
+}
+
+// applyProfile sets the flags of the profile named by --profile, or else the one matching the ROM's SHA-1,
+// leaving alone any flag given on the command line. A missing profiles file is only an error when it was
+// asked for with --profiles or --profile.
+func applyProfile(cmd *cobra.Command, rom []byte) error {
+	path := profilesPath
+	if path == "" {
+		path = defaultProfilesPath()
+	}
+	profiles, err := loadProfiles(path)
+	if errors.Is(err, fs.ErrNotExist) && profilesPath == "" && profileName == "" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading profiles: %v", err)
+	}
+
+	name := profileName
+	if name == "" {
+		if name = matchProfile(profiles, romdb.Hash(rom)); name == "" {
+			return nil
+		}
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile named %q in %s", name, path)
+	}
+
+	for _, setting := range sortedSettings(p) {
+		if setting == profileHashKey || cmd.Flags().Changed(setting) {
+			continue
+		}
+		// --refresh is the old name of --ips and sets the same value
+		if setting == "ips" && cmd.Flags().Changed("refresh") {
+			continue
+		}
+		if cmd.Flags().Lookup(setting) == nil {
+			return fmt.Errorf("profile %q: unknown setting %q", name, setting)
+		}
+		if err := cmd.Flags().Set(setting, fmt.Sprint(p[setting])); err != nil {
+			return fmt.Errorf("profile %q: invalid %s: %v", name, setting, err)
+		}
+	}
+	fmt.Printf("using profile %q\n", name)
+	return nil
+}
+
+func loadProfiles(path string) (map[string]profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var profiles map[string]profile
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return profiles, nil
+}
+
+// matchProfile returns the name of the profile for the ROM with the given SHA-1, or "" if none is for it.
+// Names are checked in order so the same profile wins every time if two claim the same ROM.
+func matchProfile(profiles map[string]profile, hash string) string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if h, ok := profiles[name][profileHashKey].(string); ok && strings.EqualFold(h, hash) {
+			return name
+		}
+	}
+	return ""
+}
+
+func sortedSettings(p profile) []string {
+	settings := make([]string, 0, len(p))
+	for s := range p {
+		settings = append(settings, s)
+	}
+	sort.Strings(settings)
+	return settings
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd runs a ROM headlessly and writes what happened to a single HTML page
+var reportCmd = &cobra.Command{
+	Use:   "report `path/to/rom`",
+	Short: "Run a ROM headlessly and write an HTML report of the run",
+	Long:  "Run `chippy report rom.ch8 --cycles 10000 -o report.html` for a shareable page with the ROM's disassembly, final screen, registers, memory, opcode coverage, and the last instructions executed",
+	Args:  cobra.ExactArgs(1),
+	Run:   runReport,
+}
+
+// reportTraceRows is how many of the last executed instructions the report's trace table shows
+const reportTraceRows = 100
+
+// report is everything the report template renders
+type report struct {
+	ROM          string
+	Mode         string
+	Cycles       uint64
+	Instructions uint64
+	FrameHash    string
+	Screen       template.URL
+	State        chip8.State
+	Memory       string
+	Covered      int
+	Decoded      int
+	Opcodes      []opcodeCount
+	Disassembly  []disasmLine
+	Trace        []traceLine
+}
+
+type opcodeCount struct {
+	Pattern string
+	Count   uint64
+}
+
+type disasmLine struct {
+	chip8.Instruction
+	Hits uint64
+}
+
+type traceLine struct {
+	chip8.StepResult
+	Mnemonic string
+	Changes  string
+}
+
+func runReport(cmd *cobra.Command, args []string) {
+	if cycles == 0 {
+		log.Fatal("report needs --cycles to know how long to run the ROM for")
+	}
+
+	rom, name, err := openROM(args[0])
+	if err != nil {
+		log.Fatalf("\nerror loading rom: %v\n", err)
+	}
+	m, err := chip8.ParseMode(mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sp, err := chip8.ParseStackPolicy(stackPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cycleAccurate && !clockSpeedGiven(cmd) {
+		refreshRate = chip8.VIPClockSpeed
+	}
+
+	hits := map[uint16]uint64{}
+	opcodes := map[string]uint64{}
+	var trace []chip8.StepResult
+	vm, err := chip8.NewVM(bytes.NewReader(rom), refreshRate, chip8.Config{
+		IdleWindow:    idleWindow,
+		MemorySize:    memorySize,
+		StackDepth:    stackDepth,
+		StackPolicy:   sp,
+		CycleAccurate: cycleAccurate,
+		Mode:          m,
+		Quirks:        quirks,
+		Headless:      true,
+		MaxCycles:     cycles,
+		Seed:          seed,
+		OnStep: func(res chip8.StepResult) {
+			hits[res.PC]++
+			opcodes[opcodePattern(res.Opcode, m)]++
+			if len(trace) == reportTraceRows {
+				trace = trace[1:]
+			}
+			trace = append(trace, res)
+		},
+	})
+	if err != nil {
+		log.Fatalf("\nerror creating a new chip-8 VM: %v\n", err)
+	}
+	vm.Run()
+
+	var screen bytes.Buffer
+	if err := png.Encode(&screen, vm.Screenshot(screenshotScale)); err != nil {
+		log.Fatalf("\nerror rendering the final screen: %v\n", err)
+	}
+
+	r := report{
+		ROM:          filepath.Base(name),
+		Mode:         m.String(),
+		Cycles:       vm.Stats().Cycles.Load(),
+		Instructions: vm.Stats().Instructions.Load(),
+		FrameHash:    vm.FrameHash(),
+		Screen:       template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(screen.Bytes())),
+		State:        vm.Snapshot(),
+	}
+	r.Memory = hexDump(r.State.Memory)
+	for _, in := range chip8.Disassemble(rom, m) {
+		if in.Known {
+			r.Decoded++
+			if hits[in.Addr] > 0 {
+				r.Covered++
+			}
+		}
+		r.Disassembly = append(r.Disassembly, disasmLine{Instruction: in, Hits: hits[in.Addr]})
+	}
+	for pattern, n := range opcodes {
+		r.Opcodes = append(r.Opcodes, opcodeCount{Pattern: pattern, Count: n})
+	}
+	sort.Slice(r.Opcodes, func(i, j int) bool { return r.Opcodes[i].Pattern < r.Opcodes[j].Pattern })
+	for _, res := range trace {
+		mn, _ := chip8.Mnemonic(res.Opcode, m)
+		r.Trace = append(r.Trace, traceLine{StepResult: res, Mnemonic: mn, Changes: res.Changes()})
+	}
+
+	f, err := os.Create(reportOut)
+	if err != nil {
+		log.Fatalf("\nerror creating report: %v\n", err)
+	}
+	defer f.Close()
+	if err := reportTemplate.Execute(f, r); err != nil {
+		log.Fatalf("\nerror writing report: %v\n", err)
+	}
+	fmt.Printf("wrote %s\n", reportOut)
+}
+
+// opcodePattern names an opcode by its pattern, e.g. "DXYN", with unknown opcodes grouped together
+func opcodePattern(opcode uint16, m chip8.Mode) string {
+	mn, ok := chip8.Mnemonic(opcode, m)
+	if !ok {
+		return "unknown"
+	}
+	return strings.Fields(mn)[0]
+}
+
+// hexDump formats memory 16 bytes to a line, each line starting with its address
+func hexDump(memory []byte) string {
+	var sb strings.Builder
+	for addr := 0; addr < len(memory); addr += 16 {
+		fmt.Fprintf(&sb, "0x%04X:", addr)
+		for _, b := range memory[addr:min(addr+16, len(memory))] {
+			fmt.Fprintf(&sb, " %02X", b)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"hex": func(width int, n any) string { return fmt.Sprintf("0x%0*X", width, n) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>chippy report: {{.ROM}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+td, pre, code { font-family: monospace; }
+img { image-rendering: pixelated; border: 1px solid #ccc; }
+.unhit { color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.ROM}}</h1>
+<p>Ran {{.Cycles}} clock cycles in {{.Mode}} mode, executing {{.Instructions}} instructions. The final frame hashes to <code>{{.FrameHash}}</code>.</p>
+
+<h2 id="screen">Final screen</h2>
+<img src="{{.Screen}}" alt="The screen after the last cycle">
+
+<h2 id="registers">Registers</h2>
+<table>
+<tr><th>PC</th><th>I</th><th>SP</th><th>DT</th><th>ST</th></tr>
+<tr><td>{{hex 3 .State.PC}}</td><td>{{hex 3 .State.I}}</td><td>{{.State.SP}}</td><td>{{.State.DelayTimer}}</td><td>{{.State.SoundTimer}}</td></tr>
+</table>
+<table>
+<tr>{{range $r, $_ := .State.V}}<th>V{{printf "%X" $r}}</th>{{end}}</tr>
+<tr>{{range .State.V}}<td>{{hex 2 .}}</td>{{end}}</tr>
+</table>
+<table>
+<tr><th>Stack</th>{{range .State.Stack}}<td>{{hex 3 .}}</td>{{end}}</tr>
+</table>
+
+<h2 id="coverage">Opcode coverage</h2>
+<p>{{.Covered}} of the {{.Decoded}} instructions in the ROM were executed.</p>
+<table>
+<tr><th>Opcode</th><th>Executed</th></tr>
+{{range .Opcodes}}<tr><td>{{.Pattern}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2 id="disassembly">Disassembly</h2>
+<table>
+<tr><th>Address</th><th>Instruction</th><th>Executed</th></tr>
+{{range .Disassembly}}<tr{{if not .Hits}} class="unhit"{{end}}><td>{{hex 3 .Addr}}</td><td>{{.Text}}</td><td>{{.Hits}}</td></tr>
+{{end}}</table>
+
+<h2 id="trace">Trace</h2>
+<p>The last {{len .Trace}} instructions executed.</p>
+<table>
+<tr><th>Cycle</th><th>PC</th><th>Opcode</th><th>Instruction</th><th>Changed</th></tr>
+{{range .Trace}}<tr><td>{{.Cycle}}</td><td>{{hex 3 .PC}}</td><td>{{printf "%04X" .Opcode}}</td><td>{{.Mnemonic}}</td><td>{{.Changes}}</td></tr>
+{{end}}</table>
+
+<h2 id="memory">Memory</h2>
+<pre>{{.Memory}}</pre>
+</body>
+</html>
+`))
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "report.html")
+	setFlags(t, reportCmd, map[string]string{
+		"cycles": "1000",
+		"seed":   "1",
+		"out":    out,
+	})
+
+	runReport(reportCmd, []string{"../roms/ibm_logo.ch8"})
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("report wasn't written: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{
+		"<title>chippy report: ibm_logo.ch8</title>",
+		`<h2 id="screen">`, `<img src="data:image/png;base64,`,
+		`<h2 id="registers">`,
+		`<h2 id="coverage">`, "DXYN",
+		`<h2 id="disassembly">`, "00E0 clear",
+		`<h2 id="trace">`,
+		`<h2 id="memory">`, "0x0200: 00 E0",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// romExt is the file extension chippy looks for when searching a collection for ROMs
+const romExt = ".ch8"
+
+// maxROMRead caps how much of a ROM is read from stdin or the network. It's one byte past the largest
+// memory a VM can have, so the VM still sees an oversized ROM and reports it as too large.
+const maxROMRead = 0x10000 + 1
+
+// stdinROM is the ROM argument that reads the ROM from stdin
+const stdinROM = "-"
+
+// openROM resolves the user supplied ROM argument into the ROM's contents along with its name.
+// "-" reads the ROM from stdin and http(s) URLs are downloaded. Plain files are read as is, while
+// zip archives are searched for .ch8 entries.
+func openROM(pathToROM string) ([]byte, string, error) {
+	switch {
+	case pathToROM == stdinROM:
+		rom, err := io.ReadAll(io.LimitReader(os.Stdin, maxROMRead))
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading rom from stdin: %v", err)
+		}
+		return rom, "stdin", nil
+	case isURL(pathToROM):
+		return fetchROM(pathToROM)
+	case strings.EqualFold(filepath.Ext(pathToROM), ".zip"):
+		return openZipROM(pathToROM)
+	}
+	rom, err := os.ReadFile(pathToROM)
+	if err != nil {
+		return nil, "", err
+	}
+	return rom, pathToROM, nil
+}
+
+// isURL reports whether the ROM argument is an http(s) URL rather than a path
+func isURL(pathToROM string) bool {
+	return strings.HasPrefix(pathToROM, "http://") || strings.HasPrefix(pathToROM, "https://")
+}
+
+// fetchROM downloads the ROM at rawURL, naming it after the last element of the URL's path
+func fetchROM(rawURL string) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid rom url: %v", err)
+	}
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading rom: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error downloading rom: %s", resp.Status)
+	}
+
+	rom, err := io.ReadAll(io.LimitReader(resp.Body, maxROMRead))
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading rom: %v", err)
+	}
+	return rom, path.Base(u.Path), nil
+}
+
+// openZipROM opens the archive at pathToROM and returns the contents of the ROM inside it
+func openZipROM(pathToROM string) ([]byte, string, error) {
+	zr, err := zip.OpenReader(pathToROM)
+	if err != nil {
+		return nil, "", fmt.Errorf("error opening zip archive: %v", err)
+	}
+	defer zr.Close()
+	return pickZipROM(&zr.Reader, pathToROM, os.Stdin, os.Stdout)
+}
+
+// pickZipROM returns the contents of the ROM in an archive, the one --entry names if it's given. When the
+// archive holds more than one ROM the user is asked on out to choose which one to run, and answers on in.
+func pickZipROM(zr *zip.Reader, archive string, in io.Reader, out io.Writer) ([]byte, string, error) {
+	roms := zipROMs(zr)
+	var entry *zip.File
+	var err error
+	switch {
+	case zipEntry != "":
+		if entry, err = findZipEntry(roms, zipEntry); err != nil {
+			return nil, "", fmt.Errorf("%s: %v", archive, err)
+		}
+	case len(roms) == 0:
+		return nil, "", fmt.Errorf("no %s files found in %s", romExt, archive)
+	case len(roms) == 1:
+		entry = roms[0]
+	default:
+		if entry, err = chooseROM(roms, in, out); err != nil {
+			return nil, "", err
+		}
+	}
+
+	rom, err := readZipEntry(entry)
+	if err != nil {
+		return nil, "", err
+	}
+	return rom, entry.Name, nil
+}
+
+// zipROMs returns the ROM entries of an archive, at any depth, skipping directories and other files
+func zipROMs(zr *zip.Reader) []*zip.File {
+	var roms []*zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), romExt) {
+			continue
+		}
+		roms = append(roms, f)
+	}
+	return roms
+}
+
+// findZipEntry picks the ROM named by --entry, by its full path in the archive or just its file name
+func findZipEntry(roms []*zip.File, name string) (*zip.File, error) {
+	var matches []*zip.File
+	for _, f := range roms {
+		if f.Name == name {
+			return f, nil
+		}
+		if path.Base(f.Name) == name {
+			matches = append(matches, f)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		names := make([]string, len(roms))
+		for i, f := range roms {
+			names[i] = f.Name
+		}
+		return nil, fmt.Errorf("no entry named %q, the archive has: %s", name, strings.Join(names, ", "))
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("more than one entry is named %q, give its full path in the archive", name)
+}
+
+// chooseROM lists the ROMs found in an archive on out and reads the number of the one to run from in
+func chooseROM(roms []*zip.File, in io.Reader, out io.Writer) (*zip.File, error) {
+	fmt.Fprintln(out, "Multiple ROMs found in archive:")
+	for i, f := range roms {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, f.Name)
+	}
+	fmt.Fprintf(out, "Select a ROM [1-%d]: ", len(roms))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("error reading ROM selection: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(roms) {
+		return nil, fmt.Errorf("invalid ROM selection: %q", strings.TrimSpace(line))
+	}
+	return roms[n-1], nil
+}
+
+// readZipEntry reads an archive entry fully so the archive can be closed before the VM loads it. Like stdin
+// and downloads, no more than maxROMRead is read, so an entry that decompresses to something huge is
+// still only reported as too large.
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	rom, err := io.ReadAll(io.LimitReader(rc, maxROMRead))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", f.Name, err)
+	}
+	return rom, nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// newZip builds an in memory archive of files, by name
+func newZip(t *testing.T, files ...[2]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestPickZipROM(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    [][2]string
+		entry    string
+		choice   string
+		wantROM  string
+		wantName string
+		wantErr  string
+	}{
+		{
+			name:     "one rom runs without asking",
+			files:    [][2]string{{"readme.txt", "hi"}, {"games/", ""}, {"games/pong.ch8", "pong"}},
+			wantROM:  "pong",
+			wantName: "games/pong.ch8",
+		},
+		{
+			name:     "many roms ask which to run",
+			files:    [][2]string{{"pong.ch8", "pong"}, {"games/TETRIS.CH8", "tetris"}, {"notes.md", "#"}},
+			choice:   "2\n",
+			wantROM:  "tetris",
+			wantName: "games/TETRIS.CH8",
+		},
+		{
+			name:    "many roms with a bad choice",
+			files:   [][2]string{{"pong.ch8", "pong"}, {"tetris.ch8", "tetris"}},
+			choice:  "3\n",
+			wantErr: `invalid ROM selection: "3"`,
+		},
+		{
+			name:     "--entry picks by file name",
+			files:    [][2]string{{"pong.ch8", "pong"}, {"games/tetris.ch8", "tetris"}},
+			entry:    "tetris.ch8",
+			wantROM:  "tetris",
+			wantName: "games/tetris.ch8",
+		},
+		{
+			name:    "no roms",
+			files:   [][2]string{{"readme.txt", "hi"}},
+			wantErr: "no .ch8 files found in test.zip",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zipEntry = tt.entry
+			defer func() { zipEntry = "" }()
+
+			var out strings.Builder
+			rom, name, err := pickZipROM(newZip(t, tt.files...), "test.zip", strings.NewReader(tt.choice), &out)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("pickZipROM error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pickZipROM: %v", err)
+			}
+			if string(rom) != tt.wantROM || name != tt.wantName {
+				t.Errorf("pickZipROM = %q, %q, want %q, %q", rom, name, tt.wantROM, tt.wantName)
+			}
+			if asked := out.Len() > 0; asked != (tt.choice != "") {
+				t.Errorf("asked which rom to run = %v, output %q", asked, out.String())
+			}
+		})
+	}
+}
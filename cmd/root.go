@@ -3,7 +3,9 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/bradford-hamilton/chippy/internal/chip8"
 	"github.com/spf13/cobra"
 )
 
@@ -23,15 +25,368 @@ func runRoot(cmd *cobra.Command, args []string) {
 	fmt.Println("Unknown command. Try `chippy help` for more information")
 }
 
-// refreshRate is used for holding a flag value and controlling the VM's clock speed
+// refreshRate is used for holding a flag value and controlling the VM's clock speed, set with --ips when running ROMs
 var refreshRate int
 
+// autoSpeed lets the VM tune its clock speed to what the host can keep up with
+var autoSpeed bool
+
+// cycleAccurate meters the clock in the COSMAC VIP's machine cycles, each instruction costing what it did
+var cycleAccurate bool
+
+// fastForward is how many times faster holding Tab runs the VM
+var fastForward int
+
+// suggestedIPS runs the ROM at the clock speed the suggest command recommends for it, unless --ips is given
+var suggestedIPS bool
+
+// idleWindow holds the longest loop, in instructions, the VM treats as the ROM idling
+var idleWindow int
+
+// memorySize holds the amount of RAM given to the VM
+var memorySize int
+
+// stackDepth holds how many calls deep the VM's stack goes
+var stackDepth int
+
+// stackPolicy holds the name of what the VM does when the stack overflows or underflows
+var stackPolicy string
+
+// startAddress is where the ROM is loaded and run from
+var startAddress uint16
+
+// mode holds the name of the CHIP-8 dialect to run the ROM as
+var mode string
+
+// quirks holds the interpreter behaviors picked with the --quirk-* flags
+var quirks chip8.Quirks
+
+// title overrides the window title, which otherwise names the ROM
+var title string
+
+// inputMapHints shows suggested controls for ROMs found in the ROM database
+var inputMapHints bool
+
+// showUnknown prints unknown opcodes as they are hit rather than only summarizing them on shutdown
+var showUnknown bool
+
+// haltOnUnknown halts the VM at an unknown opcode instead of skipping it
+var haltOnUnknown bool
+
+// presetRegs and presetMem are the --preset-reg and --preset-mem values, e.g. "V5=0x0A" and "0x300=0xFF"
+var presetRegs, presetMem []string
+
+// headless runs without a window, for --cycles clock cycles
+var headless bool
+
+// backend is what chippy draws to and reads keys from: a window, or the terminal
+var backend string
+
+// backends are the values --backend accepts
+var backends = []string{"window", "terminal"}
+
+// cycles stops the run after that many clock cycles, 0 meaning no limit
+var cycles uint64
+
+// exitAfter stops the run after that long, 0 meaning no limit
+var exitAfter time.Duration
+
+// screenshotOnExit is where to save a PNG of the final frame, empty for none
+var screenshotOnExit string
+
+// dumpOnExit is where to save the VM's final state for a post-mortem, and resumePath a saved state to start from
+var dumpOnExit, resumePath string
+
+// strict lets chippy crash with a stack trace when an instruction panics
+var strict bool
+
+// highlightCollisions tints the pixels sprites collided on
+var highlightCollisions bool
+
+// drawStep is how long to pause after each sprite draw, 0 for no pause
+var drawStep time.Duration
+
+// metricsAddr is the address to serve Prometheus metrics on, empty for none
+var metricsAddr string
+
+// inspectAddr is the address to serve the VM's live state on as JSON, empty for none
+var inspectAddr string
+
+// breakOnCollision halts the VM on the first sprite collision
+var breakOnCollision bool
+
+// beepHz is the frequency of the beep tone
+var beepHz int
+
+// fontName is the --font built in font set or font file to draw FX29's glyphs with
+var fontName string
+
+// mute starts with the sound off
+var mute bool
+
+// minBeepMS is the shortest a beep plays for, in milliseconds
+var minBeepMS int
+
+// scale is how many window pixels each CHIP-8 pixel is drawn with
+var scale int
+
+// fullscreen opens the window fullscreen on the primary monitor
+var fullscreen bool
+
+// fade turns pixels off gradually to smooth out flicker
+var fade bool
+
+// pixelGap is the gap left between pixels, as a fraction of a pixel's width
+var pixelGap float64
+
+// overlay shows the frames and instructions per second over the window
+var overlay bool
+
+// memoryView opens a second window showing memory live
+var memoryView bool
+
+// screenshotDir is where F12 saves screenshots
+var screenshotDir string
+
+// recordPath is the GIF --record writes the session to, and recordFPS the rate it's captured at
+var recordPath string
+var recordFPS int
+
+// recordInputPath is where --record-input writes an input log of the session, and playInputPath the
+// input log --play-input plays back
+var recordInputPath, playInputPath string
+
+// seed seeds CXNN's random numbers, 0 for a seed from the current time
+var seed int64
+
+// profilesPath is the --profiles file of per-ROM settings, and profileName the profile to use from it
+// instead of the one matching the ROM
+var profilesPath, profileName string
+
+// zipEntry is the --entry ROM to run from a zip archive holding several
+var zipEntry string
+
+// demoName is the --demo bundled ROM to run in place of a ROM argument
+var demoName string
+
+// playlistPath is a file listing ROMs to run one after another
+var playlistPath string
+
+// allQuirks has the test command try every quirk in turn rather than just the ones given
+var allQuirks bool
+
+// keymapPath is the --keymap file binding keyboard keys to the CHIP-8 keypad
+var keymapPath string
+
+// gamepad reads the keypad from a connected gamepad as well as the keyboard
+var gamepad bool
+
+// keyRepeat is how often a held key is pressed again, and noKeyRepeat shows just the keys held instead
+var keyRepeat time.Duration
+var noKeyRepeat bool
+
+// rewindDepth is how many snapshots Backspace can step back through, taken every rewindInterval
+var rewindDepth int
+var rewindInterval time.Duration
+
+// fgColor and bgColor are the --fg and --bg hex colors, e.g. "#00FF00"
+var fgColor, bgColor string
+
+// debugMode starts the VM in the step debugger, and breakpoints are the --break addresses it halts at
+var debugMode bool
+var breakpoints []string
+
+// keysImage is the path the keys command renders the keypad to
+var keysImage string
+
+// expectHash is the final frame hash verify-replay checks against
+var expectHash string
+
+// capabilitiesJSON switches the capabilities command to JSON output
+var capabilitiesJSON bool
+
+// disasmOut is the directory disasm writes .asm files to when disassembling a directory of ROMs
+var disasmOut string
+
+// reportOut is the path report writes its HTML page to
+var reportOut string
+
+// eventsJSON is where per-cycle JSON events are written, "-" being stdout. Empty disables them.
+var eventsJSON string
+
+// logJSON writes the VM's lifecycle events to stderr as JSON lines instead of printing them for people
+var logJSON bool
+
+// tracePath is where the --trace log of executed instructions is written, "-" being stderr. Empty disables it.
+var tracePath string
+
 func init() {
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(keysCmd)
+	rootCmd.AddCommand(capabilitiesCmd)
+	rootCmd.AddCommand(verifyReplayCmd)
+	rootCmd.AddCommand(disasmCmd)
+	rootCmd.AddCommand(asmCmd)
+	rootCmd.AddCommand(fontdumpCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(demosCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(suggestCmd)
 
 	// Check for flags set by the user and hyrate their corresponding variables.
-	runCmd.Flags().IntVarP(&refreshRate, "refresh", "r", 60, "Set the refresh rate in Hz")
+	addClockFlags(runCmd)
+	runCmd.Flags().BoolVar(&autoSpeed, "auto-speed", false, "Ramp the clock speed up from --ips until frames start dropping")
+	runCmd.Flags().IntVar(&fastForward, "fast-forward", 10, "How many times faster the ROM runs, timers included, while Tab is held. 1 turns it off")
+	runCmd.Flags().BoolVar(&suggestedIPS, "suggest-ips", false, "Run at the clock speed `chippy suggest` recommends for the ROM when --ips isn't given")
+	runCmd.Flags().IntVar(&idleWindow, "idle-window", 2, "Longest loop (in instructions) treated as the ROM idling, 0 to disable")
+	runCmd.Flags().StringVar(&tracePath, "trace", "", "Write a line per executed instruction to a file, or stderr when given -")
+	runCmd.Flags().StringVar(&eventsJSON, "events-json", "", "Write a JSON line per executed cycle to a file, or stdout when no file is given")
+	runCmd.Flags().Lookup("events-json").NoOptDefVal = "-"
+	runCmd.Flags().BoolVar(&logJSON, "log-json", false, "Log the VM's lifecycle, like the ROM loading, pauses, resets, and shutting down, to stderr as JSON lines")
+	runCmd.Flags().StringVar(&title, "title", "", "Set the window title, defaults to the ROM's file name")
+	runCmd.Flags().BoolVar(&inputMapHints, "input-map-hints", false, "Show suggested controls for well known ROMs over the first few seconds of play")
+	runCmd.Flags().BoolVar(&showUnknown, "show-unknown", false, "Print unknown opcodes as they are hit, not just a count of them on exit")
+	runCmd.Flags().BoolVar(&haltOnUnknown, "halt-on-unknown", false, "Halt and print the VM's state at an unknown opcode instead of skipping it. Space resumes")
+	runCmd.Flags().StringArrayVar(&presetRegs, "preset-reg", nil, "Set a register before the ROM starts, e.g. V5=0x0A. Repeatable")
+	runCmd.Flags().StringArrayVar(&presetMem, "preset-mem", nil, "Set a byte of memory before the ROM starts, e.g. 0x300=0xFF. Repeatable")
+	runCmd.Flags().BoolVar(&headless, "headless", false, "Run without opening a window, as fast as possible. Requires --cycles or --exit-after")
+	runCmd.Flags().StringVar(&backend, "backend", "window", "Draw to and read keys from a window, or the terminal with half blocks for running over SSH: window or terminal")
+	runCmd.Flags().Uint64Var(&cycles, "cycles", 0, "Stop after this many clock cycles, 0 to run until the window is closed")
+	runCmd.Flags().DurationVar(&exitAfter, "exit-after", 0, "Stop after running this long, e.g. 10s, 0 to run until the window is closed")
+	runCmd.Flags().StringVar(&screenshotOnExit, "screenshot-on-exit", "", "Save the final frame as a PNG at this path")
+	runCmd.Flags().StringVar(&dumpOnExit, "dump-on-exit", "", "Save the VM's memory, registers, stack, timers, and screen to this path when it stops, in the F5 save state format")
+	runCmd.Flags().StringVar(&resumePath, "resume", "", "Start from a state saved with F5 or --dump-on-exit instead of the start of the ROM")
+	runCmd.Flags().BoolVar(&strict, "strict", false, "Crash with a stack trace when executing an instruction panics, instead of logging it and stopping the VM")
+	runCmd.Flags().StringVar(&screenshotDir, "screenshot-dir", "", "Directory F12 saves screenshots to, the current directory by default")
+	runCmd.Flags().StringVar(&recordPath, "record", "", "Record the session to an animated GIF at this path, written when chippy exits")
+	runCmd.Flags().IntVar(&recordFPS, "record-fps", 25, "Frames a second --record captures, up to 100")
+	runCmd.Flags().StringVar(&recordInputPath, "record-input", "", "Record the key presses to an input log at this path, written when chippy exits, for --play-input or verify-replay")
+	runCmd.Flags().StringVar(&playInputPath, "play-input", "", "Play the key presses back from an input log with its seed instead of reading the keyboard, stopping where the log ends")
+	runCmd.Flags().BoolVar(&highlightCollisions, "highlight-collisions", false, "Flash the pixels where sprites collide red for a frame")
+	runCmd.Flags().DurationVar(&drawStep, "draw-step", 0, "Pause this long after every sprite draw, e.g. 500ms, highlighting the sprite. Space skips ahead")
+	runCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics at this address, e.g. :9100")
+	runCmd.Flags().StringVar(&inspectAddr, "inspect", "", "Serve the VM's registers, memory, and screen as JSON at this address's /state, e.g. :8080")
+	runCmd.Flags().BoolVar(&breakOnCollision, "break-on-collision", false, "Halt and print the VM's state the first time a sprite draw collides. Space resumes")
+	runCmd.Flags().StringVar(&keymapPath, "keymap", "", "Load the keyboard keys bound to the CHIP-8 keypad from a JSON file")
+	runCmd.Flags().DurationVar(&keyRepeat, "key-repeat", 200*time.Millisecond, "How often a held key is pressed again for the ROM, e.g. 100ms")
+	runCmd.Flags().BoolVar(&noKeyRepeat, "no-key-repeat", false, "Show the ROM only the keys held at each moment, like the original hardware, instead of latching and repeating presses")
+	runCmd.Flags().IntVar(&rewindDepth, "rewind-depth", 100, "How many snapshots holding Backspace can rewind through, 0 to turn rewinding off. Each takes about 12KB with 4K of memory")
+	runCmd.Flags().DurationVar(&rewindInterval, "rewind-interval", 100*time.Millisecond, "How often a snapshot is taken for rewinding, e.g. 250ms")
+	runCmd.Flags().BoolVar(&gamepad, "gamepad", false, "Read the keypad from a connected gamepad too: the d-pad is 2/4/6/8 and A is 5")
+	runCmd.Flags().StringVar(&fgColor, "fg", "", "Color lit pixels are drawn in, as hex like #33FF33. White by default")
+	runCmd.Flags().StringVar(&bgColor, "bg", "", "Color the background is drawn in, as hex like #002200. Black by default")
+	runCmd.Flags().IntVar(&beepHz, "beep-hz", 440, "Set the frequency of the beep tone in Hz")
+	runCmd.Flags().BoolVar(&mute, "mute", false, "Start with the sound off. The sound timer still runs, so games play the same. M toggles it")
+	runCmd.Flags().IntVar(&minBeepMS, "min-beep-ms", 50, "Play every beep for at least this many milliseconds, however briefly the ROM sounds it")
+	runCmd.Flags().IntVar(&scale, "scale", 16, "Set how many window pixels wide each CHIP-8 pixel is drawn, 16 for a 1024x512 window")
+	runCmd.Flags().BoolVar(&fullscreen, "fullscreen", false, "Open fullscreen on the primary monitor. F11 toggles fullscreen")
+	runCmd.Flags().BoolVar(&overlay, "overlay", false, "Show the frames and instructions run per second in the corner of the window. F3 toggles it")
+	runCmd.Flags().BoolVar(&memoryView, "memory-view", false, "Open a second window showing memory live, with the bytes at the program counter and index register and the calls on the stack highlighted")
+	runCmd.Flags().Float64Var(&pixelGap, "pixel-gap", 0, "Leave a gap between pixels, as a fraction of a pixel's width like 0.1, for the look of a real display's grid")
+	runCmd.Flags().BoolVar(&fade, "fade", false, "Fade pixels out over a few frames instead of switching them off, to smooth out flicker")
+	runCmd.Flags().Int64Var(&seed, "seed", 0, "Seed the random numbers CXNN draws from so runs can be reproduced, 0 to seed from the current time")
+	runCmd.Flags().StringVar(&profilesPath, "profiles", "", "Load per-ROM profiles from this JSON file instead of profiles.json in the chippy config directory")
+	runCmd.Flags().StringVar(&profileName, "profile", "", "Apply this profile instead of the one matching the ROM's SHA-1")
+	runCmd.Flags().StringVar(&zipEntry, "entry", "", "Run the ROM with this name from a zip archive instead of asking which one, e.g. pong.ch8")
+	runCmd.Flags().StringVar(&demoName, "demo", "", "Run one of the ROMs bundled with chippy instead of a ROM file, e.g. ibm. `chippy demos` lists them")
+	runCmd.Flags().StringVar(&playlistPath, "playlist", "", "Run the ROMs listed in this file, one to a line, after any given as arguments. F2 or closing the window moves on to the next")
+	runCmd.Flags().BoolVar(&debugMode, "debug", false, "Start paused in the step debugger: space runs one instruction, enter runs to the next breakpoint")
+	runCmd.Flags().StringArrayVar(&breakpoints, "break", nil, "Halt in the step debugger when the program counter reaches this address, e.g. 0x2A4. Repeatable")
+	runCmd.Flags().StringVar(&fontName, "font", "", "Draw the 0-F glyphs with a built in font, classic, schip, or vip, or a font file of 80 bytes of 0-F glyphs, 160 of large glyphs, or 240 of both")
+	runCmd.Flags().StringVar(&mode, "mode", "chip8", "Set the CHIP-8 dialect to interpret: chip8, schip, or xochip")
+	runCmd.Flags().IntVar(&memorySize, "memory-size", 0, "Set the VM's memory size in bytes, from 4096 up to 65536. Defaults to 4096, or 65536 in xochip mode")
+	runCmd.Flags().IntVar(&stackDepth, "stack-depth", 16, "How many calls deep the stack goes, up to 256. The COSMAC VIP's went 12")
+	runCmd.Flags().BoolVar(&cycleAccurate, "cycle-accurate", false, fmt.Sprintf("Count the clock in the COSMAC VIP's machine cycles, each instruction taking as long as it did on the VIP. --ips then sets machine cycles a second, the VIP's %d by default", chip8.VIPClockSpeed))
+	runCmd.Flags().StringVar(&stackPolicy, "stack-overflow", "halt", "What to do when a ROM calls with the stack full or returns with it empty: halt, wrap, or error")
+	runCmd.Flags().Uint16Var(&startAddress, "start-address", chip8.DefaultStartAddress, "Load and start running the ROM at this address, e.g. 0x600 for ETI-660 ROMs")
+	addQuirkFlags(runCmd)
+
+	testCmd.Flags().StringVar(&mode, "mode", "chip8", "Set the CHIP-8 dialect to interpret: chip8, schip, or xochip")
+	addQuirkFlags(testCmd)
+	testCmd.Flags().BoolVar(&allQuirks, "all-quirks", false, "Test with no quirks and then with each quirk on its own, instead of with the quirks given")
+
+	keysCmd.Flags().StringVar(&keysImage, "image", "", "Render the keypad to a PNG at this path")
+	keysCmd.Flags().StringVar(&keymapPath, "keymap", "", "Show the keymap loaded from this JSON file instead of the default")
+
+	fontdumpCmd.Flags().StringVar(&fontName, "font", "", "Print this built in font, classic, schip, or vip, or the 0-F glyphs of this font file, instead of the default")
+
+	capabilitiesCmd.Flags().BoolVar(&capabilitiesJSON, "json", false, "Print the capabilities as JSON")
+
+	disasmCmd.Flags().StringVarP(&disasmOut, "out", "o", "", "Directory to write .asm files to, next to each ROM by default")
+	disasmCmd.Flags().StringVar(&mode, "mode", "chip8", "Set the CHIP-8 dialect to decode: chip8, schip, or xochip")
+	asmCmd.Flags().StringVar(&mode, "mode", "chip8", "Set the CHIP-8 dialect to assemble for: chip8, schip, or xochip")
+
+	reportCmd.Flags().Uint64Var(&cycles, "cycles", 0, "How many clock cycles to run the ROM for")
+	reportCmd.Flags().StringVarP(&reportOut, "out", "o", "report.html", "Path to write the report to")
+	addClockFlags(reportCmd)
+	reportCmd.Flags().StringVar(&mode, "mode", "chip8", "Set the CHIP-8 dialect to interpret: chip8, schip, or xochip")
+	reportCmd.Flags().IntVar(&memorySize, "memory-size", 0, "Set the VM's memory size in bytes, from 4096 up to 65536. Defaults to 4096, or 65536 in xochip mode")
+	reportCmd.Flags().IntVar(&stackDepth, "stack-depth", 16, "How many calls deep the stack goes, up to 256. The COSMAC VIP's went 12")
+	reportCmd.Flags().BoolVar(&cycleAccurate, "cycle-accurate", false, fmt.Sprintf("Count the clock in the COSMAC VIP's machine cycles, each instruction taking as long as it did on the VIP. --ips then sets machine cycles a second, the VIP's %d by default", chip8.VIPClockSpeed))
+	reportCmd.Flags().StringVar(&stackPolicy, "stack-overflow", "halt", "What to do when a ROM calls with the stack full or returns with it empty: halt, wrap, or error")
+	addQuirkFlags(reportCmd)
+	reportCmd.Flags().Int64Var(&seed, "seed", 0, "Seed the random numbers CXNN draws from, 0 to seed from the current time")
+	reportCmd.Flags().IntVar(&idleWindow, "idle-window", 2, "Longest loop (in instructions) treated as the ROM idling, 0 to disable")
+
+	verifyReplayCmd.Flags().StringVar(&expectHash, "expect-hash", "", "The SHA-256 of the final frame the replay should produce")
+	verifyReplayCmd.MarkFlagRequired("expect-hash")
+	addClockFlags(verifyReplayCmd)
+	verifyReplayCmd.Flags().StringVar(&mode, "mode", "chip8", "Set the CHIP-8 dialect to interpret: chip8, schip, or xochip")
+	verifyReplayCmd.Flags().IntVar(&memorySize, "memory-size", 0, "Set the VM's memory size in bytes, from 4096 up to 65536. Defaults to 4096, or 65536 in xochip mode")
+	verifyReplayCmd.Flags().IntVar(&stackDepth, "stack-depth", 16, "How many calls deep the stack goes, up to 256. The COSMAC VIP's went 12")
+	verifyReplayCmd.Flags().BoolVar(&cycleAccurate, "cycle-accurate", false, fmt.Sprintf("Count the clock in the COSMAC VIP's machine cycles, each instruction taking as long as it did on the VIP. --ips then sets machine cycles a second, the VIP's %d by default", chip8.VIPClockSpeed))
+	verifyReplayCmd.Flags().StringVar(&stackPolicy, "stack-overflow", "halt", "What to do when a ROM calls with the stack full or returns with it empty: halt, wrap, or error")
+	addQuirkFlags(verifyReplayCmd)
+	verifyReplayCmd.Flags().BoolVar(&noKeyRepeat, "no-key-repeat", false, "Replay with the keypad showing only the keys held, for input logs recorded with --no-key-repeat")
+	verifyReplayCmd.Flags().IntVar(&idleWindow, "idle-window", 2, "Longest loop (in instructions) treated as the ROM idling, 0 to disable")
+}
+
+// addClockFlags registers --ips, and --refresh, its old name, on cmd. Every command running ROMs defaults to the
+// same clock speed, so the timers, which count down relative to it, run the same whichever command runs a ROM.
+func addClockFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&refreshRate, "ips", chip8.DefaultClockSpeed, "Set the clock speed in instructions per second. The timers count down at 60Hz regardless")
+	cmd.Flags().IntVarP(&refreshRate, "refresh", "r", chip8.DefaultClockSpeed, "Set the clock speed in Hz")
+	cmd.Flags().MarkDeprecated("refresh", "use --ips instead")
+}
+
+// clockSpeedGiven reports whether the clock speed was set on the command line, by --ips or --refresh
+func clockSpeedGiven(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("ips") || cmd.Flags().Changed("refresh")
+}
+
+// quirkFlags pairs each --quirk-* flag with the setting it turns on
+var quirkFlags = []struct {
+	name  string
+	field func(*chip8.Quirks) *bool
+	usage string
+}{
+	{"quirk-timers", func(q *chip8.Quirks) *bool { return &q.TimersPerInstruction }, "Decrement the delay and sound timers once per instruction instead of at 60Hz"},
+	{"quirk-shift", func(q *chip8.Quirks) *bool { return &q.ShiftInPlace }, "Shift VX in place with 8XY6/8XYE instead of shifting VY into VX"},
+	{"quirk-load-store", func(q *chip8.Quirks) *bool { return &q.LoadStoreIncrementsI }, "Advance the index register past the registers FX55/FX65 save or load"},
+	{"quirk-jump", func(q *chip8.Quirks) *bool { return &q.JumpUsesVX }, "Jump BNNN to XNN plus VX like SUPER-CHIP's BXNN instead of to NNN plus V0"},
+	{"quirk-wrap", func(q *chip8.Quirks) *bool { return &q.WrapSprites }, "Wrap sprites drawn off an edge of the screen around to the opposite edge instead of clipping them"},
+	{"quirk-wrap-x", func(q *chip8.Quirks) *bool { return &q.WrapSpritesX }, "Wrap sprites drawn off the right edge of the screen around to the left edge, still clipping them at the bottom"},
+	{"quirk-vf-reset", func(q *chip8.Quirks) *bool { return &q.LogicResetsVF }, "Clear VF after the 8XY1/8XY2/8XY3 logic operations like the COSMAC VIP"},
+	{"quirk-display-wait", func(q *chip8.Quirks) *bool { return &q.DisplayWait }, "Hold DXYN back until the next 60Hz frame before drawing like the COSMAC VIP, which fixes tearing in some ROMs"},
+}
+
+// addQuirkFlags registers every quirk flag on cmd, so each command running ROMs takes the same ones
+func addQuirkFlags(cmd *cobra.Command) {
+	for _, qf := range quirkFlags {
+		cmd.Flags().BoolVar(qf.field(&quirks), qf.name, false, qf.usage)
+	}
+}
+
+// quirksGiven reports whether any quirk was set on the command line
+func quirksGiven(cmd *cobra.Command) bool {
+	for _, qf := range quirkFlags {
+		if cmd.Flags().Changed(qf.name) {
+			return true
+		}
+	}
+	return false
 }
 
 // Execute runs chippy according to the user's command/subcommand(s)/flag(s)
@@ -1,34 +1,396 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+	"github.com/bradford-hamilton/chippy/internal/terminal"
+	"github.com/bradford-hamilton/chippy/roms"
+	"github.com/faiface/pixel/pixelgl"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
+// screenshotScale is how many image pixels square each CHIP-8 pixel is drawn in screenshots
+const screenshotScale = 10
+
 // runCmd runs the chippy virtual machine and waits for a shutdown signal to exit
 var runCmd = &cobra.Command{
-	Use:   "run `path/to/rom`",
+	Use:   "run `path/to/rom|path/to/roms.zip` [more roms...]",
 	Short: "run the chippy emulator",
-	Args:  cobra.MinimumNArgs(1),
 	Run:   runChippy,
 }
 
 func runChippy(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
-		log.Fatal("The run command takes one argument: a `path/to/rom`")
+	// The exit status waits for runROMs to return, so the trace and events it writes are flushed and closed first
+	if !runROMs(cmd, args) {
+		os.Exit(1)
+	}
+}
+
+// runROMs runs the ROMs in args until the VM shuts down, reporting whether it stopped without an error
+func runROMs(cmd *cobra.Command, args []string) bool {
+	paths := args
+	if playlistPath != "" {
+		more, err := readPlaylist(playlistPath)
+		if err != nil {
+			log.Fatalf("\nerror reading playlist: %v\n", err)
+		}
+		paths = append(paths, more...)
+	}
+
+	var pathToROM, name string
+	var rom []byte
+	var err error
+	switch {
+	case demoName != "" && len(paths) == 0:
+		rom, name, err = roms.Open(demoName)
+	case demoName == "" && len(paths) > 0:
+		pathToROM = paths[0]
+		rom, name, err = openROM(pathToROM)
+	default:
+		log.Fatal("The run command takes `path/to/rom` arguments or a --playlist, or --demo and no arguments")
+	}
+	if err != nil {
+		log.Fatalf("\nerror loading rom: %v\n", err)
+	}
+	if err := applyProfile(cmd, rom); err != nil {
+		log.Fatal(err)
+	}
+	var replay *chip8.Replay
+	if playInputPath != "" {
+		if replay, err = loadReplay(playInputPath); err != nil {
+			log.Fatalf("\nerror reading input log: %v\n", err)
+		}
+		if err := applyReplaySettings(cmd, replay); err != nil {
+			log.Fatal(err)
+		}
 	}
-	pathToROM := os.Args[2]
 
-	vm, err := chip8.NewVM(pathToROM, refreshRate)
+	m, err := chip8.ParseMode(mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sp, err := chip8.ParseStackPolicy(stackPolicy)
 	if err != nil {
-		log.Fatalf("\nerror creating a new chip-8 VM: %v\n", err)
+		log.Fatal(err)
+	}
+	if cycleAccurate && suggestedIPS {
+		log.Fatal("--suggest-ips suggests instructions a second, so it can't be used with --cycle-accurate")
+	}
+	if cycleAccurate && !clockSpeedGiven(cmd) {
+		refreshRate = chip8.VIPClockSpeed
+	}
+	// A profile's ips counts as given, since applying it sets the flag
+	if suggestedIPS && !clockSpeedGiven(cmd) {
+		refreshRate, _ = suggestIPS(rom, m)
+		fmt.Printf("suggested clock speed: %dHz\n", refreshRate)
+	}
+
+	cfg := chip8.Config{
+		IdleWindow:          idleWindow,
+		MemorySize:          memorySize,
+		StackDepth:          stackDepth,
+		StackPolicy:         sp,
+		StartAddress:        startAddress,
+		Mode:                m,
+		Quirks:              quirks,
+		AutoSpeed:           autoSpeed,
+		CycleAccurate:       cycleAccurate,
+		FastForward:         fastForward,
+		Title:               title,
+		Scale:               scale,
+		Fullscreen:          fullscreen,
+		Fade:                fade,
+		PixelGap:            pixelGap,
+		Overlay:             overlay,
+		MemoryView:          memoryView,
+		ScreenshotDir:       screenshotDir,
+		Record:              recordPath,
+		RecordInput:         recordInputPath,
+		RecordFPS:           recordFPS,
+		ShowUnknown:         showUnknown,
+		HaltOnUnknown:       haltOnUnknown,
+		Headless:            headless,
+		MaxCycles:           cycles,
+		ExitAfter:           exitAfter,
+		Strict:              strict,
+		Seed:                seed,
+		HighlightCollisions: highlightCollisions,
+		DrawStep:            drawStep,
+		BreakOnCollision:    breakOnCollision,
+		StatePath:           statePath(pathToROM, name),
+		Debug:               debugMode,
+		BeepHz:              beepHz,
+		MinBeep:             time.Duration(minBeepMS) * time.Millisecond,
+		Mute:                mute,
+		Gamepad:             gamepad,
+		KeyRepeat:           keyRepeat,
+		NoKeyRepeat:         noKeyRepeat,
+		RewindDepth:         rewindDepth,
+		RewindInterval:      rewindInterval,
+	}
+	if replay != nil {
+		cfg.PlayInput, cfg.Seed = replay, replay.Seed
+		if cfg.MaxCycles == 0 {
+			cfg.MaxCycles = cfg.PlayInput.Cycles
+		}
+	}
+	if headless && cfg.MaxCycles == 0 && cfg.ExitAfter == 0 {
+		log.Fatal("--headless needs --cycles, --exit-after, or --play-input to know when to stop")
+	}
+	if len(paths) > 1 {
+		if headless {
+			log.Fatal("a playlist needs a window to move through it with")
+		}
+		if recordInputPath != "" || playInputPath != "" {
+			log.Fatal("--record-input and --play-input need a single ROM, not a playlist")
+		}
+		cfg.Next = playlistNext(paths[1:])
+	}
+	switch backend {
+	case "window":
+	case "terminal":
+		if headless {
+			log.Fatal("--headless and --backend=terminal can't be used together")
+		}
+		if memoryView {
+			log.Fatal("--memory-view needs a window")
+		}
+		// The terminal backend draws to stdout, so nothing else may be written to it while it runs
+		if eventsJSON == "-" {
+			log.Fatal("--events-json needs a file with --backend=terminal")
+		}
+		if tracePath == "-" && term.IsTerminal(int(os.Stderr.Fd())) {
+			log.Fatal("--trace needs a file with --backend=terminal, or stderr redirected")
+		}
+	default:
+		log.Fatalf("unknown backend %q, expected window or terminal", backend)
+	}
+	if headless && debugMode {
+		log.Fatal("--debug needs a window to step through the ROM with")
+	}
+	for _, b := range breakpoints {
+		addr, err := chip8.ParseBreakpoint(b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Breakpoints = append(cfg.Breakpoints, addr)
+	}
+	if cfg.Title == "" {
+		cfg.Title = pixel.WindowTitle(name)
 	}
+	cfg.Foreground = parseColorFlag("fg", fgColor, "white")
+	cfg.Background = parseColorFlag("bg", bgColor, "black")
+	if cfg.RegPresets, cfg.MemPresets, err = parsePresets(presetRegs, presetMem); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Font, cfg.LargeFont, err = loadFont(fontName); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.KeyMap, err = loadKeyMap(keymapPath); err != nil {
+		log.Fatal(err)
+	}
+	if inputMapHints {
+		cfg.Hints = controlHints(rom, cfg.KeyMap)
+	}
+	if logJSON {
+		cfg.Logger = chip8.NewJSONLogger(os.Stderr)
+	}
+	if eventsJSON != "" {
+		events, err := openEvents(eventsJSON)
+		if err != nil {
+			log.Fatalf("\nerror opening events output: %v\n", err)
+		}
+		defer events.Close()
+		cfg.Events = events
+	}
+	if tracePath != "" {
+		trace, err := openTrace(tracePath)
+		if err != nil {
+			log.Fatalf("\nerror opening trace output: %v\n", err)
+		}
+		defer trace.Close()
+		tw := bufio.NewWriter(trace)
+		defer tw.Flush()
+		cfg.OnStep = func(res chip8.StepResult) {
+			fmt.Fprintln(tw, formatTrace(res, m))
+		}
+	}
+
+	ok := true
+	run := func() {
+		// While the terminal backend owns the tty everything logged goes to the display instead, and JSON
+		// logs bound for the terminal are held until it's restored
+		var tty *terminal.Display
+		var heldLogs bytes.Buffer
+		closeTTY := func() {
+			if tty == nil {
+				return
+			}
+			tty.Close()
+			log.SetOutput(os.Stderr)
+			os.Stderr.Write(heldLogs.Bytes())
+			heldLogs.Reset()
+		}
+		if backend == "terminal" {
+			if tty, err = terminal.New(cfg.Title); err != nil {
+				log.Fatal(err)
+			}
+			cfg.Display = tty
+			log.SetOutput(tty)
+			switch {
+			case cfg.Logger == nil:
+				cfg.Logger = chip8.TextLogger{W: tty}
+			case logJSON && term.IsTerminal(int(os.Stderr.Fd())):
+				cfg.Logger = chip8.NewJSONLogger(&heldLogs)
+			}
+		}
+		vm, err := chip8.NewVM(bytes.NewReader(rom), refreshRate, cfg)
+		if err != nil {
+			closeTTY()
+			log.Fatalf("\nerror creating a new chip-8 VM: %v\n", err)
+		}
 
-	go vm.ManageAudio()
-	go vm.Run()
+		if metricsAddr != "" {
+			serveMetrics(metricsAddr, vm.Stats())
+		}
+		if inspectAddr != "" {
+			serveInspect(inspectAddr, vm)
+		}
+		if resumePath != "" {
+			if err := loadStateFile(vm, resumePath); err != nil {
+				closeTTY()
+				log.Fatalf("\nerror resuming from %s: %v\n", resumePath, err)
+			}
+		}
+		switch {
+		case tty != nil:
+			go vm.RingBell(tty.Bell)
+		case !headless:
+			go vm.ManageAudio()
+		}
+		go vm.Run()
 
-	<-vm.ShutdownC
+		<-vm.ShutdownC
+		// The terminal goes back to normal before anything else is printed
+		closeTTY()
+
+		if screenshotOnExit != "" {
+			if err := vm.SaveScreenshot(screenshotOnExit, screenshotScale); err != nil {
+				log.Fatalf("\nerror writing screenshot: %v\n", err)
+			}
+		}
+		if dumpOnExit != "" {
+			if err := saveStateFile(vm, dumpOnExit); err != nil {
+				log.Fatalf("\nerror writing dump: %v\n", err)
+			}
+		}
+		// The VM has already logged the error that stopped it
+		ok = vm.Err() == nil
+	}
+
+	if headless || backend == "terminal" {
+		run()
+		return ok
+	}
+	// pixelgl needs access to the main thread, which cobra runs commands on
+	pixelgl.Run(run)
+	return ok
+}
+
+// parsePresets parses the --preset-reg and --preset-mem values
+func parsePresets(regs, mem []string) ([]chip8.RegPreset, []chip8.MemPreset, error) {
+	var rp []chip8.RegPreset
+	for _, s := range regs {
+		p, err := chip8.ParseRegPreset(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		rp = append(rp, p)
+	}
+	var mp []chip8.MemPreset
+	for _, s := range mem {
+		p, err := chip8.ParseMemPreset(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		mp = append(mp, p)
+	}
+	return rp, mp, nil
+}
+
+// controlHints looks the ROM up in the ROM database and describes its controls in terms of the
+// keymap. It is empty for ROMs the database doesn't know.
+func controlHints(rom []byte, km map[uint16]pixelgl.Button) string {
+	e, ok := romdb.Lookup(rom)
+	if !ok {
+		return ""
+	}
+	return e.Hints(func(key byte) string { return km[uint16(key)].String() })
+}
+
+// openEvents opens the destination for --events-json, "-" meaning stdout
+func openEvents(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
+
+// saveStateFile writes the VM's full state to path in the save state format, which loadStateFile resumes from
+func saveStateFile(vm *chip8.VM, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := vm.SaveState(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadStateFile(vm *chip8.VM, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return vm.LoadState(f)
+}
+
+// statePath is where quick saves of a ROM go: next to the ROM, or next to the archive it was run from.
+// ROMs read from stdin or a URL, and the bundled demos, have nowhere to save to, so quick saves are off for them.
+func statePath(pathToROM, name string) string {
+	if pathToROM == "" || pathToROM == stdinROM || isURL(pathToROM) {
+		return ""
+	}
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	return filepath.Join(filepath.Dir(pathToROM), base+".state")
+}
+
+// parseColorFlag parses a hex color flag. It is nil, keeping the window's default color, when
+// the flag isn't set or can't be parsed, in which case the problem is reported.
+func parseColorFlag(flag, value, fallback string) color.Color {
+	if value == "" {
+		return nil
+	}
+	c, err := pixel.ParseHexColor(value)
+	if err != nil {
+		log.Printf("--%s: %v, using %s\n", flag, err, fallback)
+		return nil
+	}
+	return c
 }
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+	"github.com/bradford-hamilton/chippy/roms"
+	"github.com/spf13/cobra"
+)
+
+// setFlags sets cmd's flags as if they were given on the command line, putting them back when the test ends
+func setFlags(t *testing.T, cmd *cobra.Command, flags map[string]string) {
+	t.Helper()
+	for name, value := range flags {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			t.Fatalf("no --%s flag", name)
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+	}
+}
+
+func TestHeadlessRun(t *testing.T) {
+	// Keep any profiles on this machine out of the run
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	screenshot := filepath.Join(t.TempDir(), "out.png")
+	setFlags(t, runCmd, map[string]string{
+		"headless":           "true",
+		"cycles":             "1000",
+		"seed":               "1",
+		"screenshot-on-exit": screenshot,
+	})
+
+	runChippy(runCmd, []string{"../roms/ibm_logo.ch8"})
+
+	f, err := os.Open(screenshot)
+	if err != nil {
+		t.Fatalf("screenshot wasn't written: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := img.Bounds(), image.Rect(0, 0, 64*screenshotScale, 32*screenshotScale); got != want {
+		t.Errorf("screenshot bounds = %v, want %v", got, want)
+	}
+	lit := false
+	for y := 0; y < img.Bounds().Dy() && !lit; y++ {
+		for x := 0; x < img.Bounds().Dx() && !lit; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			lit = r|g|b != 0
+		}
+	}
+	if !lit {
+		t.Error("the screenshot is blank, the IBM logo wasn't drawn")
+	}
+}
+
+// A run stopped by an error still flushes its trace, which is the run most worth reading it for
+func TestRunErrorKeepsTheTrace(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	rom, trace := filepath.Join(dir, "under.ch8"), filepath.Join(dir, "t.log")
+	// 6001 then a return with nothing on the stack
+	if err := os.WriteFile(rom, []byte{0x60, 0x01, 0x00, 0xEE}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	setFlags(t, runCmd, map[string]string{
+		"headless":       "true",
+		"cycles":         "10",
+		"stack-overflow": "error",
+		"trace":          trace,
+	})
+
+	if runROMs(runCmd, []string{rom}) {
+		t.Error("the run reported no error after a stack underflow")
+	}
+	b, err := os.ReadFile(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "6XNN V0=0x01") {
+		t.Errorf("trace = %q, want the instruction before the fault", b)
+	}
+}
+
+func TestControlHints(t *testing.T) {
+	rom, _, err := roms.Open("pong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := controlHints(rom, pixel.DefaultKeyMap()), "1/Q to move the left paddle, 4/R to move the right paddle"; got != want {
+		t.Errorf("controlHints = %q, want %q", got, want)
+	}
+	if got := controlHints([]byte{0x12, 0x00}, pixel.DefaultKeyMap()); got != "" {
+		t.Errorf("controlHints for an unknown ROM = %q, want none", got)
+	}
+}
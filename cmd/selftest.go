@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/bradford-hamilton/chippy/roms"
+	"github.com/spf13/cobra"
+)
+
+// testCmd runs the bundled ROMs without a window and checks they end on the frames they're known to
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Check the bundled ROMs still draw what they should with the given mode and quirks",
+	Long:  "Run `chippy test --quirk-shift` to see whether the ROMs bundled with chippy still end on their reference frames with your flags, or `chippy test --all-quirks` to try each quirk in turn. Exits non-zero if any don't.",
+	Args:  cobra.NoArgs,
+	Run:   runTest,
+}
+
+// The bundled ROMs are run for goldenCycles at goldenIPS, with CXNN seeded with goldenSeed and no input,
+// the same way goldenFrames were recorded
+const (
+	goldenCycles = 7000
+	goldenIPS    = 700
+	goldenSeed   = 1
+)
+
+// goldenFrames are the SHA-256 hashes of the bundled ROMs' final frames, as FrameHash gives them, in
+// standard CHIP-8 with no quirks
+var goldenFrames = map[string]string{
+	"chip8_logo":    "c30b65b2ae1bbaf20343a71fd3a26f78549fce90e5ae1973c67183bb8e5a4b80",
+	"ibm_logo":      "64f86b5f5b65f4ffec634dfe8867032b10a5d2f4350e39e860ef0860bd02c9a2",
+	"invaders":      "1d536c8972605de296acd803c2cb8a1206344efb3d88dde0bf8c930f28ef12d6",
+	"particle_demo": "d08c731722356c23e8236d99c37c53de1c8d25ff43d20da6327d6f0daaf797c0",
+	"pong":          "0b55c80e6dca08c065e81b6e2c5228a3fbab7d6daef26a1092eccf1617b593e8",
+	"tetris":        "1a3d142a730db6e951232436570e90c31de0cc05d8c3795e6eab99479066725f",
+}
+
+func runTest(cmd *cobra.Command, args []string) {
+	m, err := chip8.ParseMode(mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	configs := []chip8.Quirks{quirks}
+	if allQuirks {
+		configs = []chip8.Quirks{{}}
+		for _, qf := range quirkFlags {
+			var q chip8.Quirks
+			*qf.field(&q) = true
+			configs = append(configs, q)
+		}
+	}
+
+	failed := false
+	for _, q := range configs {
+		var differ []string
+		for _, name := range roms.Names() {
+			ok, err := matchesGolden(name, m, q)
+			if err != nil {
+				log.Fatalf("\nerror running %s: %v\n", name, err)
+			}
+			if !ok {
+				differ = append(differ, name)
+			}
+		}
+
+		result := "ok  "
+		if len(differ) > 0 {
+			result = "FAIL"
+			failed = true
+		}
+		fmt.Printf("%s %-20s %d/%d match", result, describeQuirks(q), len(goldenFrames)-len(differ), len(goldenFrames))
+		if len(differ) > 0 {
+			fmt.Printf(", differ: %s", strings.Join(differ, " "))
+		}
+		fmt.Println()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// matchesGolden runs a bundled ROM the way its golden frame was recorded, but in mode m with quirks q,
+// and reports whether it ends on that frame. ROMs without a golden frame always match.
+func matchesGolden(name string, m chip8.Mode, q chip8.Quirks) (bool, error) {
+	want, ok := goldenFrames[name]
+	if !ok {
+		return true, nil
+	}
+	rom, _, err := roms.Open(name)
+	if err != nil {
+		return false, err
+	}
+	vm, err := chip8.NewVM(bytes.NewReader(rom), goldenIPS, chip8.Config{
+		Mode:     m,
+		Quirks:   q,
+		Headless: true,
+		Seed:     goldenSeed,
+	})
+	if err != nil {
+		return false, err
+	}
+	// A replay with no input runs the ROM for the cycles without Run's shutdown chatter
+	vm.RunReplay(&chip8.Replay{Cycles: goldenCycles})
+	return vm.FrameHash() == want, nil
+}
+
+// describeQuirks names the quirk flags that give q, or "no quirks"
+func describeQuirks(q chip8.Quirks) string {
+	var flags []string
+	for _, qf := range quirkFlags {
+		if *qf.field(&q) {
+			flags = append(flags, "--"+qf.name)
+		}
+	}
+	if len(flags) == 0 {
+		return "no quirks"
+	}
+	return strings.Join(flags, " ")
+}
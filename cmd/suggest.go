@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/spf13/cobra"
+)
+
+// suggestCmd recommends a clock speed for a ROM from the opcodes it runs
+var suggestCmd = &cobra.Command{
+	Use:   "suggest `path/to/rom`",
+	Short: "Suggest a clock speed to run a ROM at",
+	Long:  "Run `chippy suggest rom.ch8` for a starting point for --ips, guessed from the ROM's dialect and the opcodes it runs, and why. `chippy run --suggest-ips` starts at it.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSuggest,
+}
+
+// baseIPS is the clock speed suggestions start from for each dialect
+var baseIPS = map[chip8.Mode]int{
+	chip8.ModeChip8:  chip8.DefaultClockSpeed,
+	chip8.ModeSChip:  1000,
+	chip8.ModeXOChip: 2000,
+}
+
+// minSuggestedIPS is the slowest clock speed ever suggested
+const minSuggestedIPS = 300
+
+func runSuggest(cmd *cobra.Command, args []string) {
+	rom, name, err := openROM(args[0])
+	if err != nil {
+		log.Fatalf("\nerror loading rom: %v\n", err)
+	}
+	m, _ := guessMode(rom)
+	ips, reasons := suggestIPS(rom, m)
+
+	fmt.Printf("%-8s %s\n", "name", filepath.Base(name))
+	fmt.Printf("%-8s %d\n", "ips", ips)
+	for _, r := range reasons {
+		fmt.Printf("%-8s %s\n", "reason", r)
+	}
+}
+
+// suggestIPS guesses a clock speed for running the ROM in mode m from the opcodes it can reach, along with
+// the reasons for it. It's a starting point: ROMs that draw or wait for keys a lot are usually paced by
+// the clock and play better slower, and ROMs that never set the delay timer have nothing else pacing them.
+func suggestIPS(rom []byte, m chip8.Mode) (int, []string) {
+	counts := map[string]int{}
+	total := 0
+	for _, opcode := range reachableOpcodes(rom, m) {
+		counts[opcodePattern(opcode, m)]++
+		total++
+	}
+
+	ips := baseIPS[m]
+	reasons := []string{fmt.Sprintf("%s ROMs start from %d", m, ips)}
+	if total == 0 {
+		return ips, reasons
+	}
+
+	if counts["FX15"] == 0 {
+		ips = ips * 5 / 7
+		reasons = append(reasons, fmt.Sprintf("it never sets the delay timer, so only the clock paces it: %d", ips))
+	}
+	if waits := counts["DXYN"] + counts["DXY0"] + counts["FX0A"]; waits*10 > total {
+		// Slow down by the share of instructions that draw or wait, up to half
+		share := float64(waits) / float64(total)
+		ips -= int(float64(ips) * min(share, 0.5))
+		reasons = append(reasons, fmt.Sprintf("%.0f%% of its instructions draw or wait for a key: %d", share*100, ips))
+	}
+
+	// Round to the nearest 50, the steps anyone would pick by hand
+	ips = max((ips+25)/50*50, minSuggestedIPS)
+	return ips, reasons
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+)
+
+// openTrace opens the destination for --trace, "-" meaning stderr
+func openTrace(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stderr, nil
+	}
+	return os.Create(path)
+}
+
+// formatTrace formats an executed instruction as a line of the trace: the cycle, PC, opcode, mnemonic, and
+// the state it changed, e.g. "00000002 0x202 A22A ANNN I=0x22A             I=0x22A". The fields before the
+// changes are fixed width and everything comes from the VM alone, so runs of the same ROM produce traces
+// that diff cleanly.
+func formatTrace(res chip8.StepResult, m chip8.Mode) string {
+	mnemonic, ok := chip8.Mnemonic(res.Opcode, m)
+	if !ok {
+		mnemonic = "unknown"
+	}
+	line := fmt.Sprintf("%08d 0x%03X %04X %-24s %s", res.Cycle, res.PC, res.Opcode, mnemonic, res.Changes())
+	return strings.TrimRight(line, " ")
+}
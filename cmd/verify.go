@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/spf13/cobra"
+)
+
+// verifyReplayCmd plays an input log back on a ROM without a window and checks the final frame
+var verifyReplayCmd = &cobra.Command{
+	Use:   "verify-replay `path/to/rom` `path/to/input.log`",
+	Short: "Replay recorded input on a ROM and check the final frame's hash",
+	Long:  "Run `chippy verify-replay rom.ch8 input.log --expect-hash <hex>` to check a ROM still plays out the same way. Exits non-zero on a mismatch.",
+	Args:  cobra.ExactArgs(2),
+	Run:   runVerifyReplay,
+}
+
+func runVerifyReplay(cmd *cobra.Command, args []string) {
+	got, ok, err := verifyReplay(cmd, args[0], args[1], expectHash)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !ok {
+		fmt.Printf("mismatch: final frame hash %s, expected %s\n", got, expectHash)
+		os.Exit(1)
+	}
+	fmt.Printf("ok: final frame hash %s\n", got)
+}
+
+// verifyReplay plays the input log at logPath back on the ROM at romPath without a window, returning the final
+// frame's hash and whether it's the expected one
+func verifyReplay(cmd *cobra.Command, romPath, logPath, expected string) (string, bool, error) {
+	rom, _, err := openROM(romPath)
+	if err != nil {
+		return "", false, fmt.Errorf("\nerror loading rom: %v\n", err)
+	}
+
+	rp, err := loadReplay(logPath)
+	if err != nil {
+		return "", false, fmt.Errorf("\nerror reading input log: %v\n", err)
+	}
+	if err := applyReplaySettings(cmd, rp); err != nil {
+		return "", false, err
+	}
+
+	m, err := chip8.ParseMode(mode)
+	if err != nil {
+		return "", false, err
+	}
+	sp, err := chip8.ParseStackPolicy(stackPolicy)
+	if err != nil {
+		return "", false, err
+	}
+	if cycleAccurate && !clockSpeedGiven(cmd) {
+		refreshRate = chip8.VIPClockSpeed
+	}
+
+	cfg := chip8.Config{
+		IdleWindow:    idleWindow,
+		MemorySize:    memorySize,
+		StackDepth:    stackDepth,
+		StackPolicy:   sp,
+		CycleAccurate: cycleAccurate,
+		Mode:          m,
+		Quirks:        quirks,
+		Headless:      true,
+		Seed:          rp.Seed,
+		NoKeyRepeat:   noKeyRepeat,
+	}
+	if err := rp.CheckSettings(refreshRate, cfg); err != nil {
+		return "", false, err
+	}
+	vm, err := chip8.NewVM(bytes.NewReader(rom), refreshRate, cfg)
+	if err != nil {
+		return "", false, fmt.Errorf("\nerror creating a new chip-8 VM: %v\n", err)
+	}
+	vm.RunReplay(rp)
+
+	got := vm.FrameHash()
+	return got, strings.EqualFold(got, expected), nil
+}
+
+// loadReplay reads the input log at path
+func loadReplay(path string) (*chip8.Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return chip8.ParseReplay(f)
+}
+
+// applyReplaySettings runs with the clock speed, mode, and quirks the input log was recorded with, setting
+// their flags the way a profile does. Flags given on the command line are left alone, and the VM refuses to
+// play the log back if they don't match it. Logs that don't record their settings change nothing.
+func applyReplaySettings(cmd *cobra.Command, rp *chip8.Replay) error {
+	if rp.ClockSpeed == 0 {
+		return nil
+	}
+	settings := [][2]string{
+		{"ips", fmt.Sprint(rp.ClockSpeed)},
+		{"cycle-accurate", fmt.Sprint(rp.CycleAccurate)},
+		{"mode", rp.Mode.String()},
+	}
+	if !quirksGiven(cmd) {
+		for _, qf := range quirkFlags {
+			settings = append(settings, [2]string{qf.name, fmt.Sprint(*qf.field(&rp.Quirks))})
+		}
+	}
+	for _, s := range settings {
+		if cmd.Flags().Changed(s[0]) || s[0] == "ips" && clockSpeedGiven(cmd) {
+			continue
+		}
+		if err := cmd.Flags().Set(s[0], s[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pongLog holds Pong's left paddle up for a while, so the final frame depends on the replayed input
+const pongLog = `seed 7
+cycles 3000
+ips 700
+mode chip8
+400 1 down
+1200 1 up
+`
+
+// pongHash is the frame pongLog ends on
+const pongHash = "0b29bd3c539a50fcfbb85fee5d448be6a07f5b1c8a5f3edafbac148bec3c63d4"
+
+func TestVerifyReplay(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "input.log")
+	if err := os.WriteFile(logPath, []byte(pongLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		expect string
+		wantOK bool
+	}{
+		{name: "matching hash", expect: pongHash, wantOK: true},
+		{name: "matching hash in upper case", expect: "0B29BD3C539A50FCFBB85FEE5D448BE6A07F5B1C8A5F3EDAFBAC148BEC3C63D4", wantOK: true},
+		{name: "mismatching hash", expect: "0b55c80e6dca08c065e81b6e2c5228a3fbab7d6daef26a1092eccf1617b593e8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := verifyReplay(verifyReplayCmd, "../roms/pong.ch8", logPath, tt.expect)
+			if err != nil {
+				t.Fatalf("verifyReplay: %v", err)
+			}
+			if got != pongHash {
+				t.Errorf("final frame hash = %s, want %s", got, pongHash)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("verifyReplay ok = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
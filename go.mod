@@ -7,6 +7,7 @@ require (
 	github.com/faiface/pixel v0.10.0
 	github.com/spf13/cobra v1.7.0
 	golang.org/x/image v0.8.0
+	golang.org/x/term v0.10.0
 )
 
 require (
@@ -23,5 +24,5 @@ require (
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/exp/shiny v0.0.0-20230522175609-2e198f4a06a1 // indirect
 	golang.org/x/mobile v0.0.0-20230531173138-3c911d8e3eda // indirect
-	golang.org/x/sys v0.9.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 )
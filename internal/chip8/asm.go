@@ -0,0 +1,330 @@
+package chip8
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// asmLine is one line of assembly that lays down bytes, with the address they start at
+type asmLine struct {
+	num  int
+	op   string
+	args []string
+	addr uint16
+}
+
+// asmError is an error on one line of assembly
+type asmError struct {
+	line int
+	err  error
+}
+
+func (e asmError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// Assemble compiles CHIP-8 assembly into a ROM to load at 0x200. Instructions are written with Cowgod's
+// mnemonics, e.g. "LD V0, 0x10" or "DRW V0, V1, 5", one to a line, and ; starts a comment. A line can start
+// with a label, "loop:", that JP, CALL, LD I and the like take in place of an address, and DB lays down raw
+// bytes, e.g. "DB 0xF0, 0x90, 0xF0" for a sprite. Numbers are decimal, or hex, binary or octal with a 0x,
+// 0b or 0o prefix. Each encoded instruction is decoded again with Mnemonic, so what the mode doesn't have
+// is an error. Every line with an error is reported, each with its line number.
+func Assemble(src io.Reader, mode Mode) ([]byte, error) {
+	var lines []asmLine
+	var errs []asmError
+	labels := make(map[string]uint16)
+	addr := DefaultStartAddress
+
+	// The first pass finds where every label points, so jumps can go forwards
+	sc := bufio.NewScanner(src)
+	for num := 1; sc.Scan(); num++ {
+		text, _, _ := strings.Cut(sc.Text(), ";")
+		text = strings.TrimSpace(text)
+		for {
+			label, rest, ok := strings.Cut(text, ":")
+			if !ok || !isLabel(label) {
+				break
+			}
+			if _, dup := labels[label]; dup {
+				errs = append(errs, asmError{num, fmt.Errorf("label %q is already defined", label)})
+			}
+			labels[label] = addr
+			text = strings.TrimSpace(rest)
+		}
+		if text == "" {
+			continue
+		}
+
+		l := asmLine{num: num, addr: addr}
+		op, rest, _ := strings.Cut(text, " ")
+		l.op = strings.ToUpper(op)
+		if rest = strings.TrimSpace(rest); rest != "" {
+			for _, arg := range strings.Split(rest, ",") {
+				l.args = append(l.args, strings.TrimSpace(arg))
+			}
+		}
+		lines = append(lines, l)
+		addr += asmSize(l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	var rom []byte
+	for _, l := range lines {
+		b, err := encodeLine(l, labels, mode)
+		if err != nil {
+			errs = append(errs, asmError{l.num, err})
+			continue
+		}
+		rom = append(rom, b...)
+	}
+	if len(errs) > 0 {
+		// Labels are checked a pass ahead of everything else, so the errors are put back in line order
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].line < errs[j].line })
+		joined := make([]error, len(errs))
+		for i, e := range errs {
+			joined[i] = e
+		}
+		return nil, errors.Join(joined...)
+	}
+	return rom, nil
+}
+
+// isLabel reports whether s can name a label: letters, digits and underscores, not starting with a digit
+func isLabel(s string) bool {
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// asmSize is how many bytes a line lays down: one per DB operand, 4 for XO-CHIP's LD I, LONG, and 2 otherwise
+func asmSize(l asmLine) uint16 {
+	switch {
+	case l.op == "DB":
+		return uint16(len(l.args))
+	case l.op == "LD" && len(l.args) == 2 && isLong(l.args[1]):
+		return 4
+	default:
+		return 2
+	}
+}
+
+// isLong reports whether an LD I operand is a 16-bit "LONG addr", which F000 NNNN loads
+func isLong(arg string) bool {
+	return len(arg) > 5 && strings.EqualFold(arg[:5], "LONG ")
+}
+
+// encodeLine lays down the bytes for one line, checking that any instruction is one the mode has
+func encodeLine(l asmLine, labels map[string]uint16, mode Mode) ([]byte, error) {
+	if l.op == "DB" {
+		if len(l.args) == 0 {
+			return nil, errors.New("DB needs at least one byte")
+		}
+		b := make([]byte, len(l.args))
+		for i, arg := range l.args {
+			v, err := asmValue(arg, labels, 0xFF)
+			if err != nil {
+				return nil, err
+			}
+			b[i] = byte(v)
+		}
+		return b, nil
+	}
+
+	opcode, long, err := encode(l.op, l.args, labels)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := Mnemonic(opcode, mode); !ok {
+		return nil, fmt.Errorf("%s isn't a %v instruction", strings.TrimSpace(l.op+" "+strings.Join(l.args, ", ")), mode)
+	}
+	b := []byte{byte(opcode >> 8), byte(opcode)}
+	if opcode == 0xF000 {
+		b = append(b, byte(long>>8), byte(long))
+	}
+	return b, nil
+}
+
+// encode turns a mnemonic and its operands into an opcode, and the address that follows F000 NNNN
+func encode(op string, args []string, labels map[string]uint16) (uint16, uint16, error) {
+	// Operands are checked against what each form expects, in the order they're tried
+	invalid := fmt.Errorf("invalid operands for %s: %q", op, strings.Join(args, ", "))
+	argc := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s takes %d operands, got %d", op, n, len(args))
+		}
+		return nil
+	}
+	reg := func(i int) (uint16, bool) { return asmRegister(args[i]) }
+	is := func(i int, name string) bool { return strings.EqualFold(args[i], name) }
+	value := func(i int, max uint16) (uint16, error) { return asmValue(args[i], labels, max) }
+
+	switch op {
+	case "CLS", "RET", "SCR", "SCL", "EXIT", "LOW", "HIGH", "AUDIO":
+		if err := argc(0); err != nil {
+			return 0, 0, err
+		}
+		return map[string]uint16{
+			"CLS": 0x00E0, "RET": 0x00EE, "SCR": 0x00FB, "SCL": 0x00FC,
+			"EXIT": 0x00FD, "LOW": 0x00FE, "HIGH": 0x00FF, "AUDIO": 0xF002,
+		}[op], 0, nil
+
+	case "SCD":
+		if err := argc(1); err != nil {
+			return 0, 0, err
+		}
+		n, err := value(0, 0xF)
+		return 0x00C0 | n, 0, err
+
+	case "SYS", "CALL", "JP":
+		if op == "JP" && len(args) == 2 {
+			if !is(0, "V0") {
+				return 0, 0, invalid
+			}
+			nnn, err := value(1, 0xFFF)
+			return 0xB000 | nnn, 0, err
+		}
+		if err := argc(1); err != nil {
+			return 0, 0, err
+		}
+		nnn, err := value(0, 0xFFF)
+		return map[string]uint16{"SYS": 0x0000, "JP": 0x1000, "CALL": 0x2000}[op] | nnn, 0, err
+
+	case "SE", "SNE", "ADD", "RND":
+		if err := argc(2); err != nil {
+			return 0, 0, err
+		}
+		if op == "ADD" && is(0, "I") {
+			x, ok := reg(1)
+			if !ok {
+				return 0, 0, invalid
+			}
+			return 0xF01E | x<<8, 0, nil
+		}
+		x, ok := reg(0)
+		if !ok {
+			return 0, 0, invalid
+		}
+		if y, ok := reg(1); ok && op != "RND" {
+			return map[string]uint16{"SE": 0x5000, "SNE": 0x9000, "ADD": 0x8004}[op] | x<<8 | y<<4, 0, nil
+		}
+		nn, err := value(1, 0xFF)
+		return map[string]uint16{"SE": 0x3000, "SNE": 0x4000, "ADD": 0x7000, "RND": 0xC000}[op] | x<<8 | nn, 0, err
+
+	case "OR", "AND", "XOR", "SUB", "SUBN", "SHR", "SHL":
+		// SHR and SHL shift VX in place unless given a VY to shift into it
+		if len(args) == 1 && (op == "SHR" || op == "SHL") {
+			args = []string{args[0], args[0]}
+		}
+		if err := argc(2); err != nil {
+			return 0, 0, err
+		}
+		x, okx := reg(0)
+		y, oky := reg(1)
+		if !okx || !oky {
+			return 0, 0, invalid
+		}
+		n := map[string]uint16{"OR": 0x1, "AND": 0x2, "XOR": 0x3, "SUB": 0x5, "SHR": 0x6, "SUBN": 0x7, "SHL": 0xE}[op]
+		return 0x8000 | x<<8 | y<<4 | n, 0, nil
+
+	case "DRW":
+		if err := argc(3); err != nil {
+			return 0, 0, err
+		}
+		x, okx := reg(0)
+		y, oky := reg(1)
+		if !okx || !oky {
+			return 0, 0, invalid
+		}
+		n, err := value(2, 0xF)
+		return 0xD000 | x<<8 | y<<4 | n, 0, err
+
+	case "SKP", "SKNP", "PITCH":
+		if err := argc(1); err != nil {
+			return 0, 0, err
+		}
+		x, ok := reg(0)
+		if !ok {
+			return 0, 0, invalid
+		}
+		return map[string]uint16{"SKP": 0xE09E, "SKNP": 0xE0A1, "PITCH": 0xF03A}[op] | x<<8, 0, nil
+
+	case "LD":
+		if err := argc(2); err != nil {
+			return 0, 0, err
+		}
+		if x, ok := reg(0); ok {
+			if y, ok := reg(1); ok {
+				return 0x8000 | x<<8 | y<<4, 0, nil
+			}
+			for name, nn := range map[string]uint16{"DT": 0x07, "K": 0x0A, "[I]": 0x65, "R": 0x85} {
+				if is(1, name) {
+					return 0xF000 | x<<8 | nn, 0, nil
+				}
+			}
+			nn, err := value(1, 0xFF)
+			return 0x6000 | x<<8 | nn, 0, err
+		}
+		if is(0, "I") {
+			if isLong(args[1]) {
+				long, err := asmValue(strings.TrimSpace(args[1][5:]), labels, 0xFFFF)
+				return 0xF000, long, err
+			}
+			nnn, err := value(1, 0xFFF)
+			return 0xA000 | nnn, 0, err
+		}
+		x, ok := reg(1)
+		if !ok {
+			return 0, 0, invalid
+		}
+		for name, nn := range map[string]uint16{"DT": 0x15, "ST": 0x18, "F": 0x29, "HF": 0x30, "B": 0x33, "[I]": 0x55, "R": 0x75} {
+			if is(0, name) {
+				return 0xF000 | x<<8 | nn, 0, nil
+			}
+		}
+		return 0, 0, invalid
+	}
+	return 0, 0, fmt.Errorf("unknown instruction %q", op)
+}
+
+// asmRegister parses V0 to VF
+func asmRegister(s string) (uint16, bool) {
+	if len(s) != 2 || (s[0] != 'V' && s[0] != 'v') {
+		return 0, false
+	}
+	x, err := strconv.ParseUint(s[1:], 16, 4)
+	return uint16(x), err == nil
+}
+
+// asmValue parses a number or a label's address, no bigger than max
+func asmValue(s string, labels map[string]uint16, max uint16) (uint16, error) {
+	if addr, ok := labels[s]; ok {
+		if addr > max {
+			return 0, fmt.Errorf("label %q is at 0x%X, past 0x%X", s, addr, max)
+		}
+		return addr, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		if isLabel(s) {
+			return 0, fmt.Errorf("undefined label %q", s)
+		}
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	if v > uint64(max) {
+		return 0, fmt.Errorf("%s is bigger than 0x%X", s, max)
+	}
+	return uint16(v), nil
+}
@@ -0,0 +1,160 @@
+package chip8
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// asmFor writes opcode back out in assembly, going by the pattern Mnemonic names it with
+func asmFor(opcode uint16, pattern string) string {
+	x, y, n, nn, nnn := opcode>>8&0xF, opcode>>4&0xF, opcode&0xF, opcode&0xFF, opcode&0xFFF
+	return map[string]string{
+		"00E0": "CLS",
+		"00EE": "RET",
+		"00CN": fmt.Sprintf("SCD %d", n),
+		"00FB": "SCR",
+		"00FC": "SCL",
+		"00FD": "EXIT",
+		"00FE": "LOW",
+		"00FF": "HIGH",
+		"0NNN": fmt.Sprintf("SYS 0x%03X", nnn),
+		"1NNN": fmt.Sprintf("JP 0x%03X", nnn),
+		"2NNN": fmt.Sprintf("CALL 0x%03X", nnn),
+		"3XNN": fmt.Sprintf("SE V%X, 0x%02X", x, nn),
+		"4XNN": fmt.Sprintf("SNE V%X, 0x%02X", x, nn),
+		"5XY0": fmt.Sprintf("SE V%X, V%X", x, y),
+		"6XNN": fmt.Sprintf("LD V%X, 0x%02X", x, nn),
+		"7XNN": fmt.Sprintf("ADD V%X, 0x%02X", x, nn),
+		"8XY0": fmt.Sprintf("LD V%X, V%X", x, y),
+		"8XY1": fmt.Sprintf("OR V%X, V%X", x, y),
+		"8XY2": fmt.Sprintf("AND V%X, V%X", x, y),
+		"8XY3": fmt.Sprintf("XOR V%X, V%X", x, y),
+		"8XY4": fmt.Sprintf("ADD V%X, V%X", x, y),
+		"8XY5": fmt.Sprintf("SUB V%X, V%X", x, y),
+		"8XY6": fmt.Sprintf("SHR V%X, V%X", x, y),
+		"8XY7": fmt.Sprintf("SUBN V%X, V%X", x, y),
+		"8XYE": fmt.Sprintf("SHL V%X, V%X", x, y),
+		"9XY0": fmt.Sprintf("SNE V%X, V%X", x, y),
+		"ANNN": fmt.Sprintf("LD I, 0x%03X", nnn),
+		"BNNN": fmt.Sprintf("JP V0, 0x%03X", nnn),
+		"CXNN": fmt.Sprintf("RND V%X, 0x%02X", x, nn),
+		"DXY0": fmt.Sprintf("DRW V%X, V%X, 0", x, y),
+		"DXYN": fmt.Sprintf("DRW V%X, V%X, %d", x, y, n),
+		"EX9E": fmt.Sprintf("SKP V%X", x),
+		"EXA1": fmt.Sprintf("SKNP V%X", x),
+		"F000": "LD I, LONG 0x1234",
+		"F002": "AUDIO",
+		"FX07": fmt.Sprintf("LD V%X, DT", x),
+		"FX0A": fmt.Sprintf("LD V%X, K", x),
+		"FX15": fmt.Sprintf("LD DT, V%X", x),
+		"FX18": fmt.Sprintf("LD ST, V%X", x),
+		"FX1E": fmt.Sprintf("ADD I, V%X", x),
+		"FX29": fmt.Sprintf("LD F, V%X", x),
+		"FX30": fmt.Sprintf("LD HF, V%X", x),
+		"FX33": fmt.Sprintf("LD B, V%X", x),
+		"FX3A": fmt.Sprintf("PITCH V%X", x),
+		"FX55": fmt.Sprintf("LD [I], V%X", x),
+		"FX65": fmt.Sprintf("LD V%X, [I]", x),
+		"FX75": fmt.Sprintf("LD R, V%X", x),
+		"FX85": fmt.Sprintf("LD V%X, R", x),
+	}[pattern]
+}
+
+// Every opcode Mnemonic decodes in a mode, written back out in assembly, assembles to the same instruction
+func TestAssembleRoundTrip(t *testing.T) {
+	for _, m := range Modes {
+		for op := 0; op <= 0xFFFF; op++ {
+			opcode := uint16(op)
+			text, ok := Mnemonic(opcode, m)
+			if !ok {
+				continue
+			}
+			pattern, _, _ := strings.Cut(text, " ")
+			src := asmFor(opcode, pattern)
+			if src == "" {
+				t.Fatalf("%s: no assembly for %s", m, pattern)
+			}
+			// Operand bits the VM ignores, like 5XY0's low nibble, aren't written out, so it's the decoded
+			// instruction that has to come back the same rather than every bit of it
+			rom, err := Assemble(strings.NewReader(src), m)
+			if err != nil || len(rom) < 2 {
+				t.Errorf("%s: %q (%s) didn't assemble: %v", m, src, text, err)
+				continue
+			}
+			if got, _ := Mnemonic(uint16(rom[0])<<8|uint16(rom[1]), m); got != text {
+				t.Errorf("%s: %q assembled to % X, %q, want %q", m, src, rom, got, text)
+			}
+			if opcode == 0xF000 && !bytes.Equal(rom[2:], []byte{0x12, 0x34}) {
+				t.Errorf("%s: %q assembled to % X, want the address after the opcode", m, src, rom)
+			}
+		}
+	}
+
+	// Instructions the mode doesn't have are an error even though they encode
+	for _, src := range []string{"HIGH", "SCD 2", "AUDIO", "LD HF, V1", "LD R, V1"} {
+		if _, err := Assemble(strings.NewReader(src), ModeChip8); err == nil {
+			t.Errorf("%q assembled for chip8", src)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	src := `; draws a 0 and loops forever
+start:
+	LD I, sprite  ; forward reference
+	ld v0, 10
+	DRW V0, V1, 3
+loop: JP loop
+sprite: DB 0xF0, 0b10010000, 240
+`
+	want := []byte{0xA2, 0x08, 0x60, 0x0A, 0xD0, 0x13, 0x12, 0x06, 0xF0, 0x90, 0xF0}
+	rom, err := Assemble(strings.NewReader(src), ModeChip8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rom, want) {
+		t.Errorf("Assemble = % X, want % X", rom, want)
+	}
+
+	// Long loads take 4 bytes, so labels after one move along by 4
+	rom, err = Assemble(strings.NewReader("LD I, LONG data\nJP end\nend: DB 1\ndata: DB 2"), ModeXOChip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0xF0, 0x00, 0x02, 0x07, 0x12, 0x06, 0x01, 0x02}; !bytes.Equal(rom, want) {
+		t.Errorf("Assemble = % X, want % X", rom, want)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	src := `CLS
+	LD V0, 256
+again:
+again: RET
+	JP nowhere
+	DRW V0, VG, 1
+	DB
+	MOV V0, V1
+	SHR V0, 1
+	CLS V0
+`
+	_, err := Assemble(strings.NewReader(src), ModeChip8)
+	if err == nil {
+		t.Fatal("Assemble didn't fail")
+	}
+	want := []string{
+		"line 2: 256 is bigger than 0xFF",
+		`line 4: label "again" is already defined`,
+		`line 5: undefined label "nowhere"`,
+		`line 6: invalid operands for DRW: "V0, VG, 1"`,
+		"line 7: DB needs at least one byte",
+		`line 8: unknown instruction "MOV"`,
+		`line 9: invalid operands for SHR: "V0, 1"`,
+		"line 10: CLS takes 0 operands, got 1",
+	}
+	if got := strings.Split(err.Error(), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors =\n%s\nwant\n%s", err, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,88 @@
+package chip8
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Bounds on the cycles per 60Hz frame the auto tuner will pick
+	minCyclesPerFrame = 1
+	maxCyclesPerFrame = 50
+
+	// How often the auto tuner compares the cycles run against the cycles asked for
+	tuneWindow = time.Second
+)
+
+// speedTuner ramps the number of cycles run per 60Hz frame up for as long as the
+// host keeps pace, then settles once it finds the point where frames start dropping
+type speedTuner struct {
+	perFrame int
+	settled  bool
+
+	// Ticks handled since the current window started
+	ticks       int
+	windowStart time.Time
+}
+
+func newSpeedTuner(clockSpeed int) *speedTuner {
+	return &speedTuner{perFrame: clamp(clockSpeed/60, minCyclesPerFrame, maxCyclesPerFrame)}
+}
+
+// observe takes the cycles the VM managed to run in a window against the cycles it should have run, and
+// returns the cycles per frame to use from now on. While ramping it grows by a quarter each window the
+// host keeps up; falling behind backs off and, the first time, settles on the slower speed.
+func (t *speedTuner) observe(ran, want int) int {
+	ratio := float64(ran) / float64(want)
+
+	switch {
+	case ratio < 0.9:
+		t.perFrame = clamp(t.perFrame*3/4, minCyclesPerFrame, maxCyclesPerFrame)
+		t.settled = true
+	case ratio >= 0.98 && !t.settled:
+		t.perFrame = clamp(t.perFrame+max(1, t.perFrame/4), minCyclesPerFrame, maxCyclesPerFrame)
+		t.settled = t.perFrame == maxCyclesPerFrame
+	}
+
+	return t.perFrame
+}
+
+// tick counts a clock tick and, at the end of each window, retunes the VM's clock
+func (vm *VM) tick() {
+	t := vm.speed
+	if t == nil || vm.fastForwarding {
+		return
+	}
+
+	now := time.Now()
+	if t.windowStart.IsZero() {
+		t.windowStart = now
+	}
+	t.ticks++
+
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < tuneWindow {
+		return
+	}
+
+	// Turbo scales the speed the tuner picked, so the cycles asked for are at the scaled speed
+	want := int(float64(vm.clockSpeed) * elapsed.Seconds())
+	prev, settled := t.perFrame, t.settled
+	if t.observe(t.ticks, want) != prev {
+		vm.baseClockSpeed = t.perFrame * 60
+		vm.applyTurbo()
+	}
+	if t.settled && !settled {
+		msg := fmt.Sprintf("auto-speed: settled on %d cycles per frame (%dHz)", t.perFrame, t.perFrame*60)
+		if t.perFrame == maxCyclesPerFrame {
+			msg = fmt.Sprintf("auto-speed: settled on the maximum of %d cycles per frame (%dHz)", t.perFrame, t.perFrame*60)
+		}
+		vm.log.Log("auto_speed", msg, Fields{"cycles_per_frame": t.perFrame, "clock_speed": t.perFrame * 60})
+	}
+	t.ticks = 0
+	t.windowStart = now
+}
+
+func clamp(n, lo, hi int) int {
+	return max(lo, min(n, hi))
+}
@@ -0,0 +1,39 @@
+package chip8
+
+import "testing"
+
+func TestSpeedTuner(t *testing.T) {
+	tests := []struct {
+		name string
+		// The most cycles a second the simulated host can run
+		capacity int
+		want     int
+	}{
+		// 11 grows to 13, 16, 20, then 25 asks for 1500 and backs off to 18
+		{name: "slow host", capacity: 1300, want: 18},
+		{name: "fast host", capacity: 100000, want: maxCyclesPerFrame},
+		{name: "host slower than the start", capacity: 300, want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newSpeedTuner(DefaultClockSpeed)
+			windows := 0
+			for ; !st.settled; windows++ {
+				if windows == 100 {
+					t.Fatalf("didn't settle after %d windows, at %d cycles per frame", windows, st.perFrame)
+				}
+				want := st.perFrame * 60
+				st.observe(min(want, tt.capacity), want)
+			}
+			if st.perFrame != tt.want {
+				t.Errorf("settled on %d cycles per frame, want %d", st.perFrame, tt.want)
+			}
+
+			// Once settled, keeping up leaves the speed alone
+			want := st.perFrame * 60
+			if got := st.observe(want, want); got != tt.want {
+				t.Errorf("settled speed moved to %d", got)
+			}
+		})
+	}
+}
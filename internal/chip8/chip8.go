@@ -5,14 +5,24 @@
 package chip8
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"image/color"
+	"io"
+	"math/rand"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
 	"github.com/bradford-hamilton/chippy/internal/pixel"
-	"github.com/faiface/beep/mp3"
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+	"github.com/faiface/beep"
 	"github.com/faiface/beep/speaker"
 )
 
@@ -44,8 +54,12 @@ import (
 
 // VM represents the chip-8 virtual machine
 type VM struct {
-	// Chip-8 system memory, see memory map above
-	memory [4096]byte
+	// Written for the length of every clock cycle, and read by the exported methods that look at the
+	// machine, so they can be called from any goroutine while the VM runs
+	mu sync.RWMutex
+
+	// Chip-8 system memory, see memory map above. 4K, 64K in XO-CHIP mode, unless configured otherwise
+	memory []byte
 
 	// Opcode under examination
 	opcode uint16
@@ -53,20 +67,23 @@ type VM struct {
 	// 8-bit general purpose register, (V0 - VE*)
 	v [16]byte
 
-	// index register (0x000 to 0xFFF)
+	// index register (0x000 to the end of memory)
 	i uint16
 
-	// Program counter (0x000 to 0xFFF)
+	// Program counter (0x000 to the end of memory)
 	pc uint16
 
-	// Internal stack to store return addresses when calling procedures
-	stack [16]uint16
+	// Internal stack to store return addresses when calling procedures, 16 deep unless configured
+	// otherwise, and what happens when a ROM calls past the end of it or returns with it empty
+	stack       []uint16
+	stackPolicy StackPolicy
 
 	// Stack pointer is used to store return locations from the program counter register
 	sp uint16
 
-	// Represents window pixels. Bytes get flipped on and off inside to guide drawing
-	gfx [64 * 32]byte
+	// Represents window pixels. Bytes get flipped on and off inside to guide drawing. It's sized for
+	// SUPER-CHIP's 128x64 extended mode, and only the active resolution's share of it is used.
+	gfx [128 * 64]byte
 
 	// 8-bit delay timer which counts down at 60 hertz, until it reaches 0
 	delayTimer byte
@@ -74,6 +91,18 @@ type VM struct {
 	// 8-bit sound timer which counts down at 60 hertz, until it reaches 0
 	soundTimer byte
 
+	// Whether the sound timer is running, readable from other goroutines through SoundPlaying
+	soundPlaying atomic.Bool
+
+	// OnSoundStateChange, when set, is called from the VM's goroutine as the sound starts and stops
+	OnSoundStateChange func(playing bool)
+
+	// Which CHIP-8 dialect the ROM is interpreted as
+	mode Mode
+
+	// Interpreter behaviors the ROM expects
+	quirks Quirks
+
 	// Keypad is HEX based: 0x0-0xF
 	//  1  2  3  C
 	//  4  5  6  D
@@ -81,119 +110,892 @@ type VM struct {
 	//  A  0  B  F
 	keypad [16]byte
 
+	// Which keys are physically held down, and FX0A's progress while it stalls: whether it is waiting,
+	// and the key pressed since it started waiting, or -1 until one is
+	keysHeld    [16]bool
+	awaitingKey bool
+	awaitedKey  int
+
 	// Chippy doesn't draw on every cycle, set draw flag when we need to update screen.
 	drawFlag bool
 
-	// Embedded pixel window for displaying ROMs
-	window *pixel.Window
+	// Pixels sprites collided on since the last frame was drawn, tracked when highlighting collisions
+	highlightCollisions bool
+	collided            []uint16
+
+	// How long to pause after each sprite draw, and the pixels drawn since the last frame, when stepping through draws
+	drawStep time.Duration
+	drawn    []uint16
+
+	// Watches for ROMs spinning in place so we can stop executing them until something changes
+	idle idleDetector
+
+	// Number of clock cycles since the VM started, idle ones included
+	cycles uint64
+
+	// Running totals for anything watching the VM from another goroutine
+	stats Stats
+
+	// When set, every executed cycle's StepResult is written to events and passed to onStep
+	events *json.Encoder
+	onStep func(StepResult)
+
+	// The memory addresses the instruction being stepped changed, noted by store only while step runs
+	trackWrites bool
+	written     []int
+
+	// The StepResult of the instruction the debugger or draw step is about to report, nil once reported
+	lastStep *StepResult
+
+	// Embedders' hooks, see Config
+	onCycle     func(pc, opcode uint16)
+	onDraw      func(gfx []byte, cols int)
+	onCollision func()
+	onBeep      func()
+
+	// How many times each unknown opcode was hit, and whether to print them as they happen
+	unknownOps  map[unknownOpSite]int
+	showUnknown bool
+
+	// Whether an unknown opcode halts the VM rather than being skipped
+	haltOnUnknown bool
+
+	// Set once a 0NNN machine code call has been skipped and logged
+	machineCodeLogged bool
+
+	// Tickers that repeat a held key and how often they fire, see handleKeyInput. With noKeyRepeat the
+	// keypad shows which keys are held instead, and reading a key doesn't clear it.
+	keyRepeat    [16]*time.Ticker
+	keyRepeatDur time.Duration
+	noKeyRepeat  bool
+
+	// Display for showing ROMs, a pixel window unless the VM is headless
+	window   Display
+	headless bool
+
+	// Run stops after this many clock cycles, 0 meaning it runs until stopped, and once exitAfter has
+	// passed since it started, at deadline, 0 meaning no time limit
+	maxCycles uint64
+	exitAfter time.Duration
+	deadline  time.Time
 
-	// Our "CPU clock"
+	// Set once the ROM exits with SUPER-CHIP's 00FD, or the VM stops on err
+	exited bool
+	err    error
+
+	// next gives the playlist's next ROM, nil without a playlist
+	next func() (PlaylistROM, bool, error)
+
+	// strict lets a panic executing an instruction crash the process instead of stopping the VM
+	strict bool
+
+	// A halted VM stops executing until it is resumed. breakOnCollision halts it
+	// on the first sprite collision, and is disarmed once it has.
+	halted           bool
+	breakOnCollision bool
+
+	// Set while the user has paused the VM, see pause
+	paused bool
+
+	// Recent states to step back through, nil when rewinding is off, and whether the rewind key is held, see rewind.go
+	rewind    *rewinder
+	rewinding bool
+
+	// The ROM and presets the VM was started with, kept so Reset can start over, and the address the
+	// ROM is loaded and started at
+	rom        []byte
+	startAddr  uint16
+	regPresets []RegPreset
+	memPresets []MemPreset
+
+	// SUPER-CHIP's extended screen mode, switched with 00FF and 00FE
+	hires bool
+
+	// The 0-F glyphs FX29 points at and SUPER-CHIP's large ones FX30 points at, kept so Reset can reload them
+	font      [80]byte
+	largeFont [160]byte
+
+	// SUPER-CHIP's RPL user flags that FX75 and FX85 save registers to. The HP48 only
+	// had 8 of them, but chippy allows all 16 in both modes, as XO-CHIP does.
+	rpl [16]byte
+
+	// Where quick saves go, see quickSave
+	statePath string
+
+	// Step debugger state, see debugger.go
+	debugging   bool
+	breakpoints map[uint16]bool
+	stepping    bool
+	stepFrom    uint16
+	resuming    bool
+
+	// Source of CXNN's random numbers, seeded so runs can be reproduced
+	rng *rand.Rand
+
+	// The input log being recorded and where it's written when Run stops, and the played back key events
+	// yet to come, see replay.go. While playing back the keyboard doesn't reach the keypad.
+	inputLog     *Replay
+	inputLogPath string
+	playback     []InputEvent
+	playingBack  bool
+
+	// The background and foreground colors screenshots are drawn in, and where F12 saves them
+	palette       color.Palette
+	screenshotDir string
+
+	// Captures the session as a GIF when recording, see record.go
+	recorder *recorder
+
+	// Wakes the run loop 60 times a second to run the clock cycles owed since it last woke. The loop
+	// keeps time by the wall clock rather than trusting the ticker, see nextTick.
 	Clock *time.Ticker
 
-	// Channel for sending/receiving audio events
+	// When the run loop last woke, and how many clock cycles it owes for the time since
+	lastTick time.Time
+	owed     float64
+
+	// The clock's speed in Hz, and how far the timers are through their next 60Hz tick measured in
+	// clock cycles times 60. Keeping timers on the clock rather than their own ticker keeps runs reproducible.
+	clockSpeed int
+	timerPhase int
+
+	// cycleAccurate counts the clock in the COSMAC VIP's machine cycles, each instruction taking as many as it did
+	cycleAccurate bool
+
+	// vblank is set for the clock cycle after the timers tick, the vertical blank DisplayWait draws in
+	vblank bool
+
+	// The clock speed asked for, or picked by auto speed, before turbo doubles or halves it turbo times
+	baseClockSpeed int
+	turbo          int
+
+	// How many times faster the VM runs while Tab is held, and whether it is
+	fastForward    int
+	fastForwarding bool
+
+	// Whether gfx changed since the last frame was presented, and when that was. Frames are presented
+	// at most 60 times a second so a fast clock isn't held back waiting on the window's vsync.
+	frameDirty bool
+	lastFrame  time.Time
+
+	// Retunes the clock to the fastest speed the host keeps up with, when auto speed is on
+	speed *speedTuner
+
+	// The frames and instructions per second shown over the window, see speedOverlay
+	overlay speedOverlay
+
+	// A second window showing memory live, nil unless asked for, and when it was last drawn
+	memView     MemoryView
+	lastMemView time.Time
+
+	// Channel for sending/receiving audio events, one each time a beep starts, for displays that ring a
+	// bell in place of the tone
 	audioC chan struct{}
 
-	// Channel for sending/receiving a shutdown signal
+	// The beep tone, streamed to the speaker and sounding while the sound timer runs. Nil in XO-CHIP
+	// mode, where pattern plays instead.
+	tone *tonePlayer
+
+	// Silences the beep and XO-CHIP audio without touching the sound timer, see toggleMute
+	muted bool
+
+	// XO-CHIP's audio pattern, set with F002, and the pitch it plays at, set with FX3A. In XO-CHIP mode
+	// the pattern plays through pattern for as long as the sound timer runs, instead of the beep.
+	soundBuffer [16]byte
+	pitch       byte
+	pattern     *patternPlayer
+
+	// Channel for sending/receiving a shutdown signal. It is buffered so Run can
+	// signal that it stopped without waiting for anyone to be listening.
 	ShutdownC chan struct{}
+
+	// Makes sure the shutdown is only signalled once, however many times Run returns
+	shutdown sync.Once
+
+	// Where the events in the VM's life are reported, see Logger
+	log Logger
 }
 
 const (
-	keyRepeatDur = time.Second / 5
-	maxRomSize   = 0xFFF - 0x200
+	defaultKeyRepeat = time.Second / 5
+
+	// Where the large font is loaded, right after the standard font
+	largeFontAddr = 0x50
+
+	// Standard CHIP-8 RAM is 4K, XO-CHIP ROMs can address up to 64K
+	defaultMemorySize = 0x1000
+	maxMemorySize     = 0x10000
+
+	// The largest window scale, a 4096x2048 window
+	maxScale = 64
+
+	// How long control hints stay on screen
+	hintsDuration = 10 * time.Second
+
+	// Shortest time between presented frames
+	frameInterval = time.Second / 60
+
+	// The furthest the run loop will fall behind the wall clock before dropping time instead of catching up
+	maxLag = time.Second / 4
+
+	// The beep is a sine tone of defaultBeepHz unless configured otherwise, played for as many timerTicks
+	// as the sound timer runs
+	beepSampleRate beep.SampleRate = 44100
+	defaultBeepHz                  = 440
+	timerTick                      = time.Second / 60
 )
 
+// Config holds the optional settings for a VM. The zero value is a standard CHIP-8 machine.
+type Config struct {
+	// IdleWindow is the longest loop, in instructions, that is recognized as the ROM idling.
+	// 1 only catches a jump to itself, 0 turns idle detection off.
+	IdleWindow int
+
+	// Events, when set, receives a JSON line describing every executed cycle
+	Events io.Writer
+
+	// OnStep, when set, is called with every executed cycle, as written to Events
+	OnStep func(StepResult)
+
+	// Hooks for embedders watching the VM, each called from the VM's goroutine when set:
+	//   - OnCycle with the address and opcode of every instruction, before it runs
+	//   - OnDraw with the screen after every instruction that changes it, cols pixels a row. gfx is
+	//     the VM's own screen, only valid until OnDraw returns
+	//   - OnCollision when a sprite draw turns a pixel off, setting VF
+	//   - OnBeep when the sound timer starts, whether or not the beep is heard
+	OnCycle     func(pc, opcode uint16)
+	OnDraw      func(gfx []byte, cols int)
+	OnCollision func()
+	OnBeep      func()
+
+	// StackDepth is how many calls deep the stack goes, up to 256. Zero gives the usual 16.
+	StackDepth int
+
+	// StackPolicy is what happens when a ROM calls with the stack full or returns with it empty
+	StackPolicy StackPolicy
+
+	// MemorySize is the amount of RAM in bytes, between 4K and 64K. Zero gives 4K, or the 64K F000 NNNN
+	// can address in XO-CHIP mode.
+	MemorySize int
+
+	// StartAddress is where the ROM is loaded and run from, at or above DefaultStartAddress. Zero uses
+	// DefaultStartAddress, and ETI660StartAddress runs programs written for the ETI-660.
+	StartAddress uint16
+
+	// Mode is the CHIP-8 dialect to interpret, ModeChip8 by default
+	Mode Mode
+
+	// Quirks are the interpreter behaviors to emulate
+	Quirks Quirks
+
+	// AutoSpeed lets the VM tune its clock speed, starting from the one it was given
+	AutoSpeed bool
+
+	// CycleAccurate meters the clock in the COSMAC VIP's machine cycles instead of instructions. The clock
+	// speed counts machine cycles a second, VIPClockSpeed being the VIP's own, and each instruction takes
+	// up as many of them as it took the VIP to run, so the timers tick in step with what was run.
+	CycleAccurate bool
+
+	// FastForward is how many times faster the VM runs, timers and all, while Tab is held. Zero gives 10,
+	// and 1 turns it off.
+	FastForward int
+
+	// Display, when set, is drawn to and read from in place of a window, like the terminal backend
+	Display Display
+
+	// Title is the window title, "chippy" when empty
+	Title string
+
+	// Scale is how many window pixels wide each pixel of the 64x32 screen is drawn. Zero uses pixel.DefaultScale.
+	Scale int
+
+	// Fullscreen opens the window fullscreen on the primary monitor. F11 toggles it either way.
+	Fullscreen bool
+
+	// Fade turns pixels off over a few frames instead of at once, to smooth out sprite flicker
+	Fade bool
+
+	// PixelGap leaves a gap between the window's pixels, as a fraction of a pixel's width below 1. Zero draws none.
+	PixelGap float64
+
+	// Overlay shows the frames and instructions run per second in the corner of the window. F3 toggles it either way.
+	Overlay bool
+
+	// MemoryView opens a second window showing memory as a grid of bytes, updated every frame, with the bytes
+	// at the program counter and index register and the calls on the stack highlighted
+	MemoryView bool
+
+	// ScreenshotDir is where F12 saves screenshots, the current directory when empty
+	ScreenshotDir string
+
+	// RecordInput, when set, is the path an input log of the session's key presses is written to when Run
+	// stops, see ParseReplay. PlayInput plays one back, in place of the keyboard, as Run goes. It should
+	// be paired with the log's seed for the run to play out the same, and NewVM fails if the clock speed,
+	// mode, or quirks differ from the ones it was recorded with, see Replay.CheckSettings.
+	RecordInput string
+	PlayInput   *Replay
+
+	// Record, when set, is the path an animated GIF of the session is written to when Run stops,
+	// captured at RecordFPS frames a second of emulated time. Zero RecordFPS uses 25.
+	Record    string
+	RecordFPS int
+
+	// Hints, when set, is shown over the window for the first few seconds to explain the ROM's controls
+	Hints string
+
+	// Headless runs the VM without opening a window
+	Headless bool
+
+	// ShowUnknown prints every unknown opcode as it is hit, instead of only a summary on shutdown
+	ShowUnknown bool
+
+	// HaltOnUnknown halts the VM at an unknown opcode, see halt, instead of skipping over it
+	HaltOnUnknown bool
+
+	// RegPresets and MemPresets set registers and memory once the ROM is loaded, before the first cycle
+	RegPresets []RegPreset
+	MemPresets []MemPreset
+
+	// HighlightCollisions tints the pixels where sprites collided for a frame, to debug drawing
+	HighlightCollisions bool
+
+	// DrawStep pauses for this long after every sprite draw, highlighting the sprite, so drawing can be
+	// watched one sprite at a time. Zero doesn't pause. Headless VMs never pause.
+	DrawStep time.Duration
+
+	// BreakOnCollision halts the VM the first time a sprite draw collides, see halt
+	BreakOnCollision bool
+
+	// Strict lets a panic while executing an instruction crash the process with a stack trace. Otherwise
+	// the VM logs it along with the opcode, program counter, and state, and stops with Err set.
+	Strict bool
+
+	// Next, when set, gives the ROM to run after this one, for playlists. Run carries on with it in the same
+	// window when the window is closed, F2 is pressed, or the ROM exits, and stops once Next reports none are left.
+	// A ROM Next can't open is returned as an error, which is logged before Next is asked for the one after it.
+	Next func() (PlaylistROM, bool, error)
+
+	// MaxCycles stops Run after this many clock cycles. Zero runs until the window is closed.
+	MaxCycles uint64
+
+	// ExitAfter stops Run once it has run this long by the wall clock. Zero has no time limit.
+	ExitAfter time.Duration
+
+	// Seed seeds the random numbers CXNN generates. Zero picks a seed from the current time.
+	Seed int64
+
+	// StatePath is where F5 quick saves the VM's state and F9 loads it from. Empty disables quick saves.
+	StatePath string
+
+	// KeyMap binds the CHIP-8 hex keys to keyboard keys. Nil uses pixel.DefaultKeyMap.
+	KeyMap KeyMap
+
+	// Gamepad reads the keypad from the first connected gamepad too, bound with pixel.DefaultGamepadMap
+	Gamepad bool
+
+	// A key press stays on the keypad until EX9E, EXA1, or FX0A reads it, and while the key is held it's
+	// pressed again every KeyRepeat. Zero repeats every 200ms. NoKeyRepeat turns that off, the keypad
+	// showing just the keys held at that moment like the original hardware.
+	KeyRepeat   time.Duration
+	NoKeyRepeat bool
+
+	// Foreground and Background color the window's lit pixels and background. Nil keeps white on black.
+	Foreground, Background color.Color
+
+	// BeepHz is the frequency of the beep tone. Zero uses 440Hz.
+	BeepHz int
+
+	// MinBeep is the shortest a beep plays for, however briefly the sound timer runs. Zero has no minimum.
+	MinBeep time.Duration
+
+	// Mute starts with the sound off. The sound timer runs as usual, and M toggles it either way.
+	Mute bool
+
+	// RewindDepth is how many snapshots are kept for holding Backspace to step back through, taken every
+	// RewindInterval of play. Zero RewindDepth turns rewinding off and zero RewindInterval uses 100ms.
+	// Headless VMs never rewind.
+	RewindDepth    int
+	RewindInterval time.Duration
+
+	// Font and LargeFont replace the glyphs FX29 and FX30 point the index register at. Nil keeps
+	// pixel.FontSet and pixel.LargeFontSet.
+	Font      *[80]byte
+	LargeFont *[160]byte
+
+	// Logger is told of the events in the VM's life. Nil prints their messages to stdout.
+	Logger Logger
+
+	// Debug starts the VM halted in the step debugger, and Breakpoints drop it into the debugger
+	// whenever the program counter reaches one of them, see debugger.go
+	Debug       bool
+	Breakpoints []uint16
+}
+
+// NewHeadlessVM loads the ROM at pathToROM into a VM with no window, for tests and CI
+// where there's no screen to draw to. It runs until Run is stopped through ShutdownC.
+func NewHeadlessVM(pathToROM string, clockSpeed int) (*VM, error) {
+	f, err := os.Open(pathToROM)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewVM(f, clockSpeed, Config{Headless: true})
+}
+
 // NewVM initializes a Window and a VM, loads the font set and the
 // ROM into memory, and returns a pointer to the VM or an error
-func NewVM(pathToROM string, clockSpeed int) (*VM, error) {
-	window, err := pixel.NewWindow()
-	if err != nil {
-		log.Fatal(err)
+func NewVM(rom io.Reader, clockSpeed int, cfg Config) (*VM, error) {
+	if clockSpeed <= 0 {
+		return nil, fmt.Errorf("clock speed must be above 0Hz, got %d", clockSpeed)
+	}
+
+	memorySize := cfg.MemorySize
+	if memorySize == 0 {
+		memorySize = defaultMemorySize
+		if cfg.Mode == ModeXOChip {
+			memorySize = maxMemorySize
+		}
+	}
+	if memorySize < defaultMemorySize || memorySize > maxMemorySize {
+		return nil, fmt.Errorf("memory size must be between %d and %d bytes, got %d", defaultMemorySize, maxMemorySize, memorySize)
+	}
+	stackDepth := cfg.StackDepth
+	if stackDepth == 0 {
+		stackDepth = defaultStackDepth
+	}
+	if stackDepth < 0 || stackDepth > maxStackDepth {
+		return nil, fmt.Errorf("stack depth must be between 1 and %d, got %d", maxStackDepth, stackDepth)
+	}
+	startAddr := cfg.StartAddress
+	if startAddr == 0 {
+		startAddr = DefaultStartAddress
+	}
+	if startAddr < DefaultStartAddress || MaxROMSize(memorySize, startAddr) <= 0 {
+		return nil, fmt.Errorf("start address must be between 0x%03X and 0x%X, got 0x%03X", DefaultStartAddress, memorySize-2, startAddr)
+	}
+	if err := checkMemPresets(cfg.MemPresets, memorySize); err != nil {
+		return nil, err
+	}
+	beepHz := cfg.BeepHz
+	if beepHz == 0 {
+		beepHz = defaultBeepHz
+	}
+	if beepHz < 0 || beepHz >= int(beepSampleRate)/2 {
+		return nil, fmt.Errorf("beep frequency must be between 1 and %dHz, got %d", int(beepSampleRate)/2-1, beepHz)
+	}
+	keyRepeat := cfg.KeyRepeat
+	if keyRepeat == 0 {
+		keyRepeat = defaultKeyRepeat
+	}
+	if keyRepeat < 0 {
+		return nil, fmt.Errorf("key repeat interval can't be negative, got %v", keyRepeat)
+	}
+	rewindInterval := cfg.RewindInterval
+	if rewindInterval == 0 {
+		rewindInterval = defaultRewindInterval
+	}
+	if cfg.RewindDepth < 0 || rewindInterval < 0 {
+		return nil, fmt.Errorf("rewind depth and interval can't be negative, got %d and %v", cfg.RewindDepth, rewindInterval)
+	}
+	if cfg.ExitAfter < 0 {
+		return nil, fmt.Errorf("exit after can't be negative, got %v", cfg.ExitAfter)
+	}
+	if cfg.MinBeep < 0 {
+		return nil, fmt.Errorf("minimum beep length can't be negative, got %v", cfg.MinBeep)
+	}
+
+	if cfg.Scale < 0 || cfg.Scale > maxScale {
+		return nil, fmt.Errorf("window scale must be between 1 and %d, got %d", maxScale, cfg.Scale)
+	}
+	if cfg.PlayInput != nil {
+		if err := cfg.PlayInput.CheckSettings(clockSpeed, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CycleAccurate && cfg.AutoSpeed {
+		return nil, errors.New("auto speed tunes instructions a second, so it can't be used cycle accurate")
+	}
+	if cfg.PixelGap < 0 || cfg.PixelGap >= 1 {
+		return nil, fmt.Errorf("pixel gap must be at least 0 and less than 1, got %v", cfg.PixelGap)
+	}
+
+	fastForward := cfg.FastForward
+	if fastForward == 0 {
+		fastForward = defaultFastForward
+	}
+	if fastForward < 0 || fastForward > maxFastForward {
+		return nil, fmt.Errorf("fast forward must be between 1 and %d times, got %d", maxFastForward, fastForward)
+	}
+
+	recordFPS := cfg.RecordFPS
+	if recordFPS == 0 {
+		recordFPS = defaultRecordFPS
+	}
+	if recordFPS < 0 || recordFPS > 100 {
+		return nil, fmt.Errorf("recording frame rate must be between 1 and 100, got %d", recordFPS)
+	}
+
+	var window Display = headlessDisplay{}
+	var memView MemoryView
+	if cfg.Display != nil {
+		window = cfg.Display
+	} else if !cfg.Headless {
+		w, mv, err := openWindow(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error opening window: %w", err)
+		}
+		window, memView = w, mv
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = TextLogger{W: os.Stdout}
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
 
 	vm := VM{
-		memory:    [4096]byte{},
-		v:         [16]byte{},
-		pc:        0x200,
-		stack:     [16]uint16{},
-		gfx:       [64 * 32]byte{},
-		keypad:    [16]byte{},
-		window:    window,
-		Clock:     time.NewTicker(time.Second / time.Duration(clockSpeed)),
-		audioC:    make(chan struct{}),
-		ShutdownC: make(chan struct{}),
-	}
-
-	if err := vm.initialize(pathToROM); err != nil {
+		memory:              make([]byte, memorySize),
+		v:                   [16]byte{},
+		pc:                  startAddr,
+		startAddr:           startAddr,
+		stack:               make([]uint16, stackDepth),
+		stackPolicy:         cfg.StackPolicy,
+		mode:                cfg.Mode,
+		quirks:              cfg.Quirks,
+		keypad:              [16]byte{},
+		idle:                newIdleDetector(cfg.IdleWindow),
+		showUnknown:         cfg.ShowUnknown,
+		haltOnUnknown:       cfg.HaltOnUnknown,
+		highlightCollisions: cfg.HighlightCollisions,
+		drawStep:            cfg.DrawStep,
+		breakOnCollision:    cfg.BreakOnCollision,
+		window:              window,
+		memView:             memView,
+		headless:            cfg.Headless,
+		maxCycles:           cfg.MaxCycles,
+		exitAfter:           cfg.ExitAfter,
+		strict:              cfg.Strict,
+		next:                cfg.Next,
+		statePath:           cfg.StatePath,
+		debugging:           cfg.Debug || len(cfg.Breakpoints) > 0,
+		breakpoints:         make(map[uint16]bool),
+		rng:                 rand.New(rand.NewSource(seed)),
+		palette:             newPalette(cfg.Foreground, cfg.Background),
+		screenshotDir:       cfg.ScreenshotDir,
+		Clock:               time.NewTicker(frameInterval),
+		clockSpeed:          clockSpeed,
+		cycleAccurate:       cfg.CycleAccurate,
+		baseClockSpeed:      clockSpeed,
+		fastForward:         fastForward,
+		audioC:              make(chan struct{}),
+		muted:               cfg.Mute,
+		keyRepeatDur:        keyRepeat,
+		noKeyRepeat:         cfg.NoKeyRepeat,
+		font:                pixel.FontSet,
+		largeFont:           pixel.LargeFontSet,
+		soundBuffer:         defaultSoundBuffer,
+		pitch:               defaultPitch,
+		ShutdownC:           make(chan struct{}, 1),
+		log:                 logger,
+	}
+
+	vm.stats.Started = time.Now()
+	vm.stats.sample(vm.stats.Started)
+	if cfg.Record != "" {
+		vm.recorder = newRecorder(cfg.Record, recordFPS, vm.clockSpeed)
+	}
+	if cfg.RecordInput != "" {
+		vm.inputLog = &Replay{Seed: seed, ClockSpeed: clockSpeed, CycleAccurate: cfg.CycleAccurate, Mode: cfg.Mode, Quirks: cfg.Quirks}
+		vm.inputLogPath = cfg.RecordInput
+	}
+	if cfg.PlayInput != nil {
+		vm.playback, vm.playingBack = cfg.PlayInput.Events, true
+	}
+	if cfg.Font != nil {
+		vm.font = *cfg.Font
+	}
+	if cfg.LargeFont != nil {
+		vm.largeFont = *cfg.LargeFont
+	}
+	if cfg.RewindDepth > 0 && !cfg.Headless {
+		vm.rewind = newRewinder(cfg.RewindDepth, rewindInterval)
+	}
+	if vm.mode == ModeXOChip {
+		vm.pattern = newPatternPlayer()
+	} else {
+		vm.tone = newTonePlayer(beepHz, cfg.MinBeep)
+	}
+	if cfg.Events != nil {
+		vm.events = json.NewEncoder(cfg.Events)
+	}
+	vm.onStep = cfg.OnStep
+	vm.onCycle, vm.onDraw, vm.onCollision, vm.onBeep = cfg.OnCycle, cfg.OnDraw, cfg.OnCollision, cfg.OnBeep
+	if cfg.AutoSpeed {
+		vm.speed = newSpeedTuner(clockSpeed)
+		vm.baseClockSpeed = vm.speed.perFrame * 60
+		vm.applyTurbo()
+	}
+
+	vm.log.Log("created", "", Fields{"mode": vm.mode.String(), "clock_hz": clockSpeed, "memory": memorySize})
+	if err := vm.initialize(rom); err != nil {
 		return nil, err
 	}
+	vm.log.Log("rom_loaded", "", Fields{"size": len(vm.rom), "sha1": romdb.Hash(vm.rom), "start": startAddr})
+	vm.regPresets, vm.memPresets = cfg.RegPresets, cfg.MemPresets
+	vm.applyPresets(vm.regPresets, vm.memPresets)
+	vm.startDebugger(cfg.Debug, cfg.Breakpoints)
+	if cfg.Overlay {
+		vm.overlay.shown = true
+		vm.restartSpeedOverlay(speedPending)
+	}
 
 	return &vm, nil
 }
 
 // Run starts the vm and emulates a clock that runs by default at 60MHz
-// This can be changed with a flag.
+// This can be changed with a flag. Run returns as soon as the window is
+// closed, a shutdown signal is received, or MaxCycles have run.
 func (vm *VM) Run() {
-outer:
-	for {
+	vm.lastTick = time.Now()
+	if vm.exitAfter > 0 {
+		vm.deadline = vm.lastTick.Add(vm.exitAfter)
+	}
+	for vm.nextTick() {
+		vm.tick()
+		vm.clockCycle()
+	}
+	vm.finishRecording()
+	vm.finishInputLog()
+	vm.logUnknownOps()
+	vm.signalShutdown("Received signal - gracefully shutting down...")
+}
+
+// clockCycle does everything the VM does on one tick of its clock
+func (vm *VM) clockCycle() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	// Memory is shown whatever the VM is doing, stepping through the debugger included
+	vm.updateMemoryView()
+	if vm.halted {
+		vm.whileHalted()
+		return
+	}
+	if vm.paused {
+		vm.whilePaused()
+		return
+	}
+	if vm.whileRewinding() {
+		return
+	}
+	if vm.atBreakpoint() {
+		vm.halt(fmt.Sprintf("breakpoint at 0x%03X", vm.pc))
+		return
+	}
+	vm.playInput()
+
+	// An instruction taking several clock cycles pays for the rest of them on top of the one nextTick took
+	cost := vm.tickCost()
+	vm.owed -= float64(cost - 1)
+	vm.cycles += cost
+	vm.stats.Cycles.Add(cost)
+	if !vm.idling() {
+		vm.cycle()
+		vm.stats.Instructions.Add(1)
+	}
+	vm.drawOrUpdate()
+	if vm.pausesOnDraw() {
+		vm.pauseOnDraw()
+	}
+	vm.handleKeyInput()
+	vm.updateSpeedOverlay()
+	if vm.quirks.TimersPerInstruction {
+		vm.delayTimerTick()
+		vm.soundTimerTick()
+		vm.vblank = true
+	} else {
+		vm.timerCycle(cost)
+	}
+	if vm.vblank {
+		vm.stats.sample(time.Now())
+	}
+	vm.syncSoundState()
+	vm.capture()
+	vm.saveRewind()
+	if vm.stepping {
+		vm.finishStep()
+	}
+}
+
+// timerCycle keeps the timers counting down at 60Hz however fast the clock runs, by ticking them on
+// the clock cycles where a 60Hz timer would have fired, over the cycles the last instruction took.
+// Fast-forwarding speeds them up with the clock.
+func (vm *VM) timerCycle(cycles uint64) {
+	vm.vblank = false
+	for vm.timerPhase += 60 * vm.speedFactor() * int(cycles); vm.timerPhase >= vm.clockSpeed; vm.timerPhase -= vm.clockSpeed {
+		vm.delayTimerTick()
+		vm.soundTimerTick()
+		vm.vblank = true
+	}
+}
+
+// nextTick waits until the VM owes a clock cycle and reports whether it should keep running. Each time
+// the clock wakes it, the wall clock time since the last wake is turned into cycles owed at the clock
+// speed, so a late tick or the process being descheduled is caught up on rather than lost and games
+// run at the same speed on any machine. Headless VMs have nobody watching them, so they don't wait on the clock.
+func (vm *VM) nextTick() bool {
+	if (vm.exited || vm.window.Closed()) && !vm.advance() {
+		return false
+	}
+	if vm.maxCycles > 0 && vm.cycles >= vm.maxCycles {
+		return false
+	}
+	if !vm.deadline.IsZero() && !time.Now().Before(vm.deadline) {
+		return false
+	}
+	if vm.headless {
+		select {
+		case <-vm.ShutdownC:
+			return false
+		default:
+			return true
+		}
+	}
+	for vm.owed < 1 {
 		select {
 		case <-vm.Clock.C:
-			if !vm.window.Closed() {
-				vm.emulateCycle()
-				vm.drawOrUpdate()
-				vm.handleKeyInput()
-				vm.delayTimerTick()
-				vm.soundTimerTick()
-				continue
-			}
-			break outer
+			vm.accrue(time.Now())
 		case <-vm.ShutdownC:
-			break outer
+			return false
 		}
 	}
-	vm.signalShutdown("Received signal - gracefully shutting down...")
+	vm.owed--
+	return !vm.window.Closed() || vm.advance()
+}
+
+// accrue adds the clock cycles owed for the time since the run loop last woke. Anything further behind
+// than maxLag is dropped, so after a long stall the VM carries on at normal speed instead of racing to catch up.
+func (vm *VM) accrue(now time.Time) {
+	elapsed := min(now.Sub(vm.lastTick), maxLag)
+	vm.lastTick = now
+	vm.owed += elapsed.Seconds() * float64(vm.clockSpeed)
 }
 
-func (vm *VM) initialize(pathToROM string) error {
+func (vm *VM) initialize(rom io.Reader) error {
 	vm.loadFontSet()
-	if err := vm.loadROM(pathToROM); err != nil {
+	if err := vm.loadROM(rom); err != nil {
 		return err
 	}
 	return nil
 }
 
-// loads the font set into the first 80 bytes of memory
+// loads the font set into the first 80 bytes of memory, followed by SUPER-CHIP's large font
 func (vm *VM) loadFontSet() {
-	for i := range 80 {
-		vm.memory[i] = pixel.FontSet[i]
-	}
+	copy(vm.memory, vm.font[:])
+	copy(vm.memory[largeFontAddr:], vm.largeFont[:])
 }
 
-func (vm *VM) loadROM(path string) error {
-	rom, err := os.ReadFile(path)
+// loadROM reads the ROM from r and writes it into memory at the program start address
+func (vm *VM) loadROM(r io.Reader) error {
+	// Read one byte past the limit so an oversized ROM is detected without reading all of it
+	rom, err := io.ReadAll(io.LimitReader(r, int64(vm.maxROMSize())+1))
 	if err != nil {
 		return err
 	}
-	if len(rom) > maxRomSize {
-		return errors.New("error: rom too large. Max size: 3583")
+	if len(rom) > vm.maxROMSize() {
+		// Count the rest without keeping it, to report the ROM's full size
+		rest, _ := io.Copy(io.Discard, r)
+		return fmt.Errorf("rom too large: %d bytes, max is %d", len(rom)+int(rest), vm.maxROMSize())
 	}
 
-	for i := range len(rom) {
-		vm.memory[0x200+i] = rom[i] // Write memory with pc offset
-	}
+	copy(vm.memory[vm.startAddr:], rom)
+	vm.rom = rom
 
 	return nil
 }
 
+// Where ROMs are loaded and start running: 0x200 for nearly every ROM, and 0x600 for ones written for the ETI-660
+const (
+	DefaultStartAddress uint16 = 0x200
+	ETI660StartAddress  uint16 = 0x600
+)
+
+// DefaultClockSpeed is the instructions a second ROMs are run at unless asked otherwise, about the pace
+// the games written for the original interpreters expect
+const DefaultClockSpeed = 700
+
+// maxROMSize is the room between the program start address and the end of memory
+func (vm *VM) maxROMSize() int {
+	return MaxROMSize(len(vm.memory), vm.startAddr)
+}
+
+// MaxROMSize is the largest ROM, in bytes, that loads at start into memorySize bytes of memory
+func MaxROMSize(memorySize int, start uint16) int {
+	return memorySize - 1 - int(start)
+}
+
+// addr wraps an address into memory, so the index register can't reach past the end of RAM
+func (vm *VM) addr(a uint16) int {
+	return int(a) % len(vm.memory)
+}
+
+// opcodeAt reads the two byte opcode stored at addr
+func (vm *VM) opcodeAt(addr uint16) uint16 {
+	return uint16(vm.memory[vm.addr(addr)])<<8 | uint16(vm.memory[vm.addr(addr+1)])
+}
+
 // emulateCycle runs a full fetch, decode, and execute cycle.
 // One opcode is 2 bytes long (ex. 0xA2FO) so we need to fetch two successive bytes (ex. 0xA2 and 0xF0) and merge them
 // to get the actual opcode. First we shift current instruction left 8 (ex. from 10100010 -> 1010001000000000)
 // Then we OR it with the upcoming byte which gives us a 16 bit chunk containing the combined bytes
 func (vm *VM) emulateCycle() {
-	vm.opcode = uint16(vm.memory[vm.pc])<<8 | uint16(vm.memory[vm.pc+1])
+	vm.opcode = vm.opcodeAt(vm.pc)
 	vm.drawFlag = false
+	if vm.onCycle != nil {
+		vm.onCycle(vm.pc, vm.opcode)
+	}
 
-	if err := vm.parseOpcode(); err != nil {
-		fmt.Printf("error parsing opcode: %v", err)
+	err := vm.parseOpcode()
+	switch {
+	case errors.Is(err, errStackOverflow), errors.Is(err, errStackUnderflow):
+		vm.stackFault(err)
+	case err != nil:
+		vm.recordUnknownOp(err)
+	}
+	if vm.drawFlag && vm.onDraw != nil {
+		cols, _ := vm.resolution()
+		vm.onDraw(vm.getGraphics(), cols)
+	}
+}
+
+// cycle executes the next instruction, reporting it to the events stream and OnStep when they are set,
+// and keeping what it changed for the debugger or draw step to report
+func (vm *VM) cycle() {
+	if !vm.strict {
+		defer vm.recoverCycle()
+	}
+	if vm.events == nil && vm.onStep == nil && !vm.stepping && vm.drawStep == 0 {
+		vm.emulateCycle()
+		return
+	}
+
+	res := vm.step()
+	if vm.stepping || vm.drawStep > 0 {
+		vm.lastStep = &res
+	}
+	if vm.onStep != nil {
+		vm.onStep(res)
+	}
+	if vm.events == nil {
+		return
+	}
+	if err := vm.events.Encode(res); err != nil {
+		vm.log.Log("error", fmt.Sprintf("error writing cycle event: %v", err), Fields{"error": err.Error()})
+		vm.events = nil
 	}
 }
 
@@ -204,19 +1006,42 @@ func (vm *VM) parseOpcode() error {
 	nnn := vm.opcode & 0x0FFF      // load last 12-bits
 
 	switch vm.opcode & 0xF000 {
-	case 0x0000: // 0NNN -> Execute machine language subroutine at address NNN
-		switch vm.opcode & 0x00FF {
-		case 0x00E0:
+	case 0x0000:
+		switch {
+		case vm.opcode == 0x00E0:
 			vm._0x00E0() // 00E0 -> Clear the screen
-		case 0x00EE:
-			vm._0x00EE() // 00EE -> Return from a subroutine.
+		case vm.opcode == 0x00EE:
+			return vm._0x00EE() // 00EE -> Return from a subroutine.
+		case vm.opcode >= 0x00FB && vm.opcode <= 0x00FF:
+			if vm.mode == ModeChip8 {
+				return vm.unknownOp()
+			}
+			switch vm.opcode {
+			case 0x00FB:
+				vm._0x00FB() // 00FB -> (SUPER-CHIP) Scroll the screen right 4 pixels
+			case 0x00FC:
+				vm._0x00FC() // 00FC -> (SUPER-CHIP) Scroll the screen left 4 pixels
+			case 0x00FD:
+				vm._0x00FD() // 00FD -> (SUPER-CHIP) Exit the interpreter
+			case 0x00FE:
+				vm._0x00FE() // 00FE -> (SUPER-CHIP) Leave extended screen mode
+			case 0x00FF:
+				vm._0x00FF() // 00FF -> (SUPER-CHIP) Enter extended screen mode
+			}
+		case vm.opcode&0xFFF0 == 0x00C0:
+			if vm.mode == ModeChip8 {
+				return vm.unknownOp()
+			}
+			vm._0x00C0(vm.opcode & 0x000F) // 00CN -> (SUPER-CHIP) Scroll the screen down N pixels
+		case vm.opcode == 0x0000:
+			return vm.unknownOp() // Running into empty memory, not a call to machine code at 0x000
 		default:
-			return vm.unknownOp(vm.opcode & 0x00FF)
+			vm._0x0NNN(nnn) // 0NNN -> Execute machine language subroutine at address NNN, which is skipped
 		}
 	case 0x1000:
 		vm._0x1000(nnn) // 1NNN -> Jump to address NNN
 	case 0x2000:
-		vm._0x2000(nnn) // 2NNN -> Execute subroutine starting at address NNN
+		return vm._0x2000(nnn) // 2NNN -> Execute subroutine starting at address NNN
 	case 0x3000:
 		vm._0x3000(x, nn) // 3XNN -> Skip the following instruction if the value of register VX == NN
 	case 0x4000:
@@ -248,14 +1073,14 @@ func (vm *VM) parseOpcode() error {
 		case 0x000E:
 			vm._0x000E(x, y) // 8XYE -> Store the value of register VY shifted left one bit in register VX
 		default:
-			return vm.unknownOp(vm.opcode & 0x000F)
+			return vm.unknownOp()
 		}
 	case 0x9000:
 		vm._0x9000(x, y) // 9XY0 -> Skip the following instruction if the value of VX != value of VY
 	case 0xA000:
 		vm._0xA000(nnn) // ANNN -> Store memory address NNN in index register
 	case 0xB000:
-		vm._0xB000(nnn) // BNNN -> Jump to address NNN + V0
+		vm._0xB000(x, nnn) // BNNN -> Jump to address NNN + V0, or BXNN -> XNN + VX with the jump quirk
 	case 0xC000:
 		vm._0xC000(x, nn) // CXNN -> Set VX to a random number from 0-255 with a mask of NN
 	case 0xD000:
@@ -267,10 +1092,20 @@ func (vm *VM) parseOpcode() error {
 		case 0x00A1:
 			vm._0x00A1(x) // EXA1 -> Skip the following instruction if the key corresponding to the hex value currently stored in register VX is not pressed
 		default:
-			return vm.unknownOp(vm.opcode & 0x00FF)
+			return vm.unknownOp()
 		}
 	case 0xF000:
 		switch vm.opcode & 0x00FF {
+		case 0x0000:
+			if x != 0 || vm.mode != ModeXOChip {
+				return vm.unknownOp()
+			}
+			vm._0x0000_2() // F000 NNNN -> (XO-CHIP) Store the 16-bit address NNNN in the following word in index register
+		case 0x0002:
+			if x != 0 || vm.mode != ModeXOChip {
+				return vm.unknownOp()
+			}
+			vm._0x0002_2() // F002 -> (XO-CHIP) Store 16 bytes starting at the address in index register in the audio pattern buffer
 		case 0x0007:
 			vm._0x0007_2(x) // FX07 -> Store the current value of the delay timer in register VX
 		case 0x000A:
@@ -283,115 +1118,306 @@ func (vm *VM) parseOpcode() error {
 			vm._0x001E(x) // FX1E -> Add the value stored in register VX to index register
 		case 0x0029:
 			vm._0x0029(x) // FX29 -> Set index register to the memory address of the sprite data corresponding to the hexadecimal digit stored in register VX
+		case 0x0030:
+			if vm.mode == ModeChip8 {
+				return vm.unknownOp()
+			}
+			vm._0x0030(x) // FX30 -> (SUPER-CHIP) Set index register to the memory address of the large sprite data corresponding to the hexadecimal digit stored in register VX
+		case 0x003A:
+			if vm.mode != ModeXOChip {
+				return vm.unknownOp()
+			}
+			vm._0x003A(x) // FX3A -> (XO-CHIP) Set the audio pattern's playback pitch to the value of register VX
 		case 0x0033:
 			vm._0x0033(x) // FX33 -> Store the binary-coded decimal equivalent of the value stored in register VX at addresses i, i+1, and i+2
 		case 0x0055:
 			vm._0x0055(x) // FX55 -> Store the values of registers V0 to VX inclusive in memory starting at address i
 		case 0x0065:
 			vm._0x0065(x) // FX65 -> Fill registers V0 to VX inclusive with the values stored in memory starting at address i
+		case 0x0075, 0x0085:
+			if vm.mode == ModeChip8 {
+				return vm.unknownOp()
+			}
+			if vm.opcode&0x00FF == 0x0075 {
+				vm._0x0075(x) // FX75 -> (SUPER-CHIP) Store the values of registers V0 to VX inclusive in the RPL user flags
+			} else {
+				vm._0x0085(x) // FX85 -> (SUPER-CHIP) Fill registers V0 to VX inclusive from the RPL user flags
+			}
 		default:
-			return vm.unknownOp(vm.opcode & 0x00FF)
+			return vm.unknownOp()
 		}
 	default:
-		return vm.unknownOp(vm.opcode & 0x00FF)
+		return vm.unknownOp()
 	}
 	return nil
 }
 
-func (vm VM) getGraphics() [64 * 32]byte { return vm.gfx }
+// getGraphics returns the pixels of the active resolution, a row at a time
+func (vm *VM) getGraphics() []byte {
+	w, h := vm.resolution()
+	return vm.gfx[:w*h]
+}
+
+// resolution returns the screen's width and height, 128x64 in SUPER-CHIP's extended mode and 64x32 otherwise
+func (vm *VM) resolution() (int, int) {
+	if vm.hires {
+		return 128, 64
+	}
+	return 64, 32
+}
+
+// FrameHash returns a hex encoded SHA-256 of the screen, for checking a run ended on the expected frame
+func (vm *VM) FrameHash() string {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	sum := sha256.Sum256(vm.getGraphics())
+	return hex.EncodeToString(sum[:])
+}
 
 func (vm *VM) setKeyDown(index byte) {
 	vm.keypad[index] = 1
 }
 
-func (vm VM) unknownOp(opcode uint16) error {
-	return fmt.Errorf("unknown opcode: %x", opcode)
+// pressKey and releaseKey handle a key going down or coming back up. Unlike the key repeat's
+// setKeyDown, they are only called once per press, which is what FX0A waits on.
+func (vm *VM) pressKey(key byte) {
+	vm.recordInput(key, true, false)
+	vm.keysHeld[key] = true
+	if vm.awaitingKey && vm.awaitedKey < 0 {
+		vm.awaitedKey = int(key)
+	}
+	vm.setKeyDown(key)
+}
+
+// repeatKey presses a held key again for the key repeat
+func (vm *VM) repeatKey(key byte) {
+	vm.recordInput(key, true, true)
+	vm.setKeyDown(key)
+}
+
+func (vm *VM) releaseKey(key byte) {
+	vm.recordInput(key, false, false)
+	vm.keysHeld[key] = false
+	if vm.noKeyRepeat {
+		vm.keypad[key] = 0
+	}
+}
+
+// PressKey and ReleaseKey press and release a CHIP-8 key without the window, for scripts, tests, and agents
+// driving a headless VM. A press is seen exactly like a key going down on the keyboard, FX0A included,
+// except that it isn't repeated while held. They're safe to call from any goroutine, and keys past 0xF
+// are ignored.
+func (vm *VM) PressKey(hex byte) {
+	if int(hex) >= len(vm.keypad) {
+		return
+	}
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.pressKey(hex)
+}
+
+func (vm *VM) ReleaseKey(hex byte) {
+	if int(hex) >= len(vm.keypad) {
+		return
+	}
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.releaseKey(hex)
+}
+
+// consumeKey clears a key press once the ROM has read it, unless the keypad is showing held keys
+func (vm *VM) consumeKey(key byte) {
+	if !vm.noKeyRepeat {
+		vm.keypad[key] = 0
+	}
+}
+
+func (vm *VM) unknownOp() error {
+	return fmt.Errorf("unknown opcode %04X at 0x%03X", vm.opcode, vm.pc)
 }
 
 func (vm *VM) handleKeyInput() {
-	for i, key := range vm.window.KeyMap {
-		if vm.window.JustReleased(key) && vm.window.KeysDown[i] != nil {
-			vm.window.KeysDown[i].Stop()
-			vm.window.KeysDown[i] = nil
-		} else if vm.window.JustPressed(key) {
-			if vm.window.KeysDown[i] == nil {
-				vm.window.KeysDown[i] = time.NewTicker(keyRepeatDur)
+	keys := vm.window.Hotkeys()
+	if keys.Has(hotkey.Pause) {
+		vm.pause()
+		return
+	}
+	if keys.Has(hotkey.Reset) {
+		vm.reset()
+		return
+	}
+	if keys.Has(hotkey.Fullscreen) {
+		vm.window.ToggleFullscreen()
+		vm.frameDirty = true
+	}
+	if keys.Has(hotkey.Screenshot) {
+		vm.quickScreenshot()
+	}
+	if keys.Has(hotkey.Mute) {
+		vm.toggleMute()
+	}
+	vm.handleTurboKeys(keys)
+	vm.handleFastForward(keys)
+	vm.handleNextKey(keys)
+	if vm.statePath != "" {
+		if keys.Has(hotkey.SaveState) {
+			vm.quickSave()
+		} else if keys.Has(hotkey.LoadState) {
+			vm.quickLoad()
+		}
+	}
+
+	// The keypad is the input log's alone while it plays back, or it wouldn't play out the same
+	if vm.playingBack {
+		return
+	}
+	for i := range vm.keyRepeat {
+		key := byte(i)
+		if vm.window.KeyJustReleased(key) {
+			vm.releaseKey(key)
+			if vm.keyRepeat[i] != nil {
+				vm.keyRepeat[i].Stop()
+				vm.keyRepeat[i] = nil
+			}
+		} else if vm.window.KeyJustPressed(key) {
+			if vm.keyRepeat[i] == nil && !vm.noKeyRepeat {
+				vm.keyRepeat[i] = time.NewTicker(vm.keyRepeatDur)
 			}
-			vm.setKeyDown(byte(i))
+			vm.pressKey(key)
 		}
 
-		if vm.window.KeysDown[i] == nil {
+		if vm.keyRepeat[i] == nil {
 			continue
 		}
 
 		select {
-		case <-vm.window.KeysDown[i].C:
-			vm.setKeyDown(byte(i))
+		case <-vm.keyRepeat[i].C:
+			vm.repeatKey(key)
 		default:
 		}
 	}
 }
 
 func (vm *VM) drawSprite(x, y uint16) {
-	height := vm.opcode & 0x000F
+	height, width := vm.opcode&0x000F, uint16(8)
+	if height == 0 && vm.mode != ModeChip8 {
+		// SUPER-CHIP's DXY0 draws a 16x16 sprite, two bytes to a row
+		height, width = 16, 16
+	}
 	vm.v[0xF] = 0
+	vm.stats.Draws.Add(1)
+	stride, rows := vm.resolution()
+	gfx := vm.getGraphics()
 	var pix uint16
 
+	// The sprite's corner always wraps onto the screen, each axis by its own size. What runs past the right
+	// and bottom edges is clipped unless the quirks wrap it around.
+	x, y = x%uint16(stride), y%uint16(rows)
+	wrapX, wrapY := vm.quirks.WrapSprites || vm.quirks.WrapSpritesX, vm.quirks.WrapSprites
+
 	for yLine := uint16(0); yLine < height; yLine++ {
-		pix = uint16(vm.memory[vm.i+yLine])
+		py := y + yLine
+		if py >= uint16(rows) {
+			if !wrapY {
+				break
+			}
+			py %= uint16(rows)
+		}
+
+		// Rows are read into the high bits so 8 and 16 wide sprites are drawn the same way
+		if width == 16 {
+			pix = uint16(vm.memory[vm.addr(vm.i+yLine*2)])<<8 | uint16(vm.memory[vm.addr(vm.i+yLine*2+1)])
+		} else {
+			pix = uint16(vm.memory[vm.addr(vm.i+yLine)]) << 8
+		}
 
-		for xLine := uint16(0); xLine < 8; xLine++ {
-			ind := (x + xLine + ((y + yLine) * 64))
-			if ind >= uint16(len(vm.getGraphics())) {
-				continue
+		for xLine := uint16(0); xLine < width; xLine++ {
+			px := x + xLine
+			if px >= uint16(stride) {
+				if !wrapX {
+					break
+				}
+				px %= uint16(stride)
 			}
-			if (pix & (0x80 >> xLine)) != 0 {
-				if vm.getGraphics()[ind] == 1 {
+			ind := px + py*uint16(stride)
+			if (pix & (0x8000 >> xLine)) != 0 {
+				if vm.drawStep > 0 {
+					vm.drawn = append(vm.drawn, ind)
+				}
+				if gfx[ind] == 1 {
 					vm.v[0xF] = 1
+					if vm.highlightCollisions {
+						vm.collided = append(vm.collided, ind)
+					}
 				}
-				vm.gfx[ind] ^= 1
+				gfx[ind] ^= 1
 			}
 		}
 	}
 
 	vm.drawFlag = true
-}
 
-// ManageAudio reads and decodes the beep.mp3, initializes the speaker, and plays
-// a beep each time an audio event is placed on the channel
-func (vm *VM) ManageAudio() {
-	f, err := os.Open("assets/beep.mp3")
-	if err != nil {
-		return
+	if vm.v[0xF] == 1 && vm.onCollision != nil {
+		vm.onCollision()
 	}
-	defer f.Close()
+	if vm.v[0xF] == 1 && vm.breakOnCollision {
+		vm.breakOnCollision = false
+		vm.halt(fmt.Sprintf("sprite collision drawing at 0x%03X", vm.pc))
+	}
+}
 
-	streamer, format, err := mp3.Decode(f)
-	if err != nil {
-		return
+// RingBell calls bell for each beep in place of playing a tone, for displays like a terminal with a bell
+// but no speaker of their own. Like ManageAudio it runs until the VM shuts down.
+func (vm *VM) RingBell(bell func()) {
+	for range vm.audioC {
+		bell()
 	}
-	defer streamer.Close()
+}
 
-	if err := speaker.Init(
-		format.SampleRate,
-		format.SampleRate.N(time.Second/10),
-	); err != nil {
-		panic("failed to initialize speakers")
+// ManageAudio initializes the speaker and streams the beep tone to it, which sounds whenever the sound timer runs.
+// In XO-CHIP mode it streams the ROM's audio pattern instead. When there's no speaker to play on the error is
+// logged and the VM carries on silently.
+func (vm *VM) ManageAudio() {
+	if err := speaker.Init(beepSampleRate, beepSampleRate.N(time.Second/10)); err != nil {
+		vm.log.Log("error", fmt.Sprintf("error initializing speaker, sound is off: %v", err), Fields{"error": err.Error()})
+		vm.RingBell(func() {})
+		return
 	}
 
-	for range vm.audioC {
-		speaker.Play(streamer)
+	if vm.pattern != nil {
+		speaker.Play(vm.pattern)
+	} else {
+		speaker.Play(vm.tone)
 	}
+	// The players follow the VM themselves, the beeps sent for bells only need draining
+	vm.RingBell(func() {})
 }
 
+// drawOrUpdate presents the screen when it has changed and a frame is due, and otherwise just polls for input.
+// Headless VMs and sprite draws being paused on present every change.
 func (vm *VM) drawOrUpdate() {
 	if vm.drawFlag {
-		vm.window.DrawGraphics(vm.getGraphics())
+		vm.frameDirty = true
+	}
+	if vm.frameDirty && (vm.headless || vm.pausesOnDraw() || time.Since(vm.lastFrame) >= frameInterval) {
+		vm.presentFrame()
 	} else {
 		vm.window.UpdateInput()
 	}
 }
 
+// presentFrame draws the screen to the window along with the pixels to tint since the last frame
+func (vm *VM) presentFrame() {
+	w, _ := vm.resolution()
+	vm.window.DrawGraphics(vm.getGraphics(), w, vm.collided, vm.drawn)
+	vm.stats.Frames.Add(1)
+	vm.collided = vm.collided[:0]
+	vm.drawn = vm.drawn[:0]
+	vm.frameDirty = false
+	vm.lastFrame = time.Now()
+}
+
 func (vm *VM) delayTimerTick() {
 	if vm.delayTimer > 0 {
 		vm.delayTimer--
@@ -400,21 +1426,34 @@ func (vm *VM) delayTimerTick() {
 
 func (vm *VM) soundTimerTick() {
 	if vm.soundTimer > 0 {
-		if vm.soundTimer == 1 {
-			vm.audioC <- struct{}{}
-		}
 		vm.soundTimer--
 	}
 }
 
+// signalShutdown stops the audio goroutine and tells whoever is waiting on ShutdownC that Run stopped.
+// It only does so the first time it's called. If a shutdown signal sent to stop the VM is still waiting
+// in ShutdownC, that signal is left for the listener rather than blocking on a full channel.
 func (vm *VM) signalShutdown(msg string) {
-	fmt.Println(msg)
-	close(vm.audioC)
-	vm.ShutdownC <- struct{}{}
+	vm.shutdown.Do(func() {
+		vm.logSummary()
+		vm.log.Log("shutdown", msg, Fields{"cycles": vm.cycles, "instructions": vm.stats.Instructions.Load()})
+		close(vm.audioC)
+		select {
+		case vm.ShutdownC <- struct{}{}:
+		default:
+		}
+	})
 }
 
-func (vm *VM) debug() {
-	fmt.Printf(`opcode: %x
+// Debug writes the current opcode and the VM's registers to w
+func (vm *VM) Debug(w io.Writer) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	vm.debug(w)
+}
+
+func (vm *VM) debug(w io.Writer) {
+	fmt.Fprintf(w, `opcode: %x
 pc: %d
 sp: %d
 i: %d
@@ -442,3 +1481,12 @@ VF: %d`,
 		vm.v[13], vm.v[14], vm.v[15],
 	)
 }
+
+// logState logs the current opcode and the VM's registers as a state event, its message being what Debug
+// writes followed by a blank line
+func (vm *VM) logState() {
+	var b strings.Builder
+	vm.debug(&b)
+	b.WriteString("\n")
+	vm.log.Log("state", b.String(), Fields{"opcode": vm.opcode, "pc": vm.pc, "sp": vm.sp, "i": vm.i, "v": vm.v})
+}
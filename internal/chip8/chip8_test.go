@@ -0,0 +1,164 @@
+package chip8
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestVM returns a headless VM with program loaded at 0x200, ready for tests to poke its memory and
+// registers, run its opcode handlers, and check the state they leave. Nothing it logs is printed.
+func newTestVM(t testing.TB, cfg Config, program ...uint16) *VM {
+	t.Helper()
+	rom := make([]byte, 0, len(program)*2)
+	for _, op := range program {
+		rom = append(rom, byte(op>>8), byte(op))
+	}
+	if len(rom) == 0 {
+		rom = []byte{0x12, 0x00}
+	}
+
+	cfg.Headless = true
+	if cfg.Seed == 0 {
+		cfg.Seed = 1
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = TextLogger{W: io.Discard}
+	}
+	vm, err := NewVM(bytes.NewReader(rom), DefaultClockSpeed, cfg)
+	if err != nil {
+		t.Fatalf("NewVM: %v", err)
+	}
+	t.Cleanup(vm.Clock.Stop)
+	return vm
+}
+
+// exec runs opcode as the instruction at pc, returning what parseOpcode did
+func (vm *VM) exec(opcode uint16) error {
+	vm.opcode = opcode
+	vm.drawFlag = false
+	return vm.parseOpcode()
+}
+
+// closingDisplay is a headless display the user closes after it has been polled closeAfter times
+type closingDisplay struct {
+	headlessDisplay
+	polls, closeAfter int
+}
+
+func (d *closingDisplay) DrawGraphics(gfx []byte, cols int, collided, drawn []uint16) { d.polls++ }
+func (d *closingDisplay) UpdateInput()                                                { d.polls++ }
+func (d *closingDisplay) Closed() bool                                                { return d.polls >= d.closeAfter }
+
+// runs calls Run and reports whether it returned within a second
+func runs(vm *VM) bool {
+	done := make(chan struct{})
+	go func() {
+		vm.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestRunExitsWhenTheWindowCloses(t *testing.T) {
+	for _, headless := range []bool{false, true} {
+		vm := newTestVM(t, Config{}, 0x1200)
+		d := &closingDisplay{closeAfter: 1}
+		vm.window, vm.headless = d, headless
+		if !runs(vm) {
+			t.Fatalf("headless %v: Run didn't return after the window closed", headless)
+		}
+		if vm.cycles != 1 {
+			t.Errorf("headless %v: ran %d cycles, want the 1 before the window closed", headless, vm.cycles)
+		}
+	}
+}
+
+func TestRunExitsOnShutdown(t *testing.T) {
+	vm := newTestVM(t, Config{}, 0x1200)
+	vm.window, vm.headless = &closingDisplay{closeAfter: math.MaxInt}, false
+	vm.ShutdownC <- struct{}{}
+	if !runs(vm) {
+		t.Fatal("Run didn't return after a shutdown signal")
+	}
+}
+
+func TestTimerQuirk(t *testing.T) {
+	tests := []struct {
+		name   string
+		quirks Quirks
+		want   byte
+	}{
+		// 35 cycles at 700Hz are 3 ticks of a 60Hz timer
+		{"60Hz timers", Quirks{}, 97},
+		{"timers per instruction", Quirks{TimersPerInstruction: true}, 65},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newTestVM(t, Config{Quirks: tt.quirks}, 0x7001, 0x1200)
+			vm.delayTimer, vm.soundTimer = 100, 100
+			for range 35 {
+				vm.clockCycle()
+			}
+			if vm.delayTimer != tt.want || vm.soundTimer != tt.want {
+				t.Errorf("delay timer = %d, sound timer = %d, want both %d", vm.delayTimer, vm.soundTimer, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitOpcodeShutsDown(t *testing.T) {
+	vm := newTestVM(t, Config{Mode: ModeSChip}, 0x6001, 0x00FD, 0x6002)
+	vm.headless = false
+	if !runs(vm) {
+		t.Fatal("Run didn't return after 00FD")
+	}
+	select {
+	case <-vm.ShutdownC:
+	default:
+		t.Error("00FD didn't signal a shutdown")
+	}
+	if vm.v[0] != 1 || vm.Err() != nil {
+		t.Errorf("V0 = %d, err = %v, want the ROM to have stopped cleanly at 00FD", vm.v[0], vm.Err())
+	}
+}
+
+func TestLoadROMSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		size    int
+		wantErr string
+	}{
+		{name: "largest that fits in 4K", size: 3583},
+		{name: "one byte over", size: 3584, wantErr: "rom too large: 3584 bytes, max is 3583"},
+		{name: "far over", size: 10000, wantErr: "rom too large: 10000 bytes, max is 3583"},
+		{name: "fits in 64K", cfg: Config{MemorySize: 0x10000}, size: 10000},
+		{name: "over from the ETI-660's start", cfg: Config{StartAddress: ETI660StartAddress}, size: 2560,
+			wantErr: "rom too large: 2560 bytes, max is 2559"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Headless = true
+			vm, err := NewVM(bytes.NewReader(make([]byte, tt.size)), DefaultClockSpeed, cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewVM: %v", err)
+				}
+				vm.Clock.Stop()
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewVM error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package chip8
+
+import "fmt"
+
+// recoverCycle stops the VM cleanly when executing an instruction panics, rather than the process going down
+// with a stack trace. The opcode and program counter are logged along with the VM's state, and Err reports
+// the panic once Run returns, so --dump-on-exit still saves the state it panicked in. Strict VMs panic as usual.
+func (vm *VM) recoverCycle() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	vm.err = fmt.Errorf("panic executing %04X at 0x%03X: %v", vm.opcode, vm.pc, r)
+	vm.exited = true
+	vm.log.Log("panic", "error running rom: "+vm.err.Error(), Fields{"opcode": vm.opcode, "pc": vm.pc, "cycles": vm.cycles, "panic": fmt.Sprint(r)})
+	vm.logState()
+}
@@ -0,0 +1,75 @@
+package chip8
+
+const (
+	// The COSMAC VIP's 1802 ran at 1.7609MHz and 8 clocks to a machine cycle, 3668 machine cycles every 60Hz
+	// frame. The display's DMA and the interrupt routine driving it took about a third of them for themselves.
+	vipCyclesPerFrame = 3668
+	vipDisplayCycles  = 1224
+
+	// VIPClockSpeed is how many machine cycles a second the VIP had left over to run CHIP-8 instructions,
+	// the clock speed cycle accurate VMs run at unless given another
+	VIPClockSpeed = (vipCyclesPerFrame - vipDisplayCycles) * 60
+
+	// fetchCycles is how many machine cycles the VIP's interpreter took to fetch and decode each instruction
+	// before running it
+	fetchCycles = 40
+)
+
+// opcodeCost is roughly how many machine cycles the VIP's interpreter took to run an instruction once it was
+// decoded, rounded from published timings of it. Skips cost the same whether or not they skip, and the time
+// DXYN spends waiting for the display is left to the DisplayWait quirk. The SUPER-CHIP and XO-CHIP
+// instructions the VIP never had cost as much as their nearest CHIP-8 equivalent.
+func opcodeCost(opcode uint16) int {
+	x := int(opcode&0x0F00) >> 8
+	n := int(opcode & 0x000F)
+	switch opcode & 0xF000 {
+	case 0x0000:
+		if opcode == 0x00E0 {
+			return 24
+		}
+		return 23
+	case 0x1000, 0x2000, 0xB000:
+		return 23
+	case 0x3000, 0x4000:
+		return 12
+	case 0x5000, 0x9000, 0xE000:
+		return 16
+	case 0x6000:
+		return 6
+	case 0x7000:
+		return 10
+	case 0x8000:
+		return 44
+	case 0xA000:
+		return 12
+	case 0xC000:
+		return 36
+	case 0xD000:
+		// Each row is shifted into place and XORed onto two bytes of the display. DXY0 draws 16 rows.
+		if n == 0 {
+			n = 16
+		}
+		return 68 + 46*n
+	}
+	switch opcode & 0xF0FF {
+	case 0xF01E:
+		return 19
+	case 0xF029, 0xF030:
+		return 20
+	case 0xF033:
+		// BCD counts down by hundreds and tens, so it's slow
+		return 204
+	case 0xF055, 0xF065, 0xF075, 0xF085:
+		return 14 + 14*(x+1)
+	}
+	return 10
+}
+
+// tickCost is how many clock cycles running the instruction at pc takes up: 1, or its machine cycles when
+// cycle accurate
+func (vm *VM) tickCost() uint64 {
+	if !vm.cycleAccurate {
+		return 1
+	}
+	return uint64(fetchCycles + opcodeCost(vm.opcodeAt(vm.pc)))
+}
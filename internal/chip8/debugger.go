@@ -0,0 +1,62 @@
+package chip8
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The step debugger is built on halt: a breakpoint halts the VM, and while it's halted space runs a
+// single instruction, halting again right after it, and enter lets it run freely to the next breakpoint.
+
+// ParseBreakpoint parses a breakpoint's address, written like "0x2A4"
+func ParseBreakpoint(s string) (uint16, error) {
+	addr, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid breakpoint %q, expected an address like 0x2A4", s)
+	}
+	return uint16(addr), nil
+}
+
+// startDebugger sets the breakpoints and, when paused, halts the VM before its first instruction.
+// Idle detection is turned off while debugging so every step runs an instruction.
+func (vm *VM) startDebugger(paused bool, breakpoints []uint16) {
+	for _, addr := range breakpoints {
+		vm.breakpoints[addr] = true
+	}
+	if vm.debugging {
+		vm.idle = newIdleDetector(0)
+	}
+	if paused {
+		vm.halt("debugger started")
+	}
+}
+
+// atBreakpoint reports whether the next instruction is a breakpoint, other than the one the VM was just resumed from
+func (vm *VM) atBreakpoint() bool {
+	if vm.resuming {
+		vm.resuming = false
+		return false
+	}
+	return vm.breakpoints[vm.pc]
+}
+
+// finishStep halts the VM after the single instruction it was stepped through and logs what it ran
+func (vm *VM) finishStep() {
+	vm.stepping = false
+	vm.halted = true
+
+	text, ok := Mnemonic(vm.opcode, vm.mode)
+	if !ok {
+		text = fmt.Sprintf("unknown %04X", vm.opcode)
+	}
+	msg, fields := Instruction{Addr: vm.stepFrom, Text: text}.String(), Fields{"addr": vm.stepFrom, "opcode": vm.opcode, "text": text}
+	if res := vm.lastStep; res != nil {
+		if changes := res.Changes(); changes != "" {
+			msg += "  " + changes
+		}
+		fields["step"] = res
+		vm.lastStep = nil
+	}
+	vm.log.Log("step", msg, fields)
+	vm.logState()
+}
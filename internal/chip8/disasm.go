@@ -0,0 +1,191 @@
+package chip8
+
+import "fmt"
+
+// Instruction is one line of a disassembly: an opcode, or for bytes that don't decode, raw data
+type Instruction struct {
+	Addr uint16
+
+	// Bytes are the bytes the instruction was decoded from, 4 for XO-CHIP's F000 NNNN
+	Bytes []byte
+
+	// Text is the decoded mnemonic, e.g. "ANNN I=0x2EA", or the raw bytes when Known is false
+	Text  string
+	Known bool
+}
+
+func (in Instruction) String() string {
+	return fmt.Sprintf("0x%03X: %s", in.Addr, in.Text)
+}
+
+// Disassemble decodes a ROM loaded at 0x200 two bytes at a time. CHIP-8 ROMs mix code and
+// sprite data freely, so words that aren't opcodes come back as raw data rather than an error.
+func Disassemble(rom []byte, mode Mode) []Instruction {
+	var out []Instruction
+	for i := 0; i < len(rom); {
+		in := Instruction{Addr: uint16(0x200 + i)}
+		if i+1 == len(rom) {
+			in.Bytes = rom[i:]
+			in.Text = fmt.Sprintf("0x%02X", rom[i])
+			out = append(out, in)
+			break
+		}
+
+		opcode := uint16(rom[i])<<8 | uint16(rom[i+1])
+		in.Bytes = rom[i : i+2]
+		in.Text, in.Known = Mnemonic(opcode, mode)
+		if in.Known && opcode == 0xF000 && mode == ModeXOChip && i+3 < len(rom) {
+			in.Bytes = rom[i : i+4]
+			in.Text = fmt.Sprintf("F000 NNNN I=0x%04X", uint16(rom[i+2])<<8|uint16(rom[i+3]))
+		}
+		if !in.Known {
+			in.Text = fmt.Sprintf("0x%02X 0x%02X", rom[i], rom[i+1])
+		}
+		out = append(out, in)
+		i += len(in.Bytes)
+	}
+	return out
+}
+
+// Mnemonic decodes an opcode the same way parseOpcode does, naming it by its pattern followed by what it
+// does with its operands, e.g. "ANNN I=0x2EA". ok is false for opcodes the VM would reject as unknown.
+func Mnemonic(opcode uint16, mode Mode) (string, bool) {
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+	nn := opcode & 0x00FF
+	nnn := opcode & 0x0FFF
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opcode == 0x00E0:
+			return "00E0 clear", true
+		case opcode == 0x00EE:
+			return "00EE return", true
+		case opcode == 0x0000:
+			return "", false
+		case opcode&0xFFF0 == 0x00C0, opcode >= 0x00FB && opcode <= 0x00FF:
+			if mode == ModeChip8 {
+				break
+			}
+			switch {
+			case opcode&0xFFF0 == 0x00C0:
+				return fmt.Sprintf("00CN scroll down %d", n), true
+			case opcode == 0x00FB:
+				return "00FB scroll right", true
+			case opcode == 0x00FC:
+				return "00FC scroll left", true
+			case opcode == 0x00FD:
+				return "00FD exit", true
+			case opcode == 0x00FE:
+				return "00FE lores", true
+			case opcode == 0x00FF:
+				return "00FF hires", true
+			}
+		default:
+			return fmt.Sprintf("0NNN sys 0x%03X", nnn), true
+		}
+	case 0x1000:
+		return fmt.Sprintf("1NNN jump 0x%03X", nnn), true
+	case 0x2000:
+		return fmt.Sprintf("2NNN call 0x%03X", nnn), true
+	case 0x3000:
+		return fmt.Sprintf("3XNN skip V%X==0x%02X", x, nn), true
+	case 0x4000:
+		return fmt.Sprintf("4XNN skip V%X!=0x%02X", x, nn), true
+	case 0x5000:
+		return fmt.Sprintf("5XY0 skip V%X==V%X", x, y), true
+	case 0x6000:
+		return fmt.Sprintf("6XNN V%X=0x%02X", x, nn), true
+	case 0x7000:
+		return fmt.Sprintf("7XNN V%X+=0x%02X", x, nn), true
+	case 0x8000:
+		switch n {
+		case 0x0:
+			return fmt.Sprintf("8XY0 V%X=V%X", x, y), true
+		case 0x1:
+			return fmt.Sprintf("8XY1 V%X|=V%X", x, y), true
+		case 0x2:
+			return fmt.Sprintf("8XY2 V%X&=V%X", x, y), true
+		case 0x3:
+			return fmt.Sprintf("8XY3 V%X^=V%X", x, y), true
+		case 0x4:
+			return fmt.Sprintf("8XY4 V%X+=V%X", x, y), true
+		case 0x5:
+			return fmt.Sprintf("8XY5 V%X-=V%X", x, y), true
+		case 0x6:
+			return fmt.Sprintf("8XY6 V%X=V%X>>1", x, y), true
+		case 0x7:
+			return fmt.Sprintf("8XY7 V%X=V%X-V%X", x, y, x), true
+		case 0xE:
+			return fmt.Sprintf("8XYE V%X=V%X<<1", x, y), true
+		}
+	case 0x9000:
+		return fmt.Sprintf("9XY0 skip V%X!=V%X", x, y), true
+	case 0xA000:
+		return fmt.Sprintf("ANNN I=0x%03X", nnn), true
+	case 0xB000:
+		return fmt.Sprintf("BNNN jump 0x%03X+V0", nnn), true
+	case 0xC000:
+		return fmt.Sprintf("CXNN V%X=rand&0x%02X", x, nn), true
+	case 0xD000:
+		if n == 0 && mode != ModeChip8 {
+			return fmt.Sprintf("DXY0 draw16 V%X,V%X", x, y), true
+		}
+		return fmt.Sprintf("DXYN draw V%X,V%X,%d", x, y, n), true
+	case 0xE000:
+		switch nn {
+		case 0x9E:
+			return fmt.Sprintf("EX9E skip key V%X", x), true
+		case 0xA1:
+			return fmt.Sprintf("EXA1 skip !key V%X", x), true
+		}
+	case 0xF000:
+		switch nn {
+		case 0x00:
+			if x == 0 && mode == ModeXOChip {
+				return "F000 NNNN I=next word", true
+			}
+		case 0x02:
+			if x == 0 && mode == ModeXOChip {
+				return "F002 audio=[I]", true
+			}
+		case 0x07:
+			return fmt.Sprintf("FX07 V%X=delay", x), true
+		case 0x0A:
+			return fmt.Sprintf("FX0A V%X=key", x), true
+		case 0x15:
+			return fmt.Sprintf("FX15 delay=V%X", x), true
+		case 0x18:
+			return fmt.Sprintf("FX18 sound=V%X", x), true
+		case 0x1E:
+			return fmt.Sprintf("FX1E I+=V%X", x), true
+		case 0x29:
+			return fmt.Sprintf("FX29 I=font V%X", x), true
+		case 0x30:
+			if mode != ModeChip8 {
+				return fmt.Sprintf("FX30 I=bigfont V%X", x), true
+			}
+		case 0x3A:
+			if mode == ModeXOChip {
+				return fmt.Sprintf("FX3A pitch=V%X", x), true
+			}
+		case 0x33:
+			return fmt.Sprintf("FX33 bcd V%X", x), true
+		case 0x55:
+			return fmt.Sprintf("FX55 save V0-V%X", x), true
+		case 0x65:
+			return fmt.Sprintf("FX65 load V0-V%X", x), true
+		case 0x75:
+			if mode != ModeChip8 {
+				return fmt.Sprintf("FX75 rpl=V0-V%X", x), true
+			}
+		case 0x85:
+			if mode != ModeChip8 {
+				return fmt.Sprintf("FX85 V0-V%X=rpl", x), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,68 @@
+package chip8
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Mnemonic is meant to decode exactly the opcodes parseOpcode runs, so every opcode in every mode is checked
+// against what the VM does with it
+func TestMnemonicMatchesParseOpcode(t *testing.T) {
+	for _, m := range Modes {
+		vm := newTestVM(t, Config{Mode: m})
+		for op := 0; op <= 0xFFFF; op++ {
+			opcode := uint16(op)
+			vm.pc, vm.sp, vm.awaitingKey = 0x200, 0, false
+			err := vm.exec(opcode)
+			known := err == nil || errors.Is(err, errStackOverflow) || errors.Is(err, errStackUnderflow)
+
+			if _, ok := Mnemonic(opcode, m); ok != known {
+				t.Errorf("%s: Mnemonic(%04X) ok = %v, but parseOpcode returned %v", m, opcode, ok, err)
+			}
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		name string
+		rom  []byte
+		mode Mode
+		want []Instruction
+	}{
+		{
+			name: "opcodes",
+			rom:  []byte{0x00, 0xE0, 0xA2, 0xEA},
+			want: []Instruction{
+				{Addr: 0x200, Bytes: []byte{0x00, 0xE0}, Text: "00E0 clear", Known: true},
+				{Addr: 0x202, Bytes: []byte{0xA2, 0xEA}, Text: "ANNN I=0x2EA", Known: true},
+			},
+		},
+		{
+			name: "data comes back as raw bytes",
+			rom:  []byte{0x00, 0x00, 0x81, 0x2F, 0x12},
+			want: []Instruction{
+				{Addr: 0x200, Bytes: []byte{0x00, 0x00}, Text: "0x00 0x00"},
+				{Addr: 0x202, Bytes: []byte{0x81, 0x2F}, Text: "0x81 0x2F"},
+				{Addr: 0x204, Bytes: []byte{0x12}, Text: "0x12"},
+			},
+		},
+		{
+			name: "XO-CHIP's long load takes 4 bytes",
+			rom:  []byte{0xF0, 0x00, 0x12, 0x34, 0x00, 0xE0},
+			mode: ModeXOChip,
+			want: []Instruction{
+				{Addr: 0x200, Bytes: []byte{0xF0, 0x00, 0x12, 0x34}, Text: "F000 NNNN I=0x1234", Known: true},
+				{Addr: 0x204, Bytes: []byte{0x00, 0xE0}, Text: "00E0 clear", Known: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Disassemble(tt.rom, tt.mode); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Disassemble = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package chip8
+
+import (
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+// Display is what the VM draws frames to and reads the keypad from. pixel.Window is the
+// real thing, headlessDisplay stands in when there is no screen to draw to.
+type Display interface {
+	// DrawGraphics presents a frame of cols pixels a row and polls for input. Pixels listed in collided, by their index
+	// into gfx, are where the frame's sprites collided, and the ones in drawn are the sprite just
+	// drawn when stepping through draws. Both are tinted to stand out.
+	DrawGraphics(gfx []byte, cols int, collided, drawn []uint16)
+
+	// UpdateInput polls for input without drawing
+	UpdateInput()
+
+	// Closed reports whether the user closed the display
+	Closed() bool
+
+	// KeyJustPressed and KeyJustReleased report whether the host key bound to a
+	// CHIP-8 hex key went down or up since the last poll
+	KeyJustPressed(key byte) bool
+	KeyJustReleased(key byte) bool
+
+	// Hotkeys reports the emulator's own controls whose keys were pressed since the last poll, along with
+	// Rewind and FastForward while their keys are held
+	Hotkeys() hotkey.Actions
+
+	// ToggleFullscreen switches between fullscreen and windowed
+	ToggleFullscreen()
+
+	// ShowSpeed sets the speed overlay's text, hiding it when empty
+	ShowSpeed(text string)
+
+	// ShowOverlay shows msg over the screen for d
+	ShowOverlay(msg string, d time.Duration)
+
+	// SetTitle sets the display's title, and SetClosed takes back the user closing it, for a playlist's next ROM
+	SetTitle(title string)
+	SetClosed(closed bool)
+}
+
+// headlessDisplay is a Display that draws nothing, never closes, and never has a key pressed.
+// Input for a headless VM comes from elsewhere, like a replay.
+type headlessDisplay struct{}
+
+func (headlessDisplay) DrawGraphics(gfx []byte, cols int, collided, drawn []uint16) {}
+func (headlessDisplay) UpdateInput()                                                {}
+func (headlessDisplay) Closed() bool                                                { return false }
+func (headlessDisplay) KeyJustPressed(key byte) bool                                { return false }
+func (headlessDisplay) KeyJustReleased(key byte) bool                               { return false }
+func (headlessDisplay) Hotkeys() hotkey.Actions                                     { return 0 }
+func (headlessDisplay) ToggleFullscreen()                                           {}
+func (headlessDisplay) ShowSpeed(text string)                                       {}
+func (headlessDisplay) ShowOverlay(msg string, d time.Duration)                     {}
+func (headlessDisplay) SetTitle(title string)                                       {}
+func (headlessDisplay) SetClosed(closed bool)                                       {}
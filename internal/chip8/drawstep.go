@@ -0,0 +1,34 @@
+package chip8
+
+import (
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+// pausesOnDraw reports whether the cycle that just ran drew a sprite the VM should pause on
+func (vm *VM) pausesOnDraw() bool {
+	return vm.drawStep > 0 && !vm.headless && vm.drawFlag && vm.opcode&0xF000 == 0xD000
+}
+
+// pauseOnDraw logs the draw and holds the VM on the frame it drew for the draw step, or until the
+// step key is pressed. The window keeps polling input so it stays responsive.
+func (vm *VM) pauseOnDraw() {
+	if res := vm.lastStep; res != nil {
+		text, _ := Mnemonic(res.Opcode, vm.mode)
+		msg := Instruction{Addr: res.PC, Text: text}.String()
+		if changes := res.Changes(); changes != "" {
+			msg += "  " + changes
+		}
+		vm.log.Log("draw_step", msg, Fields{"step": res})
+		vm.lastStep = nil
+	}
+	deadline := time.Now().Add(vm.drawStep)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second / 60)
+		vm.window.UpdateInput()
+		if vm.window.Closed() || vm.window.Hotkeys().Has(hotkey.Step) {
+			return
+		}
+	}
+}
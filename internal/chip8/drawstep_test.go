@@ -0,0 +1,64 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+func TestPausesOnDraw(t *testing.T) {
+	tests := []struct {
+		name     string
+		drawStep time.Duration
+		headless bool
+		opcode   uint16
+		want     bool
+	}{
+		{name: "sprite draw", drawStep: time.Second, opcode: 0xD015, want: true},
+		{name: "draw step off", opcode: 0xD015},
+		{name: "headless", drawStep: time.Second, headless: true, opcode: 0xD015},
+		{name: "clearing the screen", drawStep: time.Second, opcode: 0x00E0},
+		{name: "not drawing", drawStep: time.Second, opcode: 0x6001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newTestVM(t, Config{DrawStep: tt.drawStep})
+			vm.headless = tt.headless
+			if err := vm.exec(tt.opcode); err != nil {
+				t.Fatal(err)
+			}
+			if got := vm.pausesOnDraw(); got != tt.want {
+				t.Errorf("pausesOnDraw = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stepDisplay has the step key pressed every time it's polled
+type stepDisplay struct {
+	headlessDisplay
+}
+
+func (stepDisplay) Hotkeys() hotkey.Actions { return hotkey.Actions(0).With(hotkey.Step) }
+
+func TestStepKeyEndsTheDrawPause(t *testing.T) {
+	var log strings.Builder
+	vm := newTestVM(t, Config{DrawStep: time.Hour, Logger: TextLogger{W: &log}}, 0xD015)
+	vm.window, vm.headless = stepDisplay{}, false
+
+	done := make(chan struct{})
+	go func() {
+		vm.clockCycle()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the step key didn't end the pause")
+	}
+	if got, want := log.String(), "0x200: DXYN draw V0,V1,5"; !strings.HasPrefix(got, want) {
+		t.Errorf("logged %q, want the draw starting %q", got, want)
+	}
+}
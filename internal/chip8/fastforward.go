@@ -0,0 +1,49 @@
+package chip8
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+const (
+	// defaultFastForward is how many times faster the VM runs while Tab is held unless configured otherwise
+	defaultFastForward = 10
+
+	// maxFastForward is the most times faster fast-forwarding can be configured to run
+	maxFastForward = 100
+)
+
+// handleFastForward runs the VM fastForward times faster for as long as Tab is held, for skipping through
+// intros and cutscenes. Unlike turbo the timers speed up with the clock, so the whole game fast-forwards
+// as it would have played, and the sound is silenced rather than beeping for the wrong lengths.
+func (vm *VM) handleFastForward(keys hotkey.Actions) {
+	held := keys.Has(hotkey.FastForward) && vm.fastForward > 1
+	if held == vm.fastForwarding {
+		return
+	}
+	vm.fastForwarding = held
+	vm.applyTurbo()
+	vm.syncSoundState()
+
+	// Auto speed would take the fast-forwarded speed for what it has to keep up with, so it starts its window over
+	if vm.speed != nil {
+		vm.speed.windowStart, vm.speed.ticks = time.Time{}, 0
+	}
+	if held {
+		vm.window.ShowOverlay(fmt.Sprintf(">> x%d", vm.fastForward), time.Hour)
+	} else {
+		vm.window.ShowOverlay("", 0)
+	}
+	vm.frameDirty = true
+}
+
+// speedFactor is how many times faster than real time the VM runs: fastForward while fast-forwarding and
+// 1 otherwise. The clock speed and the timers' 60Hz are both scaled by it.
+func (vm *VM) speedFactor() int {
+	if vm.fastForwarding {
+		return vm.fastForward
+	}
+	return 1
+}
@@ -0,0 +1,39 @@
+package chip8
+
+import "github.com/bradford-hamilton/chippy/internal/hotkey"
+
+// halt stops the VM executing and logs why, along with its state. A window stays open on the current
+// frame until space resumes it, while a headless VM, having nobody to resume it, stops running.
+func (vm *VM) halt(reason string) {
+	vm.log.Log("halted", "halted: "+reason, Fields{"reason": reason, "pc": vm.pc, "cycles": vm.cycles})
+	vm.logState()
+
+	if vm.headless {
+		vm.exited = true
+		return
+	}
+	vm.halted = true
+	if vm.debugging {
+		vm.log.Log("prompt", "press space to step, enter to continue", nil)
+	} else {
+		vm.log.Log("prompt", "press space to resume", nil)
+	}
+}
+
+// whileHalted keeps the window responsive while the VM is halted, resuming when space is pressed.
+// In the debugger space steps a single instruction instead, and enter resumes.
+func (vm *VM) whileHalted() {
+	if vm.frameDirty {
+		vm.presentFrame()
+	} else {
+		vm.window.UpdateInput()
+	}
+	keys := vm.window.Hotkeys()
+	switch {
+	case vm.debugging && keys.Has(hotkey.Step):
+		vm.halted, vm.stepping, vm.resuming = false, true, true
+		vm.stepFrom = vm.pc
+	case vm.debugging && keys.Has(hotkey.Continue), !vm.debugging && keys.Has(hotkey.Step):
+		vm.halted, vm.resuming = false, true
+	}
+}
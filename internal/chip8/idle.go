@@ -0,0 +1,105 @@
+package chip8
+
+// idleState is the part of the machine an idle loop must leave untouched. If the VM comes back to the
+// same idleState within a few instructions, nothing but a keypress or the delay timer can get it out.
+type idleState struct {
+	pc         uint16
+	i          uint16
+	sp         uint16
+	v          [16]byte
+	delayTimer byte
+}
+
+// idleDetector spots ROMs spinning in small loops that don't change any state, from the common
+// self jump (1NNN to itself) to short wait loops, so the VM can stop executing them
+type idleDetector struct {
+	// Longest loop, in instructions, recognized as idle. Zero disables detection
+	window int
+
+	// The states seen before the last window instructions ran
+	history []idleState
+
+	// Whether the VM is currently idle, and the inputs that will wake it up when changed
+	active     bool
+	keypad     [16]byte
+	keysHeld   [16]bool
+	delayTimer byte
+}
+
+func newIdleDetector(window int) idleDetector {
+	return idleDetector{window: window, history: make([]idleState, 0, window)}
+}
+
+// observe records the state before an instruction runs and reports whether it
+// matches one seen within the last window instructions
+func (d *idleDetector) observe(s idleState) bool {
+	for _, h := range d.history {
+		if h == s {
+			return true
+		}
+	}
+	if len(d.history) == d.window {
+		copy(d.history, d.history[1:])
+		d.history = d.history[:d.window-1]
+	}
+	d.history = append(d.history, s)
+	return false
+}
+
+func (d *idleDetector) reset() {
+	d.history = d.history[:0]
+	d.active = false
+}
+
+// idling reports whether the next cycle can be skipped because the ROM is sitting in an idle loop.
+// The VM stays idle until a key changes or the delay timer moves.
+func (vm *VM) idling() bool {
+	d := &vm.idle
+	if d.window == 0 {
+		return false
+	}
+
+	if d.active {
+		if vm.keypad == d.keypad && vm.keysHeld == d.keysHeld && vm.delayTimer == d.delayTimer {
+			return true
+		}
+		d.reset()
+		return false
+	}
+
+	// Loops with instructions that draw, write memory, touch the timers, or roll random numbers do real work
+	if hasSideEffects(vm.opcodeAt(vm.pc)) {
+		d.reset()
+		return false
+	}
+
+	if d.observe(idleState{pc: vm.pc, i: vm.i, sp: vm.sp, v: vm.v, delayTimer: vm.delayTimer}) {
+		d.active = true
+		d.keypad = vm.keypad
+		d.keysHeld = vm.keysHeld
+		d.delayTimer = vm.delayTimer
+		return true
+	}
+	return false
+}
+
+// hasSideEffects reports whether an opcode changes state outside of the registers idleState tracks
+func hasSideEffects(opcode uint16) bool {
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opcode == 0x00E0, opcode&0xFFF0 == 0x00C0:
+			return true
+		case opcode >= 0x00FB && opcode <= 0x00FF:
+			return true
+		}
+	case 0xC000, 0xD000:
+		return true
+	case 0xF000:
+		switch opcode & 0x00FF {
+		case 0x0002, 0x0015, 0x0018, 0x0033, 0x003A, 0x0055, 0x0075:
+			return true
+		}
+	}
+	return false
+}
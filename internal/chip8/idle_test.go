@@ -0,0 +1,62 @@
+package chip8
+
+import "testing"
+
+func TestIdleDetection(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   int
+		program  []uint16
+		wantIdle bool
+	}{
+		{name: "self jump", window: 1, program: []uint16{0x1200}, wantIdle: true},
+		{name: "two instruction loop", window: 2, program: []uint16{0x1202, 0x1200}, wantIdle: true},
+		{name: "two instruction loop past the window", window: 1, program: []uint16{0x1202, 0x1200}},
+		{name: "loop that skips on a register", window: 2, program: []uint16{0x3001, 0x1200}, wantIdle: true},
+		{name: "loop that counts", window: 2, program: []uint16{0x7001, 0x1200}},
+		{name: "loop that draws", window: 2, program: []uint16{0xD001, 0x1200}},
+		{name: "detection off", window: 0, program: []uint16{0x1200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newTestVM(t, Config{IdleWindow: tt.window}, tt.program...)
+			for i := 0; i < 20; i++ {
+				vm.clockCycle()
+			}
+
+			if vm.idle.active != tt.wantIdle {
+				t.Errorf("idle = %v, want %v", vm.idle.active, tt.wantIdle)
+			}
+			// An idle VM stops executing the loop as soon as it comes back around
+			ran := vm.stats.Instructions.Load()
+			if tt.wantIdle && ran > uint64(tt.window)+1 {
+				t.Errorf("ran %d instructions idling, want at most %d", ran, tt.window+1)
+			}
+			if !tt.wantIdle && ran != 20 {
+				t.Errorf("ran %d instructions, want all 20", ran)
+			}
+		})
+	}
+}
+
+func TestIdleWakesOnKeypress(t *testing.T) {
+	// Wait in a two instruction loop until key 5 is down, then jump to 0x206 forever
+	vm := newTestVM(t, Config{IdleWindow: 2}, 0x6005, 0xE09E, 0x1202, 0x1206)
+	for i := 0; i < 10; i++ {
+		vm.clockCycle()
+	}
+	if !vm.idle.active {
+		t.Fatal("the key wait loop wasn't detected as idle")
+	}
+
+	vm.pressKey(5)
+	vm.clockCycle()
+	if vm.idle.active {
+		t.Error("still idle after a keypress")
+	}
+	vm.clockCycle()
+	vm.clockCycle()
+	if vm.pc != 0x206 {
+		t.Errorf("pc = 0x%03X, want the loop left for 0x206", vm.pc)
+	}
+}
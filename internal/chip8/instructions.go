@@ -1,30 +1,69 @@
 package chip8
 
-import "math/rand"
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors for calls nested deeper than the stack holds and returns with no call to return from
+var (
+	errStackOverflow  = errors.New("stack overflow")
+	errStackUnderflow = errors.New("stack underflow")
+)
+
+// skipNext moves pc past the following instruction. In XO-CHIP mode that
+// may be the 4 byte F000 NNNN, which has to be skipped as a whole.
+func (vm *VM) skipNext() {
+	vm.pc += 2
+	if vm.mode == ModeXOChip && vm.opcodeAt(vm.pc) == 0xF000 {
+		vm.pc += 2
+	}
+	vm.pc += 2
+}
+
+// 0NNN ran a routine in the host CPU's machine code on the COSMAC VIP. There's no 1802 to run it on, and
+// ROMs outside the VIP's own never use it, so it's skipped like a no-op. The first one is logged, since a
+// ROM that needs it won't run right. 0000, and SUPER-CHIP's 00 opcodes when they don't apply, are
+// reported as unknown opcodes instead, being far more likely a crash or the wrong --mode.
+func (vm *VM) _0x0NNN(nnn uint16) {
+	if !vm.machineCodeLogged {
+		vm.machineCodeLogged = true
+		vm.log.Log("machine_code_call", fmt.Sprintf("skipping 0%03X at 0x%03X, chippy can't run machine code routines. Any more are skipped silently", nnn, vm.pc),
+			Fields{"addr": vm.pc, "target": nnn})
+	}
+	vm.pc += 2
+}
 
 func (vm *VM) _0x00E0() {
-	vm.gfx = [64 * 32]byte{}
+	vm.gfx = [128 * 64]byte{}
 	vm.pc += 2
 }
 
-func (vm *VM) _0x00EE() {
-	vm.pc = vm.stack[vm.sp] + 2
-	vm.sp--
+// sp is the number of return addresses on the stack, so the first call's lands in stack[0]
+func (vm *VM) _0x00EE() error {
+	addr, ok := vm.pop()
+	if !ok {
+		return fmt.Errorf("%w: 00EE at 0x%03X with nothing to return to", errStackUnderflow, vm.pc)
+	}
+	vm.pc = addr + 2
+	return nil
 }
 
 func (vm *VM) _0x1000(nnn uint16) {
 	vm.pc = nnn
 }
 
-func (vm *VM) _0x2000(nnn uint16) {
-	vm.sp++
-	vm.stack[vm.sp] = vm.pc
+func (vm *VM) _0x2000(nnn uint16) error {
+	if !vm.push(vm.pc) {
+		return fmt.Errorf("%w: calling 0x%03X at 0x%03X, %d calls deep", errStackOverflow, nnn, vm.pc, len(vm.stack))
+	}
 	vm.pc = nnn
+	return nil
 }
 
 func (vm *VM) _0x3000(x uint16, nn byte) {
 	if vm.v[x] == nn {
-		vm.pc += 4
+		vm.skipNext()
 	} else {
 		vm.pc += 2
 	}
@@ -32,7 +71,7 @@ func (vm *VM) _0x3000(x uint16, nn byte) {
 
 func (vm *VM) _0x4000(x uint16, nn byte) {
 	if vm.v[x] != nn {
-		vm.pc += 4
+		vm.skipNext()
 	} else {
 		vm.pc += 2
 	}
@@ -40,7 +79,7 @@ func (vm *VM) _0x4000(x uint16, nn byte) {
 
 func (vm *VM) _0x5000(x, y uint16) {
 	if vm.v[x] == vm.v[y] {
-		vm.pc += 4
+		vm.skipNext()
 	} else {
 		vm.pc += 2
 	}
@@ -63,72 +102,95 @@ func (vm *VM) _0x0000(x, y uint16) {
 
 func (vm *VM) _0x0001(x, y uint16) {
 	vm.v[x] |= vm.v[y]
+	vm.logicResetVF()
 	vm.pc += 2
 }
 
 func (vm *VM) _0x0002(x, y uint16) {
 	vm.v[x] &= vm.v[y]
+	vm.logicResetVF()
 	vm.pc += 2
 }
 
 func (vm *VM) _0x0003(x, y uint16) {
 	vm.v[x] ^= vm.v[y]
+	vm.logicResetVF()
 	vm.pc += 2
 }
 
+// logicResetVF clears VF after 8XY1, 8XY2, and 8XY3 when the LogicResetsVF quirk is on
+func (vm *VM) logicResetVF() {
+	if vm.quirks.LogicResetsVF {
+		vm.v[0xF] = 0
+	}
+}
+
 // Set VF to 01 if a carry occurs
 // Set VF to 00 if a carry does not occur
+// VF is written last, so with VF as VX the flag is what's left in it
 func (vm *VM) _0x0004(x, y uint16) {
-	if vm.v[y] > (0xFF - vm.v[x]) {
-		vm.v[0xF] = 1
-	} else {
-		vm.v[0xF] = 0
-	}
-	vm.v[x] += vm.v[y]
+	sum := uint16(vm.v[x]) + uint16(vm.v[y])
+	vm.v[x] = byte(sum)
+	vm.v[0xF] = byte(sum >> 8)
 	vm.pc += 2
 }
 
 // Set VF to 00 if a borrow occurs
 // Set VF to 01 if a borrow does not occur
+// VF is written last, like _0x0004
 func (vm *VM) _0x0005(x, y uint16) {
-	if vm.v[y] > vm.v[x] {
-		vm.v[0xF] = 0
-	} else {
-		vm.v[0xF] = 1
-	}
+	flag := noBorrow(vm.v[x], vm.v[y])
 	vm.v[x] -= vm.v[y]
+	vm.v[0xF] = flag
 	vm.pc += 2
 }
 
-// Set register VF to the least significant bit prior to the shift
+// Set register VF to the least significant bit prior to the shift. The value is read before VX is written,
+// since with the shift quirk it is VX.
 func (vm *VM) _0x0006(x, y uint16) {
-	vm.v[x] = vm.v[y] >> 1
-	vm.v[0xF] = vm.v[y] & 0x01
+	v := vm.v[vm.shiftSource(x, y)]
+	vm.v[x] = v >> 1
+	vm.v[0xF] = v & 0x01
 	vm.pc += 2
 }
 
 // Set VF to 00 if a borrow occurs
 // Set VF to 01 if a borrow does not occur
+// VF is written last, like _0x0004
 func (vm *VM) _0x0007_1(x, y uint16) {
-	if vm.v[x] > vm.v[y] {
-		vm.v[0xF] = 0
-	} else {
-		vm.v[0xF] = 1
-	}
+	flag := noBorrow(vm.v[y], vm.v[x])
 	vm.v[x] = vm.v[y] - vm.v[x]
+	vm.v[0xF] = flag
 	vm.pc += 2
 }
 
-// Set register VF to the most significant bit prior to the shift
+// noBorrow is VF after subtracting b from a: 1 if it doesn't borrow, 0 if it does
+func noBorrow(a, b byte) byte {
+	if b > a {
+		return 0
+	}
+	return 1
+}
+
+// Set register VF to the most significant bit prior to the shift, read before VX is written like _0x0006
 func (vm *VM) _0x000E(x, y uint16) {
-	vm.v[x] = vm.v[y] << 1
-	vm.v[0xF] = vm.v[y] & 0x80
+	v := vm.v[vm.shiftSource(x, y)]
+	vm.v[x] = v << 1
+	vm.v[0xF] = (v >> 7) & 0x01
 	vm.pc += 2
 }
 
+// shiftSource returns the register 8XY6 and 8XYE shift, VX with the shift quirk and VY without
+func (vm *VM) shiftSource(x, y uint16) uint16 {
+	if vm.quirks.ShiftInPlace {
+		return x
+	}
+	return y
+}
+
 func (vm *VM) _0x9000(x, y uint16) {
 	if vm.v[x] != vm.v[y] {
-		vm.pc += 4
+		vm.skipNext()
 	} else {
 		vm.pc += 2
 	}
@@ -139,57 +201,141 @@ func (vm *VM) _0xA000(nnn uint16) {
 	vm.pc += 2
 }
 
-func (vm *VM) _0xB000(nnn uint16) {
+func (vm *VM) _0xB000(x, nnn uint16) {
+	if vm.quirks.JumpUsesVX {
+		vm.pc = nnn + uint16(vm.v[x])
+		return
+	}
 	vm.pc = nnn + uint16(vm.v[0])
-	vm.pc += 2
 }
 
 func (vm *VM) _0xC000(x uint16, nn byte) {
-	vm.v[x] = byte(rand.Float32()*255) & nn
+	vm.v[x] = byte(vm.rng.Intn(256)) & nn
 	vm.pc += 2
 }
 
 // Set VF to 01 if any set pixels are changed to unset, and 00 otherwise
 // Get the starting x and y coordinates of the graphics array.
+// With DisplayWait, DXYN stalls until the clock cycle right after a 60Hz timer tick, running again each
+// cycle without moving on, and only then draws
 func (vm *VM) _0xD000(x, y uint16) {
+	if vm.quirks.DisplayWait && !vm.vblank {
+		return
+	}
 	x = uint16(vm.v[x])
 	y = uint16(vm.v[y])
 	vm.drawSprite(x, y)
 	vm.pc += 2
 }
 
+// Only the low nibble of VX selects a key, like on the original hardware, so
+// a stray high bit can't index past the keypad
 func (vm *VM) _0x009E(x uint16) {
-	if vm.keypad[vm.v[x]] == 1 {
-		vm.pc += 4
-		vm.keypad[vm.v[x]] = 0
+	key := vm.v[x] & 0x0F
+	if vm.keypad[key] == 1 {
+		vm.skipNext()
+		vm.consumeKey(key)
 	} else {
 		vm.pc += 2
 	}
 }
 
+// Only the low nibble of VX selects a key, see _0x009E
 func (vm *VM) _0x00A1(x uint16) {
-	if vm.keypad[vm.v[x]] == 0 {
-		vm.pc += 4
+	key := vm.v[x] & 0x0F
+	if vm.keypad[key] == 0 {
+		vm.skipNext()
 	} else {
-		vm.keypad[vm.v[x]] = 0
+		vm.consumeKey(key)
 		vm.pc += 2
 	}
 }
 
+// Run stops at its next tick and signals the shutdown
+func (vm *VM) _0x00FD() {
+	vm.exited = true
+}
+
+func (vm *VM) _0x00C0(n uint16) {
+	vm.scroll(0, int(n))
+	vm.pc += 2
+}
+
+func (vm *VM) _0x00FB() {
+	vm.scroll(4, 0)
+	vm.pc += 2
+}
+
+func (vm *VM) _0x00FC() {
+	vm.scroll(-4, 0)
+	vm.pc += 2
+}
+
+// Switching resolution clears the screen, the window rescales on the next draw
+func (vm *VM) _0x00FE() {
+	vm.hires = false
+	vm.gfx = [128 * 64]byte{}
+	vm.drawFlag = true
+	vm.pc += 2
+}
+
+func (vm *VM) _0x00FF() {
+	vm.hires = true
+	vm.gfx = [128 * 64]byte{}
+	vm.drawFlag = true
+	vm.pc += 2
+}
+
+// scroll moves the screen's contents dx pixels right and dy pixels down, clearing the pixels scrolled in
+func (vm *VM) scroll(dx, dy int) {
+	w, h := vm.resolution()
+	var gfx [128 * 64]byte
+	for y := range h {
+		for x := range w {
+			sx, sy := x-dx, y-dy
+			if sx < 0 || sx >= w || sy < 0 || sy >= h {
+				continue
+			}
+			gfx[y*w+x] = vm.gfx[sy*w+sx]
+		}
+	}
+	vm.gfx = gfx
+	vm.drawFlag = true
+}
+
+// The address is the word following the opcode, so pc moves past both
+func (vm *VM) _0x0000_2() {
+	vm.i = vm.opcodeAt(vm.pc + 2)
+	vm.pc += 4
+}
+
+func (vm *VM) _0x0002_2() {
+	for ind := range vm.soundBuffer {
+		vm.soundBuffer[ind] = vm.memory[vm.addr(vm.i+uint16(ind))]
+	}
+	vm.syncPattern(vm.soundPlaying.Load())
+	vm.pc += 2
+}
+
 func (vm *VM) _0x0007_2(x uint16) {
 	vm.v[x] = vm.delayTimer
 	vm.pc += 2
 }
 
+// FX0A stalls, running again every cycle without moving on, until a key is pressed after it started
+// waiting and then released, like the original COSMAC VIP. Keys already held when it starts don't count.
 func (vm *VM) _0x000A(x uint16) {
-	for i, k := range vm.keypad {
-		if k != 0 {
-			vm.v[x] = byte(i)
-			vm.pc += 2
-			break
-		}
+	if !vm.awaitingKey {
+		vm.awaitingKey, vm.awaitedKey = true, -1
+		return
 	}
-	vm.keypad[vm.v[x]] = 0
+	if vm.awaitedKey < 0 || vm.keysHeld[vm.awaitedKey] {
+		return
+	}
+	vm.v[x] = byte(vm.awaitedKey)
+	vm.consumeKey(byte(vm.awaitedKey))
+	vm.awaitingKey = false
+	vm.pc += 2
 }
 
 func (vm *VM) _0x0015(x uint16) {
@@ -212,25 +358,54 @@ func (vm *VM) _0x0029(x uint16) {
 	vm.pc += 2
 }
 
+func (vm *VM) _0x003A(x uint16) {
+	vm.pitch = vm.v[x]
+	vm.syncPattern(vm.soundPlaying.Load())
+	vm.pc += 2
+}
+
+// Large font glyphs are 10 bytes each
+func (vm *VM) _0x0030(x uint16) {
+	vm.i = largeFontAddr + uint16(vm.v[x]&0x0F)*10
+	vm.pc += 2
+}
+
+// Store the hundreds, tens, and ones digits of VX at i, i+1, and i+2
 func (vm *VM) _0x0033(x uint16) {
-	vm.memory[vm.i] = vm.v[x] / 100
-	vm.memory[vm.i+1] = (vm.v[x] / 10) % 10
-	vm.memory[vm.i+2] = (vm.v[x] % 100) % 10
+	vm.store(vm.addr(vm.i), vm.v[x]/100)
+	vm.store(vm.addr(vm.i+1), (vm.v[x]/10)%10)
+	vm.store(vm.addr(vm.i+2), vm.v[x]%10)
 	vm.pc += 2
 }
 
-// i is set to i+x+1 after operation
+// i is set to i+x+1 after operation with the load/store quirk
 func (vm *VM) _0x0065(x uint16) {
 	for ind := uint16(0); ind <= x; ind++ {
-		vm.v[ind] = vm.memory[vm.i+ind]
+		vm.v[ind] = vm.memory[vm.addr(vm.i+ind)]
+	}
+	if vm.quirks.LoadStoreIncrementsI {
+		vm.i += x + 1
 	}
 	vm.pc += 2
 }
 
-// i is set to i+x+1 after operation
+// i is set to i+x+1 after operation with the load/store quirk
 func (vm *VM) _0x0055(x uint16) {
 	for ind := uint16(0); ind <= x; ind++ {
-		vm.memory[vm.i+ind] = vm.v[ind]
+		vm.store(vm.addr(vm.i+ind), vm.v[ind])
+	}
+	if vm.quirks.LoadStoreIncrementsI {
+		vm.i += x + 1
 	}
 	vm.pc += 2
 }
+
+func (vm *VM) _0x0075(x uint16) {
+	copy(vm.rpl[:x+1], vm.v[:x+1])
+	vm.pc += 2
+}
+
+func (vm *VM) _0x0085(x uint16) {
+	copy(vm.v[:x+1], vm.rpl[:x+1])
+	vm.pc += 2
+}
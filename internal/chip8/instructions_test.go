@@ -0,0 +1,709 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// opcodeTest runs a single opcode at 0x200 on a VM set up by setup, then checks pc and whatever else check looks at
+type opcodeTest struct {
+	name   string
+	opcode uint16
+	mode   Mode
+	quirks Quirks
+	setup  func(vm *VM)
+	pc     uint16
+	check  func(t *testing.T, vm *VM)
+}
+
+// regs returns a check that the registers listed hold the values given, as pairs of register and value
+func regs(pairs ...int) func(t *testing.T, vm *VM) {
+	return func(t *testing.T, vm *VM) {
+		t.Helper()
+		for i := 0; i < len(pairs); i += 2 {
+			if got, want := vm.v[pairs[i]], byte(pairs[i+1]); got != want {
+				t.Errorf("V%X = 0x%02X, want 0x%02X", pairs[i], got, want)
+			}
+		}
+	}
+}
+
+// set returns a setup that loads the registers listed, as pairs of register and value
+func set(pairs ...int) func(vm *VM) {
+	return func(vm *VM) {
+		for i := 0; i < len(pairs); i += 2 {
+			vm.v[pairs[i]] = byte(pairs[i+1])
+		}
+	}
+}
+
+func runOpcodeTests(t *testing.T, tests []opcodeTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newTestVM(t, Config{Mode: tt.mode, Quirks: tt.quirks})
+			if tt.setup != nil {
+				tt.setup(vm)
+			}
+			if err := vm.exec(tt.opcode); err != nil {
+				t.Fatalf("%04X: %v", tt.opcode, err)
+			}
+			if vm.pc != tt.pc {
+				t.Errorf("pc = 0x%03X, want 0x%03X", vm.pc, tt.pc)
+			}
+			if tt.check != nil {
+				tt.check(t, vm)
+			}
+		})
+	}
+}
+
+func TestFlowOpcodes(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "00E0 clears the screen", opcode: 0x00E0, pc: 0x202,
+			setup: func(vm *VM) { vm.gfx[5], vm.gfx[64*32-1] = 1, 1 },
+			check: func(t *testing.T, vm *VM) {
+				if vm.gfx != [128 * 64]byte{} {
+					t.Error("screen not cleared")
+				}
+			}},
+		{name: "00EE returns past the call", opcode: 0x00EE, pc: 0x302,
+			setup: func(vm *VM) { vm.push(0x300) },
+			check: func(t *testing.T, vm *VM) {
+				if vm.sp != 0 {
+					t.Errorf("sp = %d, want 0", vm.sp)
+				}
+			}},
+		{name: "1NNN jumps", opcode: 0x1345, pc: 0x345},
+		{name: "2NNN calls", opcode: 0x2345, pc: 0x345,
+			check: func(t *testing.T, vm *VM) {
+				if vm.sp != 1 || vm.stack[0] != 0x200 {
+					t.Errorf("sp = %d, stack[0] = 0x%03X, want 1 and 0x200", vm.sp, vm.stack[0])
+				}
+			}},
+		{name: "BNNN jumps from V0", opcode: 0xB300, pc: 0x310, setup: set(0x0, 0x10, 0x3, 0x20)},
+		{name: "BXNN jumps from VX with the jump quirk", opcode: 0xB300, pc: 0x320, quirks: Quirks{JumpUsesVX: true},
+			setup: set(0x0, 0x10, 0x3, 0x20)},
+	})
+}
+
+func TestSkipOpcodes(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "3XNN skips when equal", opcode: 0x3A42, pc: 0x204, setup: set(0xA, 0x42)},
+		{name: "3XNN doesn't skip when not", opcode: 0x3A42, pc: 0x202, setup: set(0xA, 0x41)},
+		{name: "4XNN skips when not equal", opcode: 0x4A42, pc: 0x204, setup: set(0xA, 0x41)},
+		{name: "4XNN doesn't skip when equal", opcode: 0x4A42, pc: 0x202, setup: set(0xA, 0x42)},
+		{name: "5XY0 skips when equal", opcode: 0x5120, pc: 0x204, setup: set(0x1, 7, 0x2, 7)},
+		{name: "5XY0 doesn't skip when not", opcode: 0x5120, pc: 0x202, setup: set(0x1, 7, 0x2, 8)},
+		{name: "9XY0 skips when not equal", opcode: 0x9120, pc: 0x204, setup: set(0x1, 7, 0x2, 8)},
+		{name: "9XY0 doesn't skip when equal", opcode: 0x9120, pc: 0x202, setup: set(0x1, 7, 0x2, 7)},
+		{name: "EX9E skips when the key is down", opcode: 0xE39E, pc: 0x204,
+			setup: func(vm *VM) { vm.v[3], vm.keypad[0xB] = 0xB, 1 }},
+		{name: "EX9E doesn't skip when it's up", opcode: 0xE39E, pc: 0x202, setup: set(0x3, 0xB)},
+		{name: "EX9E only looks at VX's low nibble", opcode: 0xE39E, pc: 0x204,
+			setup: func(vm *VM) { vm.v[3], vm.keypad[0xB] = 0xFB, 1 }},
+		{name: "EXA1 skips when the key is up", opcode: 0xE3A1, pc: 0x204, setup: set(0x3, 0xB)},
+		{name: "EXA1 doesn't skip when it's down", opcode: 0xE3A1, pc: 0x202,
+			setup: func(vm *VM) { vm.v[3], vm.keypad[0xB] = 0xB, 1 }},
+		{name: "XO-CHIP skips all of F000 NNNN", opcode: 0x3A42, pc: 0x206, mode: ModeXOChip,
+			setup: func(vm *VM) {
+				vm.v[0xA] = 0x42
+				vm.memory[0x202], vm.memory[0x203] = 0xF0, 0x00
+			}},
+		{name: "CHIP-8 skips F000 as one instruction", opcode: 0x3A42, pc: 0x204,
+			setup: func(vm *VM) {
+				vm.v[0xA] = 0x42
+				vm.memory[0x202], vm.memory[0x203] = 0xF0, 0x00
+			}},
+	})
+}
+
+func TestRegisterOpcodes(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "6XNN loads", opcode: 0x6A42, pc: 0x202, check: regs(0xA, 0x42)},
+		{name: "7XNN adds", opcode: 0x7A02, pc: 0x202, setup: set(0xA, 0x40), check: regs(0xA, 0x42)},
+		{name: "7XNN wraps without touching VF", opcode: 0x7AFF, pc: 0x202, setup: set(0xA, 0x02, 0xF, 0x00),
+			check: regs(0xA, 0x01, 0xF, 0x00)},
+		{name: "8XY0 copies", opcode: 0x8120, pc: 0x202, setup: set(0x2, 0x42), check: regs(0x1, 0x42)},
+		{name: "8XY1 ors", opcode: 0x8121, pc: 0x202, setup: set(0x1, 0xF0, 0x2, 0x0F, 0xF, 0x05),
+			check: regs(0x1, 0xFF, 0xF, 0x05)},
+		{name: "8XY2 ands", opcode: 0x8122, pc: 0x202, setup: set(0x1, 0xF3, 0x2, 0x3F), check: regs(0x1, 0x33)},
+		{name: "8XY3 xors", opcode: 0x8123, pc: 0x202, setup: set(0x1, 0xFF, 0x2, 0x0F), check: regs(0x1, 0xF0)},
+		{name: "8XY1 resets VF with the logic quirk", opcode: 0x8121, pc: 0x202, quirks: Quirks{LogicResetsVF: true},
+			setup: set(0x1, 0xF0, 0x2, 0x0F, 0xF, 0x05), check: regs(0x1, 0xFF, 0xF, 0x00)},
+		{name: "ANNN loads I", opcode: 0xA2EA, pc: 0x202,
+			check: func(t *testing.T, vm *VM) {
+				if vm.i != 0x2EA {
+					t.Errorf("I = 0x%03X, want 0x2EA", vm.i)
+				}
+			}},
+		{name: "CXNN masks its random number", opcode: 0xC30F, pc: 0x202,
+			check: func(t *testing.T, vm *VM) {
+				if vm.v[3]&0xF0 != 0 {
+					t.Errorf("V3 = 0x%02X, want only the low nibble set", vm.v[3])
+				}
+			}},
+		{name: "CXNN with a zero mask is zero", opcode: 0xC300, pc: 0x202, setup: set(0x3, 0xFF), check: regs(0x3, 0x00)},
+	})
+}
+
+// The arithmetic flags, with VF as VX too, where the flag is what's left in it
+func TestArithmeticFlags(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "8XY4 without a carry", opcode: 0x8124, pc: 0x202, setup: set(0x1, 0x10, 0x2, 0x20, 0xF, 0x07),
+			check: regs(0x1, 0x30, 0xF, 0x00)},
+		{name: "8XY4 with a carry", opcode: 0x8124, pc: 0x202, setup: set(0x1, 0xFF, 0x2, 0x02),
+			check: regs(0x1, 0x01, 0xF, 0x01)},
+		{name: "8XY4 at exactly 0x100", opcode: 0x8124, pc: 0x202, setup: set(0x1, 0x80, 0x2, 0x80),
+			check: regs(0x1, 0x00, 0xF, 0x01)},
+		{name: "8XY4 into VF keeps the carry", opcode: 0x8F14, pc: 0x202, setup: set(0xF, 0xFF, 0x1, 0x01),
+			check: regs(0xF, 0x01)},
+		{name: "8XY5 without a borrow", opcode: 0x8125, pc: 0x202, setup: set(0x1, 0x30, 0x2, 0x10),
+			check: regs(0x1, 0x20, 0xF, 0x01)},
+		{name: "8XY5 of equal values doesn't borrow", opcode: 0x8125, pc: 0x202, setup: set(0x1, 0x30, 0x2, 0x30),
+			check: regs(0x1, 0x00, 0xF, 0x01)},
+		{name: "8XY5 with a borrow", opcode: 0x8125, pc: 0x202, setup: set(0x1, 0x10, 0x2, 0x30),
+			check: regs(0x1, 0xE0, 0xF, 0x00)},
+		{name: "8XY5 into VF keeps the flag", opcode: 0x8F15, pc: 0x202, setup: set(0xF, 0x10, 0x1, 0x30),
+			check: regs(0xF, 0x00)},
+		{name: "8XY7 without a borrow", opcode: 0x8127, pc: 0x202, setup: set(0x1, 0x10, 0x2, 0x30),
+			check: regs(0x1, 0x20, 0xF, 0x01)},
+		{name: "8XY7 with a borrow", opcode: 0x8127, pc: 0x202, setup: set(0x1, 0x30, 0x2, 0x10),
+			check: regs(0x1, 0xE0, 0xF, 0x00)},
+		{name: "8XY7 into VF keeps the flag", opcode: 0x8F17, pc: 0x202, setup: set(0xF, 0x30, 0x1, 0x10),
+			check: regs(0xF, 0x00)},
+		{name: "8XY6 shifts VY right", opcode: 0x8126, pc: 0x202, setup: set(0x1, 0x00, 0x2, 0x05),
+			check: regs(0x1, 0x02, 0x2, 0x05, 0xF, 0x01)},
+		{name: "8XY6 shifts VX right in place with the quirk", opcode: 0x8126, pc: 0x202, quirks: Quirks{ShiftInPlace: true},
+			setup: set(0x1, 0x04, 0x2, 0x05), check: regs(0x1, 0x02, 0xF, 0x00)},
+		{name: "8XY6 into VF keeps the bit shifted out", opcode: 0x8F16, pc: 0x202, setup: set(0xF, 0x00, 0x1, 0x03),
+			check: regs(0xF, 0x01)},
+		{name: "8XYE shifts VY left", opcode: 0x812E, pc: 0x202, setup: set(0x1, 0x00, 0x2, 0x81),
+			check: regs(0x1, 0x02, 0x2, 0x81, 0xF, 0x01)},
+		{name: "8XYE flags only the top bit", opcode: 0x812E, pc: 0x202, setup: set(0x2, 0x40),
+			check: regs(0x1, 0x80, 0xF, 0x00)},
+		{name: "8XYE shifts VX left in place with the quirk", opcode: 0x812E, pc: 0x202, quirks: Quirks{ShiftInPlace: true},
+			setup: set(0x1, 0xC0, 0x2, 0x01), check: regs(0x1, 0x80, 0xF, 0x01)},
+		{name: "8XYE into VF keeps the bit shifted out", opcode: 0x8F1E, pc: 0x202, setup: set(0xF, 0x00, 0x1, 0x80),
+			check: regs(0xF, 0x01)},
+	})
+}
+
+func TestTimerAndMemoryOpcodes(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "FX07 reads the delay timer", opcode: 0xF307, pc: 0x202,
+			setup: func(vm *VM) { vm.delayTimer = 42 }, check: regs(0x3, 42)},
+		{name: "FX15 sets the delay timer", opcode: 0xF315, pc: 0x202, setup: set(0x3, 42),
+			check: func(t *testing.T, vm *VM) {
+				if vm.delayTimer != 42 {
+					t.Errorf("delay timer = %d, want 42", vm.delayTimer)
+				}
+			}},
+		{name: "FX18 sets the sound timer", opcode: 0xF318, pc: 0x202, setup: set(0x3, 42),
+			check: func(t *testing.T, vm *VM) {
+				if vm.soundTimer != 42 {
+					t.Errorf("sound timer = %d, want 42", vm.soundTimer)
+				}
+			}},
+		{name: "FX1E adds to I", opcode: 0xF31E, pc: 0x202,
+			setup: func(vm *VM) { vm.v[3], vm.i = 0x10, 0x300 },
+			check: func(t *testing.T, vm *VM) {
+				if vm.i != 0x310 {
+					t.Errorf("I = 0x%03X, want 0x310", vm.i)
+				}
+			}},
+		{name: "FX29 points I at the digit's glyph", opcode: 0xF329, pc: 0x202, setup: set(0x3, 0xA),
+			check: func(t *testing.T, vm *VM) {
+				if vm.i != 50 {
+					t.Errorf("I = %d, want 50", vm.i)
+				}
+			}},
+		{name: "FX33 stores VX's decimal digits", opcode: 0xF333, pc: 0x202,
+			setup: func(vm *VM) { vm.v[3], vm.i = 254, 0x300 },
+			check: func(t *testing.T, vm *VM) {
+				if got := vm.memory[0x300:0x303]; got[0] != 2 || got[1] != 5 || got[2] != 4 {
+					t.Errorf("memory = %v, want [2 5 4]", got)
+				}
+			}},
+		{name: "FX55 stores V0 to VX", opcode: 0xF255, pc: 0x202,
+			setup: func(vm *VM) { set(0x0, 1, 0x1, 2, 0x2, 3, 0x3, 4)(vm); vm.i = 0x300 },
+			check: func(t *testing.T, vm *VM) {
+				if got := vm.memory[0x300:0x304]; got[0] != 1 || got[1] != 2 || got[2] != 3 || got[3] != 0 {
+					t.Errorf("memory = %v, want [1 2 3 0]", got)
+				}
+				if vm.i != 0x300 {
+					t.Errorf("I = 0x%03X, want it left at 0x300", vm.i)
+				}
+			}},
+		{name: "FX55 moves I past them with the load/store quirk", opcode: 0xF255, pc: 0x202,
+			quirks: Quirks{LoadStoreIncrementsI: true},
+			setup:  func(vm *VM) { vm.i = 0x300 },
+			check: func(t *testing.T, vm *VM) {
+				if vm.i != 0x303 {
+					t.Errorf("I = 0x%03X, want 0x303", vm.i)
+				}
+			}},
+		{name: "FX65 loads V0 to VX", opcode: 0xF265, pc: 0x202,
+			setup: func(vm *VM) {
+				copy(vm.memory[0x300:], []byte{7, 8, 9, 10})
+				vm.i = 0x300
+			},
+			check: regs(0x0, 7, 0x1, 8, 0x2, 9, 0x3, 0)},
+	})
+}
+
+func TestFX33Digits(t *testing.T) {
+	tests := []struct {
+		v    byte
+		want [3]byte
+	}{
+		{0, [3]byte{0, 0, 0}},
+		{9, [3]byte{0, 0, 9}},
+		{10, [3]byte{0, 1, 0}},
+		{99, [3]byte{0, 9, 9}},
+		{100, [3]byte{1, 0, 0}},
+		{101, [3]byte{1, 0, 1}},
+		{190, [3]byte{1, 9, 0}},
+		{255, [3]byte{2, 5, 5}},
+	}
+	for _, tt := range tests {
+		vm := newTestVM(t, Config{})
+		vm.v[0x3], vm.i = tt.v, 0x300
+		copy(vm.memory[0x300:], []byte{0xAA, 0xAA, 0xAA, 0xAA})
+		if err := vm.exec(0xF333); err != nil {
+			t.Fatal(err)
+		}
+		if got := [3]byte(vm.memory[0x300:0x303]); got != tt.want {
+			t.Errorf("FX33 of %d stored %v, want %v", tt.v, got, tt.want)
+		}
+		if vm.memory[0x303] != 0xAA || vm.i != 0x300 {
+			t.Errorf("FX33 of %d wrote past its 3 bytes or moved I to 0x%03X", tt.v, vm.i)
+		}
+	}
+}
+
+func TestSuperChipOpcodes(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "00FF switches to hires", opcode: 0x00FF, pc: 0x202, mode: ModeSChip,
+			check: func(t *testing.T, vm *VM) {
+				if cols, rows := vm.resolution(); !vm.hires || cols != 128 || rows != 64 {
+					t.Errorf("hires = %v at %dx%d, want 128x64", vm.hires, cols, rows)
+				}
+			}},
+		{name: "FX30 points I at the large glyph", opcode: 0xF330, pc: 0x202, mode: ModeSChip, setup: set(0x3, 2),
+			check: func(t *testing.T, vm *VM) {
+				if vm.i != largeFontAddr+20 {
+					t.Errorf("I = 0x%03X, want 0x%03X", vm.i, largeFontAddr+20)
+				}
+			}},
+		{name: "FX75 and FX85 round trip through the flags", opcode: 0xF275, pc: 0x202, mode: ModeSChip,
+			setup: set(0x0, 1, 0x1, 2, 0x2, 3),
+			check: func(t *testing.T, vm *VM) {
+				vm.v = [16]byte{}
+				if err := vm.exec(0xF285); err != nil {
+					t.Fatal(err)
+				}
+				regs(0x0, 1, 0x1, 2, 0x2, 3)(t, vm)
+			}},
+	})
+}
+
+func TestXOChipOpcodes(t *testing.T) {
+	runOpcodeTests(t, []opcodeTest{
+		{name: "F000 NNNN loads I with the following word", opcode: 0xF000, pc: 0x204, mode: ModeXOChip,
+			setup: func(vm *VM) { vm.memory[0x202], vm.memory[0x203] = 0xAB, 0xCD },
+			check: func(t *testing.T, vm *VM) {
+				if vm.i != 0xABCD {
+					t.Errorf("I = 0x%04X, want 0xABCD", vm.i)
+				}
+			}},
+	})
+}
+
+func TestDrawCollision(t *testing.T) {
+	vm := newTestVM(t, Config{})
+	vm.i = 0 // The 0 glyph, whose top row is 0xF0
+	vm.v[0], vm.v[1] = 0, 0
+
+	if err := vm.exec(0xD015); err != nil {
+		t.Fatal(err)
+	}
+	if vm.v[0xF] != 0 {
+		t.Errorf("VF = %d after drawing on a blank screen, want 0", vm.v[0xF])
+	}
+	if !vm.drawFlag || vm.gfx[0] != 1 || vm.gfx[4] != 0 {
+		t.Errorf("drawFlag = %v, top row = %v, want the glyph's top row drawn", vm.drawFlag, vm.gfx[:8])
+	}
+
+	if err := vm.exec(0xD015); err != nil {
+		t.Fatal(err)
+	}
+	if vm.v[0xF] != 1 {
+		t.Errorf("VF = %d after drawing over the same sprite, want 1", vm.v[0xF])
+	}
+	if vm.gfx != [128 * 64]byte{} {
+		t.Error("drawing the same sprite twice didn't erase it")
+	}
+	if vm.pc != 0x204 {
+		t.Errorf("pc = 0x%03X, want 0x204", vm.pc)
+	}
+}
+
+// recordingDisplay keeps the pixels the last frame tinted as collided
+type recordingDisplay struct {
+	headlessDisplay
+	collided []uint16
+}
+
+func (d *recordingDisplay) DrawGraphics(gfx []byte, cols int, collided, drawn []uint16) {
+	d.collided = append([]uint16(nil), collided...)
+}
+
+func TestCollisionHighlights(t *testing.T) {
+	for _, highlight := range []bool{false, true} {
+		vm := newTestVM(t, Config{HighlightCollisions: highlight})
+		d := &recordingDisplay{}
+		vm.window = d
+
+		// The 0 glyph's top row at 0,0, then again at 2,0 so they overlap at pixels 2 and 3
+		vm.i = 0
+		if err := vm.exec(0xD011); err != nil {
+			t.Fatal(err)
+		}
+		vm.v[0] = 2
+		if err := vm.exec(0xD011); err != nil {
+			t.Fatal(err)
+		}
+
+		var want []uint16
+		if highlight {
+			want = []uint16{2, 3}
+		}
+		if !slices.Equal(vm.collided, want) {
+			t.Errorf("highlight %v: collided = %v, want %v", highlight, vm.collided, want)
+		}
+
+		vm.presentFrame()
+		if !slices.Equal(d.collided, want) {
+			t.Errorf("highlight %v: frame tinted %v, want %v", highlight, d.collided, want)
+		}
+		if len(vm.collided) != 0 {
+			t.Errorf("highlight %v: collided = %v after the frame, want it cleared", highlight, vm.collided)
+		}
+	}
+}
+
+// eventCounter counts the events logged, by name
+type eventCounter map[string]int
+
+func (c eventCounter) Log(event, msg string, fields Fields) { c[event]++ }
+
+func TestBreakOnCollision(t *testing.T) {
+	// Draw the same sprite over and over, colliding every other time
+	events := eventCounter{}
+	vm := newTestVM(t, Config{BreakOnCollision: true, Logger: events}, 0xD015, 0x1200)
+	vm.headless = false
+
+	for range 4 {
+		vm.clockCycle()
+	}
+	if !vm.halted || vm.pc != 0x202 {
+		t.Fatalf("halted = %v at 0x%03X, want a halt after the colliding draw at 0x200", vm.halted, vm.pc)
+	}
+
+	// Resumed, the ROM carries on colliding without halting again
+	vm.halted = false
+	for range 20 {
+		vm.clockCycle()
+	}
+	if vm.halted || events["halted"] != 1 {
+		t.Errorf("halted %d times, want once", events["halted"])
+	}
+	if draws := vm.stats.Draws.Load(); draws < 10 {
+		t.Errorf("drew %d sprites after resuming, want the ROM to keep running", draws)
+	}
+}
+
+func TestDrawClipsAndWraps(t *testing.T) {
+	// An 8 pixel wide row drawn 2 pixels from the right edge
+	tests := []struct {
+		name    string
+		quirks  Quirks
+		lit     int
+		wrapped bool
+	}{
+		{"clipped at the right edge", Quirks{}, 2, false},
+		{"wrapped with the wrap quirk", Quirks{WrapSprites: true}, 8, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newTestVM(t, Config{Quirks: tt.quirks})
+			vm.i = 0x300
+			vm.memory[0x300] = 0xFF
+			vm.v[0] = 62
+			if err := vm.exec(0xD011); err != nil {
+				t.Fatal(err)
+			}
+			lit := 0
+			for _, p := range vm.gfx[:64] {
+				lit += int(p)
+			}
+			if lit != tt.lit {
+				t.Errorf("%d pixels lit, want %d", lit, tt.lit)
+			}
+			if wrapped := vm.gfx[0] == 1; wrapped != tt.wrapped {
+				t.Errorf("row start lit = %v, want %v", wrapped, tt.wrapped)
+			}
+		})
+	}
+}
+
+func TestDrawAtTheEdges(t *testing.T) {
+	// A sprite's rows read from the end of memory wrap around to its start instead of panicking
+	vm := newTestVM(t, Config{})
+	vm.i = 0xFFE
+	vm.memory[0xFFE], vm.memory[0xFFF] = 0x80, 0x80
+	if err := vm.exec(0xD00F); err != nil {
+		t.Fatal(err)
+	}
+	if vm.gfx[0] != 1 || vm.gfx[64] != 1 || vm.gfx[2*64] != 1 {
+		t.Error("sprite rows at the end of memory weren't drawn, or didn't carry on from the start of it")
+	}
+
+	// Rows past the bottom edge are clipped, or wrapped to the top with the wrap quirk
+	for _, wrap := range []bool{false, true} {
+		vm := newTestVM(t, Config{Quirks: Quirks{WrapSprites: wrap}})
+		vm.i = 0x300
+		copy(vm.memory[0x300:], []byte{0x80, 0x80, 0x80, 0x80})
+		vm.v[1] = 30
+		if err := vm.exec(0xD014); err != nil {
+			t.Fatal(err)
+		}
+		if vm.gfx[30*64] != 1 || vm.gfx[31*64] != 1 {
+			t.Errorf("wrap %v: rows above the bottom edge weren't drawn", wrap)
+		}
+		if top := vm.gfx[0] == 1 && vm.gfx[64] == 1; top != wrap {
+			t.Errorf("wrap %v: rows drawn at the top = %v", wrap, top)
+		}
+	}
+}
+
+// A 2x2 sprite at the bottom right corner, placed there by coordinates that wrap onto it
+func TestDrawAtTheCorner(t *testing.T) {
+	tests := []struct {
+		name   string
+		quirks Quirks
+		want   []int
+	}{
+		{"clipped", Quirks{}, []int{63 + 31*64}},
+		{"wrapped horizontally", Quirks{WrapSpritesX: true}, []int{31 * 64, 63 + 31*64}},
+		{"wrapped", Quirks{WrapSprites: true}, []int{0, 63, 31 * 64, 63 + 31*64}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newTestVM(t, Config{Quirks: tt.quirks})
+			vm.i = 0x300
+			vm.memory[0x300], vm.memory[0x301] = 0xC0, 0xC0
+			vm.v[0], vm.v[1] = 127, 63
+			if err := vm.exec(0xD012); err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for i, px := range vm.gfx {
+				if px == 1 {
+					got = append(got, i)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pixels drawn at %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFX0AWaitsForARelease(t *testing.T) {
+	vm := newTestVM(t, Config{})
+	for range 3 {
+		if err := vm.exec(0xF30A); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vm.pc != 0x200 {
+		t.Fatalf("pc = 0x%03X while waiting, want 0x200", vm.pc)
+	}
+
+	vm.pressKey(0x7)
+	if err := vm.exec(0xF30A); err != nil {
+		t.Fatal(err)
+	}
+	if vm.pc != 0x200 {
+		t.Fatalf("pc = 0x%03X with the key still down, want 0x200", vm.pc)
+	}
+
+	vm.releaseKey(0x7)
+	if err := vm.exec(0xF30A); err != nil {
+		t.Fatal(err)
+	}
+	if vm.pc != 0x202 || vm.v[3] != 0x7 {
+		t.Errorf("pc = 0x%03X, V3 = %d, want 0x202 and 7", vm.pc, vm.v[3])
+	}
+}
+
+// FX0A driven headlessly by played back input, the way a replay or test harness presses keys
+func TestFX0AWithPlayedBackKeys(t *testing.T) {
+	vm := newTestVM(t, Config{}, 0xF30A, 0x1202)
+
+	vm.RunReplay(&Replay{Cycles: 15, Events: []InputEvent{{Cycle: 10, Key: 0x7, Down: true}}})
+	if vm.pc != 0x200 {
+		t.Fatalf("pc = 0x%03X with the key held, want FX0A still waiting at 0x200", vm.pc)
+	}
+
+	vm.RunReplay(&Replay{Cycles: 30, Events: []InputEvent{{Cycle: 20, Key: 0x7}}})
+	if vm.pc != 0x202 || vm.v[3] != 0x7 {
+		t.Errorf("pc = 0x%03X, V3 = %d, want 0x202 and 7 once the key came up", vm.pc, vm.v[3])
+	}
+}
+
+func TestStackFaults(t *testing.T) {
+	vm := newTestVM(t, Config{StackDepth: 2})
+	vm.push(0x300)
+	vm.push(0x400)
+	if err := vm.exec(0x2500); !errors.Is(err, errStackOverflow) {
+		t.Errorf("2NNN on a full stack = %v, want a stack overflow", err)
+	}
+
+	vm = newTestVM(t, Config{})
+	if err := vm.exec(0x00EE); !errors.Is(err, errStackUnderflow) {
+		t.Errorf("00EE on an empty stack = %v, want a stack underflow", err)
+	}
+}
+
+// A halted stack fault skips the faulting call, so resuming doesn't fault on it again
+func TestStackHaltSkipsTheFault(t *testing.T) {
+	vm := newTestVM(t, Config{StackDepth: 1}, 0x2202, 0x2204)
+	vm.headless = false
+	vm.emulateCycle()
+	vm.emulateCycle()
+	if !vm.halted {
+		t.Fatal("a call on a full stack didn't halt the VM")
+	}
+	if vm.pc != 0x204 {
+		t.Errorf("pc = 0x%03X after the fault, want 0x204", vm.pc)
+	}
+}
+
+func TestUnknownOpcodes(t *testing.T) {
+	tests := []struct {
+		opcode uint16
+		mode   Mode
+	}{
+		{0x0000, ModeChip8},
+		{0x00FF, ModeChip8},
+		{0x00C1, ModeChip8},
+		{0x812F, ModeChip8},
+		{0xE3FF, ModeChip8},
+		{0xF330, ModeChip8},
+		{0xF000, ModeChip8},
+		{0xF000, ModeSChip},
+		{0xF100, ModeXOChip},
+		{0xF33A, ModeSChip},
+	}
+	for _, tt := range tests {
+		vm := newTestVM(t, Config{Mode: tt.mode})
+		if err := vm.exec(tt.opcode); err == nil {
+			t.Errorf("%04X in %s didn't fail", tt.opcode, tt.mode)
+		}
+	}
+}
+
+func TestHighMemory(t *testing.T) {
+	vm := newTestVM(t, Config{MemorySize: 0x10000})
+	if len(vm.memory) != 0x10000 {
+		t.Fatalf("memory is %d bytes, want 64K", len(vm.memory))
+	}
+
+	set(0x0, 1, 0x1, 2, 0x2, 3)(vm)
+	vm.i = 0xFF00
+	if err := vm.exec(0xF255); err != nil {
+		t.Fatal(err)
+	}
+	if got := vm.memory[0xFF00:0xFF03]; got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("memory at 0xFF00 = %v, want [1 2 3]", got)
+	}
+
+	vm.v = [16]byte{}
+	if err := vm.exec(0xF265); err != nil {
+		t.Fatal(err)
+	}
+	regs(0x0, 1, 0x1, 2, 0x2, 3)(t, vm)
+
+	// A sprite at the very top of memory runs off the end without faulting
+	vm.v, vm.i = [16]byte{}, 0xFFFE
+	vm.memory[0xFFFE], vm.memory[0xFFFF] = 0x80, 0x80
+	if err := vm.exec(0xD004); err != nil {
+		t.Fatal(err)
+	}
+	if vm.gfx[0] != 1 || vm.gfx[64] != 1 {
+		t.Error("sprite rows from the top of memory weren't drawn")
+	}
+}
+
+func TestMemorySize(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		size int
+		want int
+	}{
+		{ModeChip8, 0, 0x1000},
+		{ModeSChip, 0, 0x1000},
+		{ModeXOChip, 0, 0x10000},
+		{ModeChip8, 0x8000, 0x8000},
+	}
+	for _, tt := range tests {
+		vm := newTestVM(t, Config{Mode: tt.mode, MemorySize: tt.size})
+		if len(vm.memory) != tt.want {
+			t.Errorf("%s with memory size %d has %d bytes, want %d", tt.mode, tt.size, len(vm.memory), tt.want)
+		}
+	}
+
+	for _, size := range []int{0x800, 0x10001} {
+		if _, err := NewVM(bytes.NewReader([]byte{0x12, 0x00}), DefaultClockSpeed, Config{Headless: true, MemorySize: size}); err == nil {
+			t.Errorf("memory size %d didn't fail", size)
+		}
+	}
+}
+
+func TestNestedCalls(t *testing.T) {
+	// A subroutine at 0x200 that calls itself, 16 deep
+	vm := newTestVM(t, Config{})
+	for depth := 1; depth <= 16; depth++ {
+		vm.pc = 0x200
+		if err := vm.exec(0x2200); err != nil {
+			t.Fatalf("call %d: %v", depth, err)
+		}
+		if int(vm.sp) != depth {
+			t.Fatalf("sp = %d after %d calls", vm.sp, depth)
+		}
+	}
+	// Calls save their own address, and 00EE returns past it
+	if vm.stack[0] != 0x200 {
+		t.Errorf("stack[0] = 0x%03X, want the first call's address 0x200", vm.stack[0])
+	}
+	if err := vm.exec(0x2200); !errors.Is(err, errStackOverflow) {
+		t.Errorf("a 17th call = %v, want a stack overflow", err)
+	}
+
+	for depth := 15; depth >= 0; depth-- {
+		if err := vm.exec(0x00EE); err != nil {
+			t.Fatalf("return to depth %d: %v", depth, err)
+		}
+		if int(vm.sp) != depth || vm.pc != 0x202 {
+			t.Fatalf("sp = %d, pc = 0x%03X returning to depth %d, want 0x202", vm.sp, vm.pc, depth)
+		}
+	}
+	if err := vm.exec(0x00EE); !errors.Is(err, errStackUnderflow) {
+		t.Errorf("a 17th return = %v, want a stack underflow", err)
+	}
+}
@@ -0,0 +1,62 @@
+package chip8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Logger reports the events in a VM's life, like it being created, paused, reset, or shut down. event names
+// what happened, msg is the line shown to people, and fields are the details harnesses need. Events with no
+// message are only of interest to machines.
+type Logger interface {
+	Log(event, msg string, fields Fields)
+}
+
+// Fields are the details of a logged event, by name
+type Fields map[string]any
+
+// TextLogger prints the message of each event to W on a line of its own, the way chippy always has
+type TextLogger struct {
+	W io.Writer
+}
+
+// Log prints msg, and nothing for events without one
+func (l TextLogger) Log(event, msg string, fields Fields) {
+	if msg != "" {
+		fmt.Fprintln(l.W, msg)
+	}
+}
+
+// JSONLogger writes every event to a writer as a JSON line holding the time, the event's name, its
+// message if it has one, and its fields, e.g.
+//
+//	{"cycles":5120,"event":"shutdown","msg":"Received signal - gracefully shutting down...","time":"..."}
+type JSONLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLogger returns a JSONLogger writing to w
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{enc: json.NewEncoder(w)}
+}
+
+// Log writes the event as a JSON line. A field named time, event, or msg is overwritten by the event's own.
+func (l *JSONLogger) Log(event, msg string, fields Fields) {
+	line := make(Fields, len(fields)+3)
+	for k, v := range fields {
+		line[k] = v
+	}
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["event"] = event
+	if msg != "" {
+		line["msg"] = msg
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enc.Encode(line)
+}
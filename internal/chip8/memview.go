@@ -0,0 +1,28 @@
+package chip8
+
+import "time"
+
+// MemoryView is a window showing the VM's memory live. pixel.MemoryWindow is the real thing.
+type MemoryView interface {
+	// DrawMemory draws memory with the program counter, the index register, and the calls on the stack picked out
+	DrawMemory(memory []byte, pc, i uint16, stack []uint16)
+
+	// Closed reports whether the user closed the view, and Destroy gets rid of it
+	Closed() bool
+	Destroy()
+}
+
+// updateMemoryView redraws the memory view at most once a frame. Closing the view doesn't stop the VM,
+// it just isn't drawn anymore.
+func (vm *VM) updateMemoryView() {
+	if vm.memView == nil || time.Since(vm.lastMemView) < frameInterval {
+		return
+	}
+	if vm.memView.Closed() {
+		vm.memView.Destroy()
+		vm.memView = nil
+		return
+	}
+	vm.memView.DrawMemory(vm.memory, vm.pc, vm.i, vm.stack[:vm.sp])
+	vm.lastMemView = time.Now()
+}
@@ -0,0 +1,47 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode selects which CHIP-8 dialect the VM interprets. Extended modes only add opcodes, so
+// standard ROMs behave the same in any of them.
+type Mode int
+
+const (
+	// ModeChip8 is the original COSMAC VIP instruction set
+	ModeChip8 Mode = iota
+
+	// ModeSChip adds the SUPER-CHIP 1.1 instructions
+	ModeSChip
+
+	// ModeXOChip adds the XO-CHIP instructions on top of SUPER-CHIP
+	ModeXOChip
+)
+
+// Modes lists every mode chippy supports
+var Modes = []Mode{ModeChip8, ModeSChip, ModeXOChip}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeChip8:
+		return "chip8"
+	case ModeSChip:
+		return "schip"
+	case ModeXOChip:
+		return "xochip"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ParseMode returns the Mode with the given name, as printed by Mode.String
+func ParseMode(name string) (Mode, error) {
+	for _, m := range Modes {
+		if strings.EqualFold(name, m.String()) {
+			return m, nil
+		}
+	}
+	return ModeChip8, fmt.Errorf("unknown mode %q, expected one of chip8, schip, xochip", name)
+}
@@ -0,0 +1,59 @@
+package chip8
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+const (
+	// speedInterval is how often the speed overlay's counters are refreshed
+	speedInterval = time.Second
+
+	// speedPending is shown in the overlay until there's a full interval to count
+	speedPending = "-- fps  -- ips"
+)
+
+// speedOverlay is the frames and instructions per second shown in the corner of the window with
+// --overlay or F3. The rates are worked out from Stats, counted since the overlay was last refreshed.
+type speedOverlay struct {
+	shown        bool
+	since        time.Time
+	frames       uint64
+	instructions uint64
+}
+
+// updateSpeedOverlay shows or hides the overlay when F3 is pressed, and refreshes it once a speedInterval
+func (vm *VM) updateSpeedOverlay() {
+	o := &vm.overlay
+	if vm.window.Hotkeys().Has(hotkey.Speed) {
+		o.shown = !o.shown
+		if o.shown {
+			vm.restartSpeedOverlay(speedPending)
+		} else {
+			vm.window.ShowSpeed("")
+			vm.frameDirty = true
+		}
+		return
+	}
+	if !o.shown {
+		return
+	}
+	elapsed := time.Since(o.since)
+	if elapsed < speedInterval {
+		return
+	}
+	fps := float64(vm.stats.Frames.Load()-o.frames) / elapsed.Seconds()
+	ips := float64(vm.stats.Instructions.Load()-o.instructions) / elapsed.Seconds()
+	vm.restartSpeedOverlay(fmt.Sprintf("%.0f fps  %.0f ips", fps, ips))
+}
+
+// restartSpeedOverlay shows text in the overlay and starts counting towards its next refresh
+func (vm *VM) restartSpeedOverlay(text string) {
+	o := &vm.overlay
+	o.since = time.Now()
+	o.frames, o.instructions = vm.stats.Frames.Load(), vm.stats.Instructions.Load()
+	vm.window.ShowSpeed(text)
+	vm.frameDirty = true
+}
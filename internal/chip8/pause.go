@@ -0,0 +1,25 @@
+package chip8
+
+import "github.com/bradford-hamilton/chippy/internal/hotkey"
+
+// pause freezes the VM until the pause key is pressed again. Nothing executes and the timers
+// stand still, so the sound stops and picks back up where it was on resume.
+func (vm *VM) pause() {
+	vm.paused = true
+	vm.syncSoundState()
+	vm.log.Log("paused", "paused, press P to resume", Fields{"cycles": vm.cycles})
+}
+
+// whilePaused keeps the window responsive while the VM is paused, resuming when the pause key is pressed
+func (vm *VM) whilePaused() {
+	if vm.frameDirty {
+		vm.presentFrame()
+	} else {
+		vm.window.UpdateInput()
+	}
+	if vm.window.Hotkeys().Has(hotkey.Pause) {
+		vm.paused = false
+		vm.syncSoundState()
+		vm.log.Log("resumed", "", Fields{"cycles": vm.cycles})
+	}
+}
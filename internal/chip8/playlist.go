@@ -0,0 +1,82 @@
+package chip8
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+)
+
+// PlaylistROM is a ROM for a playlist to run next, along with the window title and quick save path that go with it
+type PlaylistROM struct {
+	ROM       []byte
+	Title     string
+	StatePath string
+}
+
+// advance moves on to the playlist's next ROM once the current one is done with: its window closed or it
+// exited with 00FD. It reports false when there's nothing to move on to, or the VM stopped on an error.
+func (vm *VM) advance() bool {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.err != nil {
+		return false
+	}
+	return vm.nextROM()
+}
+
+// nextROM runs the playlist's next ROM in place of the current one, in the same window, reporting false if
+// there isn't one. ROMs that can't be loaded are logged and skipped.
+func (vm *VM) nextROM() bool {
+	if vm.next == nil {
+		return false
+	}
+	for {
+		next, ok, err := vm.next()
+		if !ok {
+			return false
+		}
+		if err == nil {
+			err = vm.switchROM(next)
+		}
+		if err != nil {
+			vm.log.Log("error", fmt.Sprintf("error loading rom: %v", err), Fields{"error": err.Error()})
+			continue
+		}
+		return true
+	}
+}
+
+// switchROM loads next and restarts the VM on it, as Reset would. The unknown opcodes counted for the
+// ROM being left are logged first, and its rewind snapshots dropped.
+func (vm *VM) switchROM(next PlaylistROM) error {
+	if len(next.ROM) > vm.maxROMSize() {
+		return fmt.Errorf("rom too large: %d bytes, max is %d", len(next.ROM), vm.maxROMSize())
+	}
+	vm.logUnknownOps()
+	vm.unknownOps = nil
+
+	vm.rom = bytes.Clone(next.ROM)
+	vm.restart()
+	vm.exited = false
+	vm.statePath = next.StatePath
+	if vm.rewind != nil {
+		vm.rewind.count = 0
+	}
+	vm.window.SetTitle(next.Title)
+	vm.window.SetClosed(false)
+	vm.log.Log("rom_loaded", "", Fields{"size": len(vm.rom), "sha1": romdb.Hash(vm.rom), "start": vm.startAddr})
+	return nil
+}
+
+// handleNextKey moves on to the playlist's next ROM when F2 is pressed, or says there isn't one
+func (vm *VM) handleNextKey(keys hotkey.Actions) {
+	if vm.next == nil || !keys.Has(hotkey.Next) {
+		return
+	}
+	if !vm.nextROM() {
+		vm.window.ShowOverlay("last ROM in the playlist", turboMessageDuration)
+		vm.frameDirty = true
+	}
+}
@@ -0,0 +1,74 @@
+package chip8
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RegPreset sets a V register before the first cycle
+type RegPreset struct {
+	Reg   byte
+	Value byte
+}
+
+// MemPreset sets a byte of memory before the first cycle
+type MemPreset struct {
+	Addr  uint16
+	Value byte
+}
+
+// ParseRegPreset parses a register preset written as "V5=0x0A"
+func ParseRegPreset(s string) (RegPreset, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || len(name) != 2 || (name[0] != 'V' && name[0] != 'v') {
+		return RegPreset{}, fmt.Errorf("invalid register preset %q, expected e.g. V5=0x0A", s)
+	}
+	reg, err := strconv.ParseUint(name[1:], 16, 4)
+	if err != nil {
+		return RegPreset{}, fmt.Errorf("invalid register %q in preset %q, expected V0 to VF", name, s)
+	}
+	v, err := strconv.ParseUint(value, 0, 8)
+	if err != nil {
+		return RegPreset{}, fmt.Errorf("invalid value %q in preset %q, expected a byte", value, s)
+	}
+	return RegPreset{Reg: byte(reg), Value: byte(v)}, nil
+}
+
+// ParseMemPreset parses a memory preset written as "0x300=0xFF". Whether the address
+// fits in memory is checked when the preset is applied.
+func ParseMemPreset(s string) (MemPreset, error) {
+	addr, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return MemPreset{}, fmt.Errorf("invalid memory preset %q, expected e.g. 0x300=0xFF", s)
+	}
+	a, err := strconv.ParseUint(addr, 0, 16)
+	if err != nil {
+		return MemPreset{}, fmt.Errorf("invalid address %q in preset %q", addr, s)
+	}
+	v, err := strconv.ParseUint(value, 0, 8)
+	if err != nil {
+		return MemPreset{}, fmt.Errorf("invalid value %q in preset %q, expected a byte", value, s)
+	}
+	return MemPreset{Addr: uint16(a), Value: byte(v)}, nil
+}
+
+// checkMemPresets makes sure every memory preset lands inside a VM with memorySize bytes of memory
+func checkMemPresets(mem []MemPreset, memorySize int) error {
+	for _, p := range mem {
+		if int(p.Addr) >= memorySize {
+			return fmt.Errorf("memory preset address 0x%X is outside the VM's %d bytes of memory", p.Addr, memorySize)
+		}
+	}
+	return nil
+}
+
+// applyPresets writes the presets over the freshly loaded machine
+func (vm *VM) applyPresets(regs []RegPreset, mem []MemPreset) {
+	for _, p := range mem {
+		vm.memory[p.Addr] = p.Value
+	}
+	for _, p := range regs {
+		vm.v[p.Reg&0x0F] = p.Value
+	}
+}
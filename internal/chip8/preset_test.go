@@ -0,0 +1,52 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParsePresets(t *testing.T) {
+	if p, err := ParseRegPreset("V5=0x0A"); err != nil || p != (RegPreset{Reg: 5, Value: 0x0A}) {
+		t.Errorf("ParseRegPreset(V5=0x0A) = %+v, %v", p, err)
+	}
+	if p, err := ParseRegPreset("vf=255"); err != nil || p != (RegPreset{Reg: 0xF, Value: 255}) {
+		t.Errorf("ParseRegPreset(vf=255) = %+v, %v", p, err)
+	}
+	if p, err := ParseMemPreset("0x300=0xFF"); err != nil || p != (MemPreset{Addr: 0x300, Value: 0xFF}) {
+		t.Errorf("ParseMemPreset(0x300=0xFF) = %+v, %v", p, err)
+	}
+	for _, s := range []string{"V5", "VG=1", "V10=1", "I=1", "V5=0x100", "V5=-1"} {
+		if _, err := ParseRegPreset(s); err == nil {
+			t.Errorf("ParseRegPreset(%q) didn't fail", s)
+		}
+	}
+	for _, s := range []string{"0x300", "0x10000=1", "0x300=0x100", "x=1"} {
+		if _, err := ParseMemPreset(s); err == nil {
+			t.Errorf("ParseMemPreset(%q) didn't fail", s)
+		}
+	}
+}
+
+func TestPresetsApplyBeforeTheFirstCycle(t *testing.T) {
+	// The ROM only jumps to itself, until the memory presets replace its first instruction with 7501
+	vm := newTestVM(t, Config{
+		RegPresets: []RegPreset{{Reg: 5, Value: 0x0A}},
+		MemPresets: []MemPreset{{Addr: 0x200, Value: 0x75}, {Addr: 0x201, Value: 0x01}, {Addr: 0x300, Value: 0xFF}},
+	}, 0x1200)
+
+	vm.clockCycle()
+	if vm.v[5] != 0x0B {
+		t.Errorf("V5 = 0x%02X after adding 1, want 0x0B", vm.v[5])
+	}
+	if vm.memory[0x300] != 0xFF {
+		t.Errorf("memory at 0x300 = 0x%02X, want 0xFF", vm.memory[0x300])
+	}
+
+	_, err := NewVM(bytes.NewReader([]byte{0x12, 0x00}), DefaultClockSpeed, Config{
+		Headless:   true,
+		MemPresets: []MemPreset{{Addr: 0x1000, Value: 1}},
+	})
+	if err == nil {
+		t.Error("a memory preset outside 4K of memory didn't fail")
+	}
+}
@@ -0,0 +1,69 @@
+package chip8
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Quirks toggles behaviors that differ between CHIP-8 interpreters. ROMs are often written against
+// one interpreter's behavior, so a ROM that misbehaves may just need a different set. The zero
+// value is chippy's default behavior.
+type Quirks struct {
+	// TimersPerInstruction decrements the delay and sound timers once per instruction instead
+	// of at 60Hz, like the interpreters some ROMs were tuned on
+	TimersPerInstruction bool
+
+	// ShiftInPlace makes 8XY6 and 8XYE shift VX itself, ignoring VY, like CHIP-48 and SUPER-CHIP.
+	// Without it they shift VY into VX like the COSMAC VIP. It's named for the behavior it turns on,
+	// rather than as ShiftUsesVY, so the zero value keeps the VIP's like every other quirk. Most
+	// CHIP-48 and SUPER-CHIP games need it, Space Invaders and Blinky among them, while VIP games
+	// and the test ROMs checking for VIP accuracy expect it off.
+	ShiftInPlace bool
+
+	// LoadStoreIncrementsI leaves the index register pointing past the registers FX55 and FX65
+	// saved or loaded, i+x+1, like the COSMAC VIP. Without it they leave i alone like SUPER-CHIP.
+	LoadStoreIncrementsI bool
+
+	// JumpUsesVX makes BNNN jump to XNN plus VX, X being the address's top nibble, like CHIP-48 and
+	// SUPER-CHIP's BXNN. Without it BNNN jumps to NNN plus V0 like the COSMAC VIP.
+	JumpUsesVX bool
+
+	// WrapSprites draws the part of a sprite that runs off one edge of the screen in from the opposite
+	// edge, like a few later interpreters. Without it the part past the edge is clipped like the COSMAC VIP.
+	WrapSprites bool
+
+	// WrapSpritesX wraps just the columns of a sprite that run off the right edge around to the left edge,
+	// still clipping the rows that run off the bottom. WrapSprites wraps both.
+	WrapSpritesX bool
+
+	// LogicResetsVF clears VF after 8XY1, 8XY2, and 8XY3, a side effect of how the COSMAC VIP's
+	// interpreter ran them. Without it they leave VF alone like CHIP-48 and SUPER-CHIP.
+	LogicResetsVF bool
+
+	// DisplayWait holds DXYN back until the next 60Hz vertical blank before it draws, like the COSMAC VIP,
+	// which drew sprites in step with its display interrupt. ROMs written for it draw a sprite at most once a
+	// frame and can tear or flicker when draws land mid-frame. Without it sprites are drawn at once like SUPER-CHIP.
+	DisplayWait bool
+}
+
+// Names lists the names of the Quirks fields turned on in q, in the order they're declared
+func (q Quirks) Names() []string {
+	var names []string
+	v := reflect.ValueOf(q)
+	for i := range v.NumField() {
+		if v.Field(i).Bool() {
+			names = append(names, v.Type().Field(i).Name)
+		}
+	}
+	return names
+}
+
+// setQuirk turns on the quirk with the given field name, as listed by Names
+func setQuirk(q *Quirks, name string) error {
+	f := reflect.ValueOf(q).Elem().FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.Bool {
+		return fmt.Errorf("unknown quirk %q", name)
+	}
+	f.SetBool(true)
+	return nil
+}
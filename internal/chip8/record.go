@@ -0,0 +1,102 @@
+package chip8
+
+import (
+	"fmt"
+	"image/gif"
+	"os"
+	"slices"
+)
+
+const (
+	// The frame rate recordings are captured at unless configured otherwise
+	defaultRecordFPS = 25
+
+	// Recordings stop capturing after this many distinct frames, a few minutes of a typical game, so a
+	// long session can't use up memory. Frames are kept as raw screens until the GIF is written.
+	maxRecordFrames = 4000
+
+	// How many GIF pixels wide a pixel of the 64x32 screen is. 128x64 screens are drawn at half this.
+	recordScale = 8
+)
+
+// recorder captures the screen at a fixed rate of clock cycles while the VM runs, and writes the capture
+// out as an animated GIF when it stops. A frame the same as the one before it only lengthens that frame.
+type recorder struct {
+	path string
+
+	// Clock cycles between captures, and the cycle of the last capture
+	every uint64
+	last  uint64
+
+	frames [][]byte
+	cols   []int
+
+	// How long each frame is shown for, in clock cycles
+	cycles []uint64
+
+	full bool
+}
+
+func newRecorder(path string, fps, clockSpeed int) *recorder {
+	return &recorder{path: path, every: uint64(max(1, clockSpeed/fps))}
+}
+
+// capture adds the screen to the recording if it's time for the next frame
+func (vm *VM) capture() {
+	r := vm.recorder
+	if r == nil || r.full || vm.cycles-r.last < r.every {
+		return
+	}
+	if n := len(r.frames); n > 0 {
+		r.cycles[n-1] += vm.cycles - r.last
+	}
+	r.last = vm.cycles
+
+	gfx := vm.getGraphics()
+	cols, _ := vm.resolution()
+	if n := len(r.frames); n > 0 && r.cols[n-1] == cols && slices.Equal(r.frames[n-1], gfx) {
+		return
+	}
+	if len(r.frames) == maxRecordFrames {
+		r.full = true
+		vm.log.Log("recording_full", fmt.Sprintf("recording reached %d frames, the rest of the session won't be recorded", maxRecordFrames), Fields{"frames": maxRecordFrames})
+		return
+	}
+	r.frames = append(r.frames, slices.Clone(gfx))
+	r.cols = append(r.cols, cols)
+	r.cycles = append(r.cycles, 0)
+}
+
+// finishRecording writes the recording, if there is one, to its GIF
+func (vm *VM) finishRecording() {
+	r := vm.recorder
+	if r == nil || len(r.frames) == 0 {
+		return
+	}
+	if !r.full {
+		r.cycles[len(r.cycles)-1] += vm.cycles - r.last
+	}
+
+	anim := &gif.GIF{}
+	for i, gfx := range r.frames {
+		// Every frame is drawn at the same size, whatever the resolution
+		anim.Image = append(anim.Image, drawScreen(gfx, r.cols[i], recordScale*64/r.cols[i], vm.palette))
+		// GIF delays are in hundredths of a second
+		anim.Delay = append(anim.Delay, max(1, int(r.cycles[i]*100/uint64(vm.clockSpeed))))
+	}
+
+	f, err := os.Create(r.path)
+	if err != nil {
+		vm.log.Log("error", fmt.Sprintf("error saving recording: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	err = gif.EncodeAll(f, anim)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		vm.log.Log("error", fmt.Sprintf("error saving recording: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	vm.log.Log("recording_saved", fmt.Sprintf("saved recording of %d frames to %s", len(r.frames), r.path), Fields{"path": r.path, "frames": len(r.frames)})
+}
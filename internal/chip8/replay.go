@@ -0,0 +1,224 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// An input log records a session so it can be played back exactly. It is plain text, one entry per line:
+//
+//	# comments and blank lines are ignored
+//	seed 42         the seed CXNN's random numbers were drawn from
+//	cycles 3600     how many clock cycles the session ran for
+//	ips 700         the clock speed it was recorded at, in instructions or, cycle accurate, machine cycles a second
+//	cycle-accurate  present when the clock counted the COSMAC VIP's machine cycles
+//	mode chip8      the dialect the ROM was interpreted as
+//	quirks ShiftInPlace LoadStoreIncrementsI   the Quirks fields turned on, if any
+//	120 5 down      at clock cycle 120, hex key 5 went down
+//	132 5 repeat    ...was pressed again by key repeat while held at 132
+//	138 5 up        ...and came back up at 138
+//
+// Key events are listed in cycle order and take effect before the instruction of their cycle runs. The timers
+// count down relative to the clock, so a session only plays out the same at the clock speed, mode, and quirks
+// it was recorded with. Logs from before those were recorded have no ips entry, and leave them to the player.
+
+// InputEvent is a hex key going down or up at a given clock cycle. Repeat marks a held key being pressed
+// again by key repeat, with Down set too.
+type InputEvent struct {
+	Cycle  uint64
+	Key    byte
+	Down   bool
+	Repeat bool
+}
+
+// Replay is a recorded session: the seed it ran with, its length, and its input, along with the settings it
+// ran with that change how the input plays out. ClockSpeed is zero for logs that don't record the settings.
+type Replay struct {
+	Seed   int64
+	Cycles uint64
+	Events []InputEvent
+
+	ClockSpeed    int
+	CycleAccurate bool
+	Mode          Mode
+	Quirks        Quirks
+}
+
+// ParseReplay reads an input log
+func ParseReplay(r io.Reader) (*Replay, error) {
+	rp := &Replay{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := rp.parseLine(strings.Fields(line)); err != nil {
+			return nil, fmt.Errorf("input log line %d: %v", n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if rp.Cycles == 0 {
+		return nil, fmt.Errorf("input log is missing its cycles entry")
+	}
+	return rp, nil
+}
+
+func (rp *Replay) parseLine(fields []string) error {
+	var err error
+	switch {
+	case len(fields) == 2 && fields[0] == "seed":
+		rp.Seed, err = strconv.ParseInt(fields[1], 0, 64)
+	case len(fields) == 2 && fields[0] == "cycles":
+		rp.Cycles, err = strconv.ParseUint(fields[1], 0, 64)
+	case len(fields) == 2 && fields[0] == "ips":
+		rp.ClockSpeed, err = strconv.Atoi(fields[1])
+		if err == nil && rp.ClockSpeed <= 0 {
+			err = fmt.Errorf("clock speed must be above 0, got %d", rp.ClockSpeed)
+		}
+	case len(fields) == 1 && fields[0] == "cycle-accurate":
+		rp.CycleAccurate = true
+	case len(fields) == 2 && fields[0] == "mode":
+		rp.Mode, err = ParseMode(fields[1])
+	case len(fields) >= 1 && fields[0] == "quirks":
+		for _, name := range fields[1:] {
+			if err := setQuirk(&rp.Quirks, name); err != nil {
+				return err
+			}
+		}
+	case len(fields) == 3:
+		var ev InputEvent
+		if ev.Cycle, err = strconv.ParseUint(fields[0], 0, 64); err != nil {
+			return err
+		}
+		key, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil || key > 0xF {
+			return fmt.Errorf("invalid key %q", fields[1])
+		}
+		ev.Key = byte(key)
+		switch fields[2] {
+		case "down":
+			ev.Down = true
+		case "repeat":
+			ev.Down, ev.Repeat = true, true
+		case "up":
+		default:
+			return fmt.Errorf("invalid key state %q, expected down, repeat, or up", fields[2])
+		}
+		if len(rp.Events) > 0 && ev.Cycle < rp.Events[len(rp.Events)-1].Cycle {
+			return fmt.Errorf("event at cycle %d is out of order", ev.Cycle)
+		}
+		rp.Events = append(rp.Events, ev)
+	default:
+		return fmt.Errorf("unrecognized entry %q", strings.Join(fields, " "))
+	}
+	return err
+}
+
+// Write writes the replay as an input log, for ParseReplay to read back
+func (rp *Replay) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# chippy input log\nseed %d\ncycles %d\n", rp.Seed, rp.Cycles)
+	if rp.ClockSpeed > 0 {
+		fmt.Fprintf(bw, "ips %d\n", rp.ClockSpeed)
+		if rp.CycleAccurate {
+			fmt.Fprintln(bw, "cycle-accurate")
+		}
+		fmt.Fprintf(bw, "mode %s\n", rp.Mode)
+		if names := rp.Quirks.Names(); len(names) > 0 {
+			fmt.Fprintf(bw, "quirks %s\n", strings.Join(names, " "))
+		}
+	}
+	for _, ev := range rp.Events {
+		state := "up"
+		switch {
+		case ev.Repeat:
+			state = "repeat"
+		case ev.Down:
+			state = "down"
+		}
+		fmt.Fprintf(bw, "%d %X %s\n", ev.Cycle, ev.Key, state)
+	}
+	return bw.Flush()
+}
+
+// CheckSettings reports the first of the clock speed, mode, and quirks a VM given clockSpeed and cfg would run
+// at that differ from the ones the replay was recorded with, since it wouldn't play out the same. Replays that
+// don't record their settings are taken on trust.
+func (rp *Replay) CheckSettings(clockSpeed int, cfg Config) error {
+	switch {
+	case rp.ClockSpeed == 0:
+		return nil
+	case clockSpeed != rp.ClockSpeed:
+		return fmt.Errorf("input log was recorded at %dHz, not %dHz", rp.ClockSpeed, clockSpeed)
+	case cfg.CycleAccurate != rp.CycleAccurate:
+		return fmt.Errorf("input log was recorded with cycle accuracy %v, not %v", rp.CycleAccurate, cfg.CycleAccurate)
+	case cfg.Mode != rp.Mode:
+		return fmt.Errorf("input log was recorded in %s mode, not %s", rp.Mode, cfg.Mode)
+	case cfg.Quirks != rp.Quirks:
+		return fmt.Errorf("input log was recorded with quirks %v, not %v", rp.Quirks.Names(), cfg.Quirks.Names())
+	}
+	return nil
+}
+
+// RunReplay plays a recorded session back on the VM as fast as possible, without waiting on the clock,
+// stopping early if the ROM exits. The VM should be seeded with the replay's seed, and pass CheckSettings,
+// for the run to play out the same.
+func (vm *VM) RunReplay(rp *Replay) {
+	vm.playback, vm.playingBack = rp.Events, true
+	for vm.cycles < rp.Cycles && !vm.exited {
+		vm.clockCycle()
+	}
+}
+
+// playInput applies the played back key events due before the next instruction runs
+func (vm *VM) playInput() {
+	for len(vm.playback) > 0 && vm.playback[0].Cycle <= vm.cycles+1 {
+		ev := vm.playback[0]
+		switch {
+		case ev.Repeat:
+			vm.repeatKey(ev.Key)
+		case ev.Down:
+			vm.pressKey(ev.Key)
+		default:
+			vm.releaseKey(ev.Key)
+		}
+		vm.playback = vm.playback[1:]
+	}
+}
+
+// recordInput adds a key event to the input log being recorded, if there is one. Keys change after the
+// instruction of the current cycle has run, so the event is logged against the next.
+func (vm *VM) recordInput(key byte, down, repeat bool) {
+	if vm.inputLog == nil {
+		return
+	}
+	vm.inputLog.Events = append(vm.inputLog.Events, InputEvent{Cycle: vm.cycles + 1, Key: key, Down: down, Repeat: repeat})
+}
+
+// finishInputLog writes the input log being recorded, if there is one, now that the session's length is known
+func (vm *VM) finishInputLog() {
+	if vm.inputLog == nil {
+		return
+	}
+	vm.inputLog.Cycles = vm.cycles
+	f, err := os.Create(vm.inputLogPath)
+	if err == nil {
+		err = vm.inputLog.Write(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		vm.log.Log("error", fmt.Sprintf("error saving input log: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	vm.log.Log("input_log_saved", fmt.Sprintf("saved input log of %d key events to %s", len(vm.inputLog.Events), vm.inputLogPath),
+		Fields{"path": vm.inputLogPath, "events": len(vm.inputLog.Events), "cycles": vm.cycles})
+}
@@ -0,0 +1,49 @@
+package chip8
+
+// Reset restarts the ROM from scratch: memory is cleared and reloaded with the font set, the ROM,
+// and any presets, and the registers, stack, screen, keypad, and timers go back to zero. It's safe
+// to call from any goroutine, and waits for the cycle in progress to finish.
+func (vm *VM) Reset() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.reset()
+}
+
+// reset is Reset for the goroutine running the VM, which already holds mu during a cycle
+func (vm *VM) reset() {
+	vm.restart()
+	vm.log.Log("reset", "reset", Fields{"cycles": vm.cycles})
+}
+
+// restart does the work of reset without logging it, for loading a playlist's next ROM too
+func (vm *VM) restart() {
+	clear(vm.memory)
+	vm.loadFontSet()
+	copy(vm.memory[vm.startAddr:], vm.rom)
+
+	vm.opcode = 0
+	vm.v = [16]byte{}
+	vm.i = 0
+	vm.pc = vm.startAddr
+	clear(vm.stack)
+	vm.sp = 0
+	vm.gfx = [128 * 64]byte{}
+	vm.hires = false
+	vm.keypad = [16]byte{}
+	vm.awaitingKey = false
+	vm.delayTimer, vm.soundTimer, vm.timerPhase = 0, 0, 0
+	vm.soundBuffer, vm.pitch = defaultSoundBuffer, defaultPitch
+	vm.applyPresets(vm.regPresets, vm.memPresets)
+
+	for i, t := range vm.keyRepeat {
+		if t != nil {
+			t.Stop()
+			vm.keyRepeat[i] = nil
+		}
+	}
+	vm.idle.reset()
+	vm.collided, vm.drawn = vm.collided[:0], vm.drawn[:0]
+	vm.halted, vm.paused, vm.stepping = false, false, false
+	vm.drawFlag, vm.frameDirty = false, true
+	vm.syncSoundState()
+}
@@ -0,0 +1,105 @@
+package chip8
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+// defaultRewindInterval is how often a snapshot is taken for rewinding unless configured otherwise
+const defaultRewindInterval = 100 * time.Millisecond
+
+// rewinder keeps the VM's most recent states in a ring of save states, taken every interval of emulated
+// time, for holding the rewind key to step back through. Snapshots are kept in the save state format so
+// restoring one is the same as loading a quick save.
+type rewinder struct {
+	states   [][]byte
+	next     int
+	count    int
+	interval time.Duration
+
+	// The clock cycle of the last snapshot, and the clock cycles left until the next one is popped while rewinding
+	last uint64
+	wait int
+}
+
+func newRewinder(depth int, interval time.Duration) *rewinder {
+	return &rewinder{states: make([][]byte, depth), interval: interval}
+}
+
+// every is the number of clock cycles in the interval at the given clock speed
+func (r *rewinder) every(clockSpeed int) int {
+	return max(1, int(r.interval.Seconds()*float64(clockSpeed)))
+}
+
+// saveRewind saves the VM's state into the ring if an interval has passed since the last one, overwriting
+// the oldest state once the ring is full. The overwritten state's buffer is reused.
+func (vm *VM) saveRewind() {
+	r := vm.rewind
+	if r == nil || vm.cycles-r.last < uint64(r.every(vm.clockSpeed)) {
+		return
+	}
+	r.last = vm.cycles
+	buf := bytes.NewBuffer(r.states[r.next][:0])
+	if err := vm.saveState(buf); err != nil {
+		return
+	}
+	r.states[r.next] = buf.Bytes()
+	r.next = (r.next + 1) % len(r.states)
+	r.count = min(r.count+1, len(r.states))
+}
+
+// whileRewinding steps the VM back one snapshot every interval, at the speed it was played, for as long as
+// the rewind key is held, and reports whether it is. Nothing executes and the sound is silent meanwhile.
+// Once the oldest snapshot is reached the VM waits there, and letting go picks up from whichever snapshot
+// was restored last.
+func (vm *VM) whileRewinding() bool {
+	r := vm.rewind
+	if r == nil {
+		return false
+	}
+	if !vm.window.Hotkeys().Has(hotkey.Rewind) {
+		if vm.rewinding {
+			vm.rewinding = false
+			r.last = vm.cycles
+			vm.syncSoundState()
+		}
+		return false
+	}
+	if !vm.rewinding {
+		vm.rewinding = true
+		r.wait = 0
+		vm.syncSoundState()
+	}
+
+	if r.wait--; r.wait <= 0 {
+		vm.rewindStep()
+		r.wait = r.every(vm.clockSpeed)
+	}
+	if vm.frameDirty && time.Since(vm.lastFrame) >= frameInterval {
+		vm.presentFrame()
+	} else {
+		vm.window.UpdateInput()
+	}
+	return true
+}
+
+// rewindStep restores the most recent snapshot and drops it from the ring. The timers count on from the
+// snapshot's values with a fresh 60Hz phase, the screen is redrawn from it without it counting as a draw,
+// and the keypad is cleared since the keys it had down are long since let go.
+func (vm *VM) rewindStep() {
+	r := vm.rewind
+	if r.count == 0 {
+		return
+	}
+	prev := (r.next - 1 + len(r.states)) % len(r.states)
+	if err := vm.loadState(bytes.NewReader(r.states[prev])); err != nil {
+		return
+	}
+	r.next = prev
+	r.count--
+
+	vm.timerPhase = 0
+	vm.keypad = [16]byte{}
+}
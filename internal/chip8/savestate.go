@@ -0,0 +1,170 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Save states start with saveStateMagic and the version of the format that follows it. Bump the
+// version whenever savedMachine, the layout after it, or what a field means changes, so old saves
+// are rejected instead of being loaded wrong.
+const (
+	saveStateMagic          = "CHIPPYSS"
+	saveStateVersion uint16 = 4
+)
+
+// savedMachine is the fixed size part of a save state, written after the header and followed by
+// the length of memory and memory itself, then the depth of the stack and the stack itself
+type savedMachine struct {
+	Opcode      uint16
+	V           [16]byte
+	I           uint16
+	PC          uint16
+	SP          uint16
+	Gfx         [128 * 64]byte
+	DelayTimer  byte
+	SoundTimer  byte
+	Keypad      [16]byte
+	Hires       bool
+	RPL         [16]byte
+	SoundBuffer [16]byte
+	Pitch       byte
+}
+
+// SaveState writes the machine's full state to w, for LoadState to resume from later. Like LoadState
+// it's safe to call from any goroutine while the VM runs.
+func (vm *VM) SaveState(w io.Writer) error {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.saveState(w)
+}
+
+// saveState and loadState do the work of SaveState and LoadState for the quick save keys, which are
+// handled during a cycle while mu is already held
+func (vm *VM) saveState(w io.Writer) error {
+	if _, err := io.WriteString(w, saveStateMagic); err != nil {
+		return err
+	}
+	m := savedMachine{
+		Opcode:      vm.opcode,
+		V:           vm.v,
+		I:           vm.i,
+		PC:          vm.pc,
+		SP:          vm.sp,
+		Gfx:         vm.gfx,
+		DelayTimer:  vm.delayTimer,
+		SoundTimer:  vm.soundTimer,
+		Keypad:      vm.keypad,
+		Hires:       vm.hires,
+		RPL:         vm.rpl,
+		SoundBuffer: vm.soundBuffer,
+		Pitch:       vm.pitch,
+	}
+	for _, data := range []any{saveStateVersion, m, uint32(len(vm.memory)), vm.memory, uint16(len(vm.stack)), vm.stack} {
+		if err := binary.Write(w, binary.BigEndian, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadState restores a state written by SaveState. The VM is left untouched if the state can't be read.
+func (vm *VM) LoadState(r io.Reader) error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	return vm.loadState(r)
+}
+
+func (vm *VM) loadState(r io.Reader) error {
+	magic := make([]byte, len(saveStateMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != saveStateMagic {
+		return errors.New("not a chippy save state")
+	}
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return err
+	}
+	if version != saveStateVersion {
+		return fmt.Errorf("unsupported save state version %d, this chippy reads version %d", version, saveStateVersion)
+	}
+
+	var m savedMachine
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &m); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return err
+	}
+	if int(size) != len(vm.memory) {
+		return fmt.Errorf("save state has %d bytes of memory, the VM has %d", size, len(vm.memory))
+	}
+	memory := make([]byte, size)
+	if _, err := io.ReadFull(r, memory); err != nil {
+		return err
+	}
+	var depth uint16
+	if err := binary.Read(r, binary.BigEndian, &depth); err != nil {
+		return err
+	}
+	if int(depth) != len(vm.stack) {
+		return fmt.Errorf("save state has a stack %d calls deep, the VM's is %d", depth, len(vm.stack))
+	}
+	if m.SP > depth {
+		return fmt.Errorf("save state's stack pointer %d is past the end of its stack", m.SP)
+	}
+	stack := make([]uint16, depth)
+	if err := binary.Read(r, binary.BigEndian, stack); err != nil {
+		return err
+	}
+
+	vm.opcode, vm.v, vm.i, vm.pc = m.Opcode, m.V, m.I, m.PC
+	copy(vm.stack, stack)
+	vm.sp = m.SP
+	vm.gfx, vm.hires, vm.rpl = m.Gfx, m.Hires, m.RPL
+	vm.delayTimer, vm.soundTimer = m.DelayTimer, m.SoundTimer
+	vm.keypad = m.Keypad
+	vm.awaitingKey = false
+	vm.soundBuffer, vm.pitch = m.SoundBuffer, m.Pitch
+	vm.syncPattern(vm.soundPlaying.Load())
+	copy(vm.memory, memory)
+	vm.idle.reset()
+	// The restored screen is presented at the next frame, whether or not the ROM draws again
+	vm.drawFlag, vm.frameDirty = false, true
+	return nil
+}
+
+// quickSave and quickLoad back the F5 and F9 keys, saving to and loading from the VM's state path
+func (vm *VM) quickSave() {
+	f, err := os.Create(vm.statePath)
+	if err != nil {
+		vm.log.Log("error", fmt.Sprintf("error saving state: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	err = vm.saveState(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		vm.log.Log("error", fmt.Sprintf("error saving state: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	vm.log.Log("state_saved", "saved state to "+vm.statePath, Fields{"path": vm.statePath, "cycles": vm.cycles})
+}
+
+func (vm *VM) quickLoad() {
+	f, err := os.Open(vm.statePath)
+	if err != nil {
+		vm.log.Log("error", fmt.Sprintf("error loading state: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+	if err := vm.loadState(f); err != nil {
+		vm.log.Log("error", fmt.Sprintf("error loading state: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	vm.log.Log("state_loaded", "loaded state from "+vm.statePath, Fields{"path": vm.statePath, "cycles": vm.cycles})
+}
@@ -0,0 +1,85 @@
+package chip8
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// screenshotScale is how many image pixels square each CHIP-8 pixel is drawn in F12 screenshots
+const screenshotScale = 10
+
+// Screenshot renders the screen in the background and foreground colors, drawing each CHIP-8 pixel scale
+// pixels square. The image's palette is the background followed by the foreground.
+func (vm *VM) Screenshot(scale int) *image.Paletted {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.screenshot(scale)
+}
+
+func (vm *VM) screenshot(scale int) *image.Paletted {
+	cols, _ := vm.resolution()
+	return drawScreen(vm.getGraphics(), cols, scale, vm.palette)
+}
+
+// drawScreen draws a screen of cols pixels a row, each pixel scale pixels square, in the palette's
+// second color on its first
+func drawScreen(gfx []byte, cols, scale int, palette color.Palette) *image.Paletted {
+	rows := len(gfx) / cols
+	img := image.NewPaletted(image.Rect(0, 0, cols*scale, rows*scale), palette)
+	for y := range rows {
+		for x := range cols {
+			if gfx[y*cols+x] == 0 {
+				continue
+			}
+			for py := y * scale; py < (y+1)*scale; py++ {
+				for px := x * scale; px < (x+1)*scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// SaveScreenshot writes the screen to path as a PNG, each CHIP-8 pixel scale pixels square
+func (vm *VM) SaveScreenshot(path string, scale int) error {
+	return writePNG(path, vm.Screenshot(scale))
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// quickScreenshot backs the F12 key, saving the screen to a PNG named for the time it was taken
+func (vm *VM) quickScreenshot() {
+	path := filepath.Join(vm.screenshotDir, "chippy-"+time.Now().Format("20060102-150405.000")+".png")
+	if err := writePNG(path, vm.screenshot(screenshotScale)); err != nil {
+		vm.log.Log("error", fmt.Sprintf("error saving screenshot: %v", err), Fields{"error": err.Error()})
+		return
+	}
+	vm.log.Log("screenshot_saved", "saved screenshot to "+path, Fields{"path": path})
+}
+
+// newPalette is the palette screenshots are drawn in, white on black unless the colors are configured
+func newPalette(fg, bg color.Color) color.Palette {
+	if fg == nil {
+		fg = color.White
+	}
+	if bg == nil {
+		bg = color.Black
+	}
+	return color.Palette{bg, fg}
+}
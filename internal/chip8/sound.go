@@ -0,0 +1,131 @@
+package chip8
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// How long the mute key's "muted" or "sound on" is shown for
+const muteMessageDuration = 2 * time.Second
+
+// SoundPlaying reports whether the sound timer is running, which is when CHIP-8 plays its tone.
+// It is safe to call from any goroutine.
+func (vm *VM) SoundPlaying() bool {
+	return vm.soundPlaying.Load()
+}
+
+// syncSoundState publishes whether the sound timer is running for SoundPlaying, and
+// reports a change to OnSoundStateChange. It runs once per clock cycle, and as the VM is
+// paused, resumed, and rewound since the sound is silent while paused or rewinding.
+func (vm *VM) syncSoundState() {
+	playing := vm.soundTimer > 0 && !vm.paused && !vm.rewinding && !vm.fastForwarding
+	if vm.soundPlaying.Swap(playing) != playing {
+		if playing {
+			vm.beep()
+			if vm.onBeep != nil {
+				vm.onBeep()
+			}
+		}
+		vm.syncPattern(playing)
+		vm.syncTone(playing)
+		if vm.OnSoundStateChange != nil {
+			vm.OnSoundStateChange(playing)
+		}
+	}
+}
+
+// beep sends a beep for displays that ring a bell in place of the tone. Emulation is never held up
+// for audio, so the beep is dropped if nothing is ready to take it.
+func (vm *VM) beep() {
+	if vm.muted {
+		return
+	}
+	select {
+	case vm.audioC <- struct{}{}:
+		vm.stats.Beeps.Add(1)
+	default:
+	}
+}
+
+// toggleMute backs the M key, silencing or restoring playback. Only what's heard changes, the sound timer
+// runs on as before. A tone the ROM is in the middle of starts playing again on unmuting.
+func (vm *VM) toggleMute() {
+	vm.muted = !vm.muted
+	playing := vm.soundPlaying.Load()
+	vm.syncPattern(playing)
+	vm.syncTone(playing)
+	msg := "muted"
+	if !vm.muted {
+		msg = "sound on"
+		if playing {
+			vm.beep()
+		}
+	}
+	vm.window.ShowOverlay(msg, muteMessageDuration)
+	vm.frameDirty = true
+}
+
+// tonePlayer is a beep.Streamer that plays the beep, a sine tone, while the sound timer runs and silence
+// otherwise, so the tone stops on the speaker's next buffer however the sound does. Like patternPlayer the VM
+// updates it from its goroutine while the speaker streams it from another, so everything is behind mu.
+type tonePlayer struct {
+	mu      sync.Mutex
+	step    float64
+	playing bool
+
+	// A beep plays for at least minSamples. hold counts down what's left of that once the tone starts.
+	minSamples int
+	hold       int
+
+	// How far through a cycle of the sine the tone is, out of 1
+	phase float64
+}
+
+func newTonePlayer(hz int, minBeep time.Duration) *tonePlayer {
+	return &tonePlayer{step: float64(hz) / float64(beepSampleRate), minSamples: beepSampleRate.N(minBeep)}
+}
+
+// set starts and stops the tone. A tone that stops because the sound timer ran out plays on until it has
+// lasted the minimum, and one that's cut off, by pausing or muting say, stops at once.
+func (p *tonePlayer) set(playing, cut bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if playing && !p.playing {
+		p.hold = p.minSamples
+	}
+	if cut {
+		p.hold = 0
+	}
+	p.playing = playing
+}
+
+// Stream fills samples with the tone while it's playing and silence otherwise. It never runs out.
+func (p *tonePlayer) Stream(samples [][2]float64) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range samples {
+		v := 0.0
+		if p.playing || p.hold > 0 {
+			v = math.Sin(2 * math.Pi * p.phase)
+			p.phase = math.Mod(p.phase+p.step, 1)
+		}
+		if p.hold > 0 {
+			p.hold--
+		}
+		samples[i] = [2]float64{v, v}
+	}
+	return len(samples), true
+}
+
+func (p *tonePlayer) Err() error { return nil }
+
+// syncTone starts and stops the beep tone with the sound. A tone stopped with time left on the sound timer,
+// by pausing, rewinding or fast-forwarding, or one that's muted, is cut off rather than held to minBeep.
+func (vm *VM) syncTone(playing bool) {
+	if vm.tone != nil {
+		on := playing && !vm.muted
+		vm.tone.set(on, !on && (vm.soundTimer > 0 || vm.muted))
+	}
+}
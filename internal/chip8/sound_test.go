@@ -0,0 +1,88 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSoundPlaying(t *testing.T) {
+	// Start the sound timer at 5 with FX18, then count in a loop
+	vm := newTestVM(t, Config{}, 0x6005, 0xF018, 0x7101, 0x1204)
+	var changes []bool
+	vm.OnSoundStateChange = func(playing bool) { changes = append(changes, playing) }
+
+	vm.clockCycle()
+	if vm.SoundPlaying() {
+		t.Fatal("sound playing before the sound timer was set")
+	}
+	vm.clockCycle()
+	if !vm.SoundPlaying() {
+		t.Fatal("sound not playing with the sound timer set")
+	}
+	vm.clockCycle()
+
+	vm.soundTimer = 0
+	vm.clockCycle()
+	if vm.SoundPlaying() {
+		t.Error("sound still playing with the sound timer zeroed")
+	}
+	if want := []bool{true, false}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("OnSoundStateChange calls = %v, want %v", changes, want)
+	}
+}
+
+// sounds streams a buffer of the tone, reporting whether any of it was heard
+func sounds(p *tonePlayer) bool {
+	buf := make([][2]float64, 512)
+	p.Stream(buf)
+	for _, s := range buf {
+		if s[0] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestToneFollowsTheSound(t *testing.T) {
+	vm := newTestVM(t, Config{MinBeep: time.Second})
+	vm.soundTimer = 30
+	vm.syncSoundState()
+	if !sounds(vm.tone) {
+		t.Fatal("no tone with the sound timer running")
+	}
+
+	// Pausing and muting cut the tone off, even one that hasn't played for MinBeep yet
+	vm.pause()
+	if sounds(vm.tone) {
+		t.Error("tone still playing while paused")
+	}
+	vm.paused = false
+	vm.syncSoundState()
+	if !sounds(vm.tone) {
+		t.Error("tone didn't start again on resuming")
+	}
+	vm.toggleMute()
+	if sounds(vm.tone) {
+		t.Error("tone still playing while muted")
+	}
+	vm.toggleMute()
+	if !sounds(vm.tone) {
+		t.Error("tone didn't start again on unmuting")
+	}
+
+	// A ROM clearing the sound timer stops the tone once it has played for MinBeep
+	vm.soundTimer = 0
+	vm.syncSoundState()
+	if !sounds(vm.tone) {
+		t.Error("tone stopped before playing for MinBeep")
+	}
+	vm = newTestVM(t, Config{})
+	vm.soundTimer = 30
+	vm.syncSoundState()
+	vm.soundTimer = 0
+	vm.syncSoundState()
+	if sounds(vm.tone) {
+		t.Error("tone still playing with the sound timer cleared and no MinBeep")
+	}
+}
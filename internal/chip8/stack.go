@@ -0,0 +1,101 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultStackDepth is how many calls deep the stack goes unless configured otherwise, as on most interpreters
+// since CHIP-48. The COSMAC VIP's had room for 12.
+const defaultStackDepth = 16
+
+// maxStackDepth is the deepest stack that can be configured
+const maxStackDepth = 256
+
+// StackPolicy is what the VM does when a ROM calls with the stack full or returns with it empty
+type StackPolicy int
+
+const (
+	// StackHalt halts the VM and prints its state, as a breakpoint would. The call or return is skipped, so
+	// resuming carries on with the instruction after it rather than faulting again.
+	StackHalt StackPolicy = iota
+
+	// StackWrap lets the stack pointer wrap around, so a call on a full stack overwrites the oldest return
+	// address and a return from an empty one pops the deepest slot
+	StackWrap
+
+	// StackError stops the VM with an error
+	StackError
+)
+
+// StackPolicies lists every stack policy chippy supports
+var StackPolicies = []StackPolicy{StackHalt, StackWrap, StackError}
+
+func (p StackPolicy) String() string {
+	switch p {
+	case StackHalt:
+		return "halt"
+	case StackWrap:
+		return "wrap"
+	case StackError:
+		return "error"
+	default:
+		return fmt.Sprintf("StackPolicy(%d)", int(p))
+	}
+}
+
+// ParseStackPolicy returns the StackPolicy with the given name, as printed by StackPolicy.String
+func ParseStackPolicy(name string) (StackPolicy, error) {
+	for _, p := range StackPolicies {
+		if strings.EqualFold(name, p.String()) {
+			return p, nil
+		}
+	}
+	return StackHalt, fmt.Errorf("unknown stack policy %q, expected one of halt, wrap, error", name)
+}
+
+// push saves a return address for 2NNN, reporting false if the stack is full and the policy doesn't wrap it around
+func (vm *VM) push(addr uint16) bool {
+	if int(vm.sp) == len(vm.stack) {
+		if vm.stackPolicy != StackWrap {
+			return false
+		}
+		vm.sp = 0
+	}
+	vm.stack[vm.sp] = addr
+	vm.sp++
+	return true
+}
+
+// pop takes the last return address off the stack for 00EE, reporting false if the stack is empty and the
+// policy doesn't wrap it around
+func (vm *VM) pop() (uint16, bool) {
+	if vm.sp == 0 {
+		if vm.stackPolicy != StackWrap {
+			return 0, false
+		}
+		vm.sp = uint16(len(vm.stack))
+	}
+	vm.sp--
+	return vm.stack[vm.sp], true
+}
+
+// stackFault handles a stack overflow or underflow as the policy says: halting and skipping the faulting
+// instruction, like an unknown opcode, or stopping the VM with err
+func (vm *VM) stackFault(err error) {
+	if vm.stackPolicy != StackError {
+		vm.halt(err.Error())
+		vm.pc += 2
+		return
+	}
+	vm.err = err
+	vm.exited = true
+	vm.log.Log("error", fmt.Sprintf("error running rom: %v", err), Fields{"error": err.Error(), "pc": vm.pc, "cycles": vm.cycles})
+}
+
+// Err is the error that stopped the VM, if one did, like a stack overflow with the error policy
+func (vm *VM) Err() error {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.err
+}
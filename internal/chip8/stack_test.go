@@ -0,0 +1,87 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStackPolicies(t *testing.T) {
+	// A subroutine at 0x200 calling itself, one call deeper than a 12 deep stack holds
+	calls := func(t *testing.T, policy StackPolicy) *VM {
+		vm := newTestVM(t, Config{StackDepth: 12, StackPolicy: policy}, 0x2200)
+		vm.headless = false
+		for range 13 {
+			vm.clockCycle()
+		}
+		return vm
+	}
+
+	t.Run("halt", func(t *testing.T) {
+		vm := calls(t, StackHalt)
+		if !vm.halted || vm.sp != 12 || vm.pc != 0x202 {
+			t.Errorf("halted = %v, sp = %d, pc = 0x%03X, want halted past the 13th call with 12 on the stack", vm.halted, vm.sp, vm.pc)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		vm := calls(t, StackError)
+		if err := vm.Err(); !errors.Is(err, errStackOverflow) || !vm.exited {
+			t.Errorf("err = %v, exited = %v, want stopped on a stack overflow", err, vm.exited)
+		}
+	})
+	t.Run("wrap", func(t *testing.T) {
+		vm := calls(t, StackWrap)
+		if vm.halted || vm.exited || vm.sp != 1 {
+			t.Errorf("halted = %v, exited = %v, sp = %d, want the 13th call wrapped around to the bottom", vm.halted, vm.exited, vm.sp)
+		}
+	})
+
+	// Returning with the stack empty
+	for _, policy := range StackPolicies {
+		vm := newTestVM(t, Config{StackDepth: 12, StackPolicy: policy}, 0x00EE)
+		vm.headless = false
+		vm.stack[11] = 0x300
+		vm.clockCycle()
+		switch policy {
+		case StackHalt:
+			if !vm.halted || vm.pc != 0x202 {
+				t.Errorf("halt: halted = %v at 0x%03X, want halted past the return", vm.halted, vm.pc)
+			}
+		case StackError:
+			if !errors.Is(vm.Err(), errStackUnderflow) {
+				t.Errorf("error: err = %v, want a stack underflow", vm.Err())
+			}
+		case StackWrap:
+			if vm.sp != 11 || vm.pc != 0x302 {
+				t.Errorf("wrap: sp = %d, pc = 0x%03X, want the deepest slot popped, returning to 0x302", vm.sp, vm.pc)
+			}
+		}
+	}
+}
+
+func TestStackDepth(t *testing.T) {
+	for _, tt := range []struct {
+		depth, want int
+	}{{0, 16}, {1, 1}, {12, 12}, {256, 256}} {
+		vm := newTestVM(t, Config{StackDepth: tt.depth})
+		if len(vm.stack) != tt.want {
+			t.Errorf("stack depth %d gave a %d deep stack, want %d", tt.depth, len(vm.stack), tt.want)
+		}
+	}
+	for _, depth := range []int{-1, 257} {
+		if _, err := NewVM(bytes.NewReader([]byte{0x12, 0x00}), DefaultClockSpeed, Config{Headless: true, StackDepth: depth}); err == nil {
+			t.Errorf("stack depth %d didn't fail", depth)
+		}
+	}
+}
+
+func TestParseStackPolicy(t *testing.T) {
+	for _, p := range StackPolicies {
+		if got, err := ParseStackPolicy(p.String()); err != nil || got != p {
+			t.Errorf("ParseStackPolicy(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := ParseStackPolicy("ignore"); err == nil {
+		t.Error("ParseStackPolicy(ignore) didn't fail")
+	}
+}
@@ -0,0 +1,39 @@
+package chip8
+
+// State is a copy of the machine's registers and memory at a point in time
+type State struct {
+	PC, I, SP  uint16
+	V          [16]byte
+	Stack      []uint16
+	DelayTimer byte
+	SoundTimer byte
+	Memory     []byte
+	Cycles     uint64
+
+	// Gfx is the screen, Width pixels a row, with a byte of 1 for each lit pixel and 0 for the rest
+	Gfx           []byte
+	Width, Height int
+}
+
+// Snapshot copies the machine's current state. It's safe to call from any goroutine while the VM runs,
+// and waits for the cycle in progress to finish.
+func (vm *VM) Snapshot() State {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	w, h := vm.resolution()
+	return State{
+		PC:         vm.pc,
+		I:          vm.i,
+		SP:         vm.sp,
+		V:          vm.v,
+		Stack:      append([]uint16(nil), vm.stack...),
+		DelayTimer: vm.delayTimer,
+		SoundTimer: vm.soundTimer,
+		Memory:     append([]byte(nil), vm.memory...),
+		Cycles:     vm.cycles,
+		Gfx:        append([]byte(nil), vm.getGraphics()...),
+		Width:      w,
+		Height:     h,
+	}
+}
@@ -0,0 +1,60 @@
+package chip8
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// The exported accessors are called from other goroutines while Run mutates the VM. Run the tests with
+// -race to check they're guarded.
+func TestAccessorsWhileRunning(t *testing.T) {
+	// Draw the 0 glyph, move right, and loop
+	vm := newTestVM(t, Config{MaxCycles: 50000}, 0xA000, 0xD015, 0x7001, 0x1202)
+	done := make(chan struct{})
+	go func() {
+		vm.Run()
+		close(done)
+	}()
+
+	var saved bytes.Buffer
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		s := vm.Snapshot()
+		if len(s.Memory) != 0x1000 || len(s.Gfx) != s.Width*s.Height {
+			t.Fatalf("snapshot has %d bytes of memory and %d pixels at %dx%d", len(s.Memory), len(s.Gfx), s.Width, s.Height)
+		}
+		vm.FrameHash()
+		vm.Screenshot(1)
+		vm.Debug(io.Discard)
+		vm.SoundPlaying()
+		vm.Err()
+		vm.Stats().IPS(vm.Stats().Started)
+
+		saved.Reset()
+		if err := vm.SaveState(&saved); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.LoadState(&saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	vm := newTestVM(t, Config{}, 0x6042)
+	vm.clockCycle()
+
+	s := vm.Snapshot()
+	if s.PC != 0x202 || s.V[0] != 0x42 || s.Memory[0x200] != 0x60 {
+		t.Errorf("snapshot pc = 0x%03X, V0 = 0x%02X, memory at 0x200 = 0x%02X", s.PC, s.V[0], s.Memory[0x200])
+	}
+	s.Memory[0x200], s.Gfx[0], s.Stack[0] = 0xFF, 1, 0xFFF
+	if vm.memory[0x200] != 0x60 || vm.gfx[0] != 0 || vm.stack[0] != 0 {
+		t.Error("changing the snapshot changed the VM")
+	}
+}
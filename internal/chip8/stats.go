@@ -0,0 +1,106 @@
+package chip8
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// IPS is measured over the instructions executed in the last ipsWindow, counted every ipsSampleInterval
+	ipsWindow         = 5 * time.Second
+	ipsSampleInterval = 100 * time.Millisecond
+)
+
+// Stats are running totals of what the VM has done. They are updated as the VM
+// runs and are safe to read from any goroutine.
+type Stats struct {
+	// Cycles counts clock cycles, idle ones included
+	Cycles atomic.Uint64
+
+	// Instructions counts the instructions executed
+	Instructions atomic.Uint64
+
+	// Frames counts the frames drawn
+	Frames atomic.Uint64
+
+	// Draws counts the sprites drawn with DXYN
+	Draws atomic.Uint64
+
+	// Beeps counts the beeps played
+	Beeps atomic.Uint64
+
+	// Started is when the VM was created
+	Started time.Time
+
+	// The instruction counts sampled over the last ipsWindow, oldest first
+	mu      sync.Mutex
+	samples []ipsSample
+}
+
+type ipsSample struct {
+	at           time.Time
+	instructions uint64
+}
+
+// IPS returns the instructions executed a second over the few seconds up to now, or since the VM started
+// if that was more recently. A VM that's paused or halted for the whole window has an IPS of 0.
+func (s *Stats) IPS(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, smp := range s.samples {
+		if now.Sub(smp.at) > ipsWindow {
+			continue
+		}
+		elapsed := now.Sub(smp.at).Seconds()
+		if elapsed <= 0 {
+			return 0
+		}
+		return float64(s.Instructions.Load()-smp.instructions) / elapsed
+	}
+	return 0
+}
+
+// sample counts the instructions executed as of now for IPS, at most once every ipsSampleInterval,
+// and forgets the counts that have fallen out of the window
+func (s *Stats) sample(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.samples); n > 0 && now.Sub(s.samples[n-1].at) < ipsSampleInterval {
+		return
+	}
+	s.samples = append(s.samples, ipsSample{at: now, instructions: s.Instructions.Load()})
+	for len(s.samples) > 1 && now.Sub(s.samples[0].at) > ipsWindow {
+		s.samples = s.samples[1:]
+	}
+}
+
+// Stats returns the VM's running totals
+func (vm *VM) Stats() *Stats {
+	return &vm.stats
+}
+
+// logSummary reports the totals for the run so far, with how many instructions a second it managed against
+// the clock speed it was set to, so it's easy to see whether the host kept up
+func (vm *VM) logSummary() {
+	runtime := time.Since(vm.stats.Started)
+	instructions := vm.stats.Instructions.Load()
+	ips := 0.0
+	if runtime > 0 {
+		ips = float64(instructions) / runtime.Seconds()
+	}
+	msg := fmt.Sprintf("ran %d cycles and %d instructions in %.2fs, %.0f instructions a second at a clock of %dHz. %d frames, %d sprites drawn, %d beeps",
+		vm.stats.Cycles.Load(), instructions, runtime.Seconds(), ips, vm.clockSpeed,
+		vm.stats.Frames.Load(), vm.stats.Draws.Load(), vm.stats.Beeps.Load())
+	vm.log.Log("stats", msg, Fields{
+		"cycles":          vm.stats.Cycles.Load(),
+		"instructions":    instructions,
+		"runtime_seconds": runtime.Seconds(),
+		"ips":             ips,
+		"clock_hz":        vm.clockSpeed,
+		"frames":          vm.stats.Frames.Load(),
+		"draws":           vm.stats.Draws.Load(),
+		"beeps":           vm.stats.Beeps.Load(),
+	})
+}
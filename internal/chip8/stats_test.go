@@ -0,0 +1,45 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsIPS(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var s Stats
+	s.sample(start)
+
+	// 700 instructions a second for 10 seconds, then 1400 a second for 3
+	now := start
+	for i := 0; i < 130; i++ {
+		now = now.Add(ipsSampleInterval)
+		if i < 100 {
+			s.Instructions.Add(70)
+		} else {
+			s.Instructions.Add(140)
+		}
+		s.sample(now)
+	}
+
+	// The window holds 2 seconds at 700 and 3 at 1400
+	if got, want := s.IPS(now), (2*700.0+3*1400.0)/5; got < want-1 || got > want+1 {
+		t.Errorf("IPS = %v, want %v", got, want)
+	}
+	if got := s.IPS(now.Add(2 * ipsWindow)); got != 0 {
+		t.Errorf("IPS after a pause longer than the window = %v, want 0", got)
+	}
+	if len(s.samples) > int(ipsWindow/ipsSampleInterval)+1 {
+		t.Errorf("kept %d samples, want at most a window's worth", len(s.samples))
+	}
+}
+
+func TestStatsIPSSinceStart(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var s Stats
+	s.sample(start)
+	s.Instructions.Add(350)
+	if got := s.IPS(start.Add(time.Second / 2)); got != 700 {
+		t.Errorf("IPS half a second in = %v, want 700", got)
+	}
+}
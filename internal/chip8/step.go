@@ -0,0 +1,114 @@
+package chip8
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StepResult describes one executed instruction and the state it changed. Fields that didn't
+// change are left empty so a stream of results only carries the deltas.
+type StepResult struct {
+	Cycle      uint64          `json:"cycle"`
+	PC         uint16          `json:"pc"`
+	Opcode     uint16          `json:"opcode"`
+	V          map[string]byte `json:"v,omitempty"`
+	I          *uint16         `json:"i,omitempty"`
+	SP         *uint16         `json:"sp,omitempty"`
+	Memory     map[string]byte `json:"memory,omitempty"`
+	DelayTimer *byte           `json:"delay_timer,omitempty"`
+	SoundTimer *byte           `json:"sound_timer,omitempty"`
+	Draw       bool            `json:"draw,omitempty"`
+}
+
+// step runs a single cycle like emulateCycle and reports what it did. Registers and timers are compared
+// against a copy taken before executing, while memory is too big to copy every cycle, so the instructions
+// that write to it note the addresses they change through store instead.
+func (vm *VM) step() StepResult {
+	pc, i, sp := vm.pc, vm.i, vm.sp
+	v := vm.v
+	delayTimer, soundTimer := vm.delayTimer, vm.soundTimer
+
+	vm.trackWrites, vm.written = true, vm.written[:0]
+	defer func() { vm.trackWrites = false }()
+	vm.emulateCycle()
+
+	res := StepResult{
+		Cycle:  vm.cycles,
+		PC:     pc,
+		Opcode: vm.opcode,
+		Draw:   vm.drawFlag,
+	}
+	for r := range vm.v {
+		if vm.v[r] != v[r] {
+			if res.V == nil {
+				res.V = map[string]byte{}
+			}
+			res.V[fmt.Sprintf("V%X", r)] = vm.v[r]
+		}
+	}
+	for _, addr := range vm.written {
+		if res.Memory == nil {
+			res.Memory = map[string]byte{}
+		}
+		res.Memory[fmt.Sprintf("0x%03X", addr)] = vm.memory[addr]
+	}
+	if vm.i != i {
+		i = vm.i
+		res.I = &i
+	}
+	if vm.sp != sp {
+		sp = vm.sp
+		res.SP = &sp
+	}
+	if vm.delayTimer != delayTimer {
+		delayTimer = vm.delayTimer
+		res.DelayTimer = &delayTimer
+	}
+	if vm.soundTimer != soundTimer {
+		soundTimer = vm.soundTimer
+		res.SoundTimer = &soundTimer
+	}
+	return res
+}
+
+// store writes b to memory at addr, noting the address while step is tracking what an instruction changes
+func (vm *VM) store(addr int, b byte) {
+	if vm.trackWrites && vm.memory[addr] != b {
+		vm.written = append(vm.written, addr)
+	}
+	vm.memory[addr] = b
+}
+
+// Changes lists the state a step changed, e.g. "V3=0x0A I=0x2EA [0x2F0]=0x01"
+func (res StepResult) Changes() string {
+	var changes []string
+	for _, r := range sortedKeys(res.V) {
+		changes = append(changes, fmt.Sprintf("%s=0x%02X", r, res.V[r]))
+	}
+	if res.I != nil {
+		changes = append(changes, fmt.Sprintf("I=0x%03X", *res.I))
+	}
+	if res.SP != nil {
+		changes = append(changes, fmt.Sprintf("SP=%d", *res.SP))
+	}
+	for _, addr := range sortedKeys(res.Memory) {
+		changes = append(changes, fmt.Sprintf("[%s]=0x%02X", addr, res.Memory[addr]))
+	}
+	if res.DelayTimer != nil {
+		changes = append(changes, fmt.Sprintf("DT=%d", *res.DelayTimer))
+	}
+	if res.SoundTimer != nil {
+		changes = append(changes, fmt.Sprintf("ST=%d", *res.SoundTimer))
+	}
+	return strings.Join(changes, " ")
+}
+
+func sortedKeys(m map[string]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,46 @@
+package chip8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEventsJSON(t *testing.T) {
+	var events bytes.Buffer
+	vm := newTestVM(t, Config{Events: &events}, 0x6042, 0xA300, 0xF033, 0xD001, 0x2300)
+	for i := 0; i < 5; i++ {
+		vm.clockCycle()
+	}
+
+	// Only what each instruction changed is reported. 66's hundreds digit is the 0 already in memory.
+	want := []string{
+		`{"cycle": 1, "pc": 512, "opcode": 24642, "v": {"V0": 66}}`,
+		`{"cycle": 2, "pc": 514, "opcode": 41728, "i": 768}`,
+		`{"cycle": 3, "pc": 516, "opcode": 61491, "memory": {"0x301": 6, "0x302": 6}}`,
+		`{"cycle": 4, "pc": 518, "opcode": 53249, "draw": true}`,
+		`{"cycle": 5, "pc": 520, "opcode": 8960, "sp": 1}`,
+	}
+	sc := bufio.NewScanner(&events)
+	n := 0
+	for ; sc.Scan(); n++ {
+		if n >= len(want) {
+			t.Fatalf("got more than %d events: %s", len(want), sc.Text())
+		}
+		var got, wantEvent map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &got); err != nil {
+			t.Fatalf("event %d isn't JSON: %v: %s", n, err, sc.Text())
+		}
+		if err := json.Unmarshal([]byte(want[n]), &wantEvent); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, wantEvent) {
+			t.Errorf("event %d = %s, want %s", n, sc.Text(), want[n])
+		}
+	}
+	if n != len(want) {
+		t.Errorf("got %d events, want %d", n, len(want))
+	}
+}
@@ -0,0 +1,48 @@
+package chip8
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+const (
+	// maxTurbo is how many times PageUp can double the clock speed, and PageDown halve it
+	maxTurbo = 3
+
+	// How long the new speed is shown after it's changed
+	turboMessageDuration = 2 * time.Second
+)
+
+// handleTurboKeys doubles the clock speed on PageUp and halves it on PageDown, up to maxTurbo times
+// either way, and shows the new speed over the screen. Only the instructions run per second change.
+// The timers are kept on the clock in step with its speed, so they go on counting down at 60Hz.
+func (vm *VM) handleTurboKeys(keys hotkey.Actions) {
+	turbo := vm.turbo
+	switch {
+	case keys.Has(hotkey.SpeedUp):
+		turbo = min(turbo+1, maxTurbo)
+	case keys.Has(hotkey.SlowDown):
+		turbo = max(turbo-1, -maxTurbo)
+	default:
+		return
+	}
+	vm.turbo = turbo
+	vm.applyTurbo()
+
+	multiplier := fmt.Sprint(1 << turbo)
+	if turbo < 0 {
+		multiplier = fmt.Sprintf("1/%d", 1<<-turbo)
+	}
+	vm.window.ShowOverlay(fmt.Sprintf("speed x%s, %dHz", multiplier, vm.clockSpeed), turboMessageDuration)
+	vm.frameDirty = true
+}
+
+// applyTurbo sets the clock speed to the base speed scaled by the turbo setting, and by fast-forwarding.
+// The timers' progress towards their next tick is in cycles of the old speed, so it starts over.
+func (vm *VM) applyTurbo() {
+	vm.clockSpeed = max(1, int(math.Ldexp(float64(vm.baseClockSpeed), vm.turbo))) * vm.speedFactor()
+	vm.timerPhase = 0
+}
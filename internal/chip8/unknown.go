@@ -0,0 +1,56 @@
+package chip8
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// unknownOpSite is an unknown opcode and the address a ROM hit it at
+type unknownOpSite struct {
+	addr   uint16
+	opcode uint16
+}
+
+// recordUnknownOp counts an unknown opcode at the program counter, logging err as well when ShowUnknown is set,
+// then skips over it so one bad instruction can't leave the VM stuck on it. With HaltOnUnknown the VM halts first.
+func (vm *VM) recordUnknownOp(err error) {
+	if vm.showUnknown {
+		vm.log.Log("unknown_opcode", "error parsing opcode: "+err.Error(), Fields{"opcode": vm.opcode, "pc": vm.pc})
+	}
+	if vm.unknownOps == nil {
+		vm.unknownOps = make(map[unknownOpSite]int)
+	}
+	vm.unknownOps[unknownOpSite{addr: vm.pc, opcode: vm.opcode}]++
+	if vm.haltOnUnknown {
+		vm.halt(err.Error())
+	}
+	vm.pc += 2
+}
+
+// logUnknownOps logs how many times each unknown opcode was hit, ordered by address
+func (vm *VM) logUnknownOps() {
+	if len(vm.unknownOps) == 0 {
+		return
+	}
+
+	sites := make([]unknownOpSite, 0, len(vm.unknownOps))
+	for s := range vm.unknownOps {
+		sites = append(sites, s)
+	}
+	slices.SortFunc(sites, func(a, b unknownOpSite) int {
+		if a.addr != b.addr {
+			return int(a.addr) - int(b.addr)
+		}
+		return int(a.opcode) - int(b.opcode)
+	})
+
+	var b strings.Builder
+	b.WriteString("Unknown opcodes:")
+	hits := make([]Fields, 0, len(sites))
+	for _, s := range sites {
+		fmt.Fprintf(&b, "\n  %04X at 0x%03X: %d times", s.opcode, s.addr, vm.unknownOps[s])
+		hits = append(hits, Fields{"opcode": s.opcode, "addr": s.addr, "count": vm.unknownOps[s]})
+	}
+	vm.log.Log("unknown_opcodes", b.String(), Fields{"opcodes": hits})
+}
@@ -0,0 +1,35 @@
+package chip8
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownOpCounts(t *testing.T) {
+	for _, show := range []bool{false, true} {
+		var log strings.Builder
+		// Two unknown opcodes in a loop, hit three times each
+		vm := newTestVM(t, Config{ShowUnknown: show, Logger: TextLogger{W: &log}}, 0x0000, 0x812F, 0x1200)
+		for range 9 {
+			vm.clockCycle()
+		}
+
+		want := map[unknownOpSite]int{
+			{addr: 0x200, opcode: 0x0000}: 3,
+			{addr: 0x202, opcode: 0x812F}: 3,
+		}
+		if !reflect.DeepEqual(vm.unknownOps, want) {
+			t.Errorf("show %v: unknown opcodes = %v, want %v", show, vm.unknownOps, want)
+		}
+		if hits := strings.Count(log.String(), "error parsing opcode"); show && hits != 6 || !show && hits != 0 {
+			t.Errorf("show %v: logged %d unknown opcodes as they were hit", show, hits)
+		}
+
+		log.Reset()
+		vm.logUnknownOps()
+		if got, want := log.String(), "Unknown opcodes:\n  0000 at 0x200: 3 times\n  812F at 0x202: 3 times\n"; got != want {
+			t.Errorf("show %v: summary = %q, want %q", show, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !js
+
+package chip8
+
+import (
+	"github.com/bradford-hamilton/chippy/internal/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// KeyMap binds the CHIP-8 hex keys to the window's keyboard keys
+type KeyMap = map[uint16]pixelgl.Button
+
+// openWindow opens the window the VM draws to, and the memory view next to it if cfg asks for one
+func openWindow(cfg Config) (Display, MemoryView, error) {
+	if cfg.Title == "" {
+		cfg.Title = "chippy"
+	}
+	scale := cfg.Scale
+	if scale == 0 {
+		scale = pixel.DefaultScale
+	}
+	w, err := pixel.NewWindow(cfg.Title, scale, cfg.Fullscreen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Hints != "" {
+		w.ShowOverlay(cfg.Hints, hintsDuration)
+	}
+	if cfg.KeyMap != nil {
+		w.KeyMap = cfg.KeyMap
+	}
+	if cfg.Gamepad {
+		w.GamepadMap = pixel.DefaultGamepadMap()
+	}
+	if cfg.Foreground != nil {
+		w.Foreground = cfg.Foreground
+	}
+	if cfg.Background != nil {
+		w.Background = cfg.Background
+	}
+	w.Fade = cfg.Fade
+	w.PixelGap = cfg.PixelGap
+	if !cfg.MemoryView {
+		return w, nil, nil
+	}
+	mw, err := pixel.NewMemoryWindow(cfg.Title + " — memory")
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, mw, nil
+}
@@ -0,0 +1,13 @@
+//go:build js
+
+package chip8
+
+import "errors"
+
+// KeyMap goes unused in the browser, where the page binds the keys on the web.Display it makes
+type KeyMap = map[uint16]string
+
+// openWindow fails in the browser, which has no windows to open. The page passes a web.Display as Config.Display instead.
+func openWindow(cfg Config) (Display, MemoryView, error) {
+	return nil, nil, errors.New("there are no windows in the browser, pass a web.Display as Config.Display")
+}
@@ -0,0 +1,80 @@
+package chip8
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// The pitch XO-CHIP ROMs start at, which plays the pattern at 4000 bits a second
+	defaultPitch = 64
+
+	// How loud the pattern's set and clear bits play, out of 1
+	patternVolume = 0.25
+)
+
+// defaultSoundBuffer is the pattern played before a ROM stores its own with F002, a square wave of
+// 500Hz at the default pitch
+var defaultSoundBuffer = [16]byte{
+	0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0,
+	0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0,
+}
+
+// patternPlayer is a beep.Streamer that loops XO-CHIP's 128 bit audio pattern, one bit per
+// sample of a 1-bit waveform, at the rate set by the pitch register. The VM updates it from its
+// goroutine while the speaker streams it from another, so everything is behind mu.
+type patternPlayer struct {
+	mu      sync.Mutex
+	pattern [16]byte
+	pitch   byte
+	playing bool
+
+	// How far through the pattern playback is, in bits
+	pos float64
+}
+
+func newPatternPlayer() *patternPlayer {
+	return &patternPlayer{pattern: defaultSoundBuffer, pitch: defaultPitch}
+}
+
+// set updates the pattern, pitch, and whether the pattern plays
+func (p *patternPlayer) set(pattern [16]byte, pitch byte, playing bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pattern, p.pitch, p.playing = pattern, pitch, playing
+}
+
+// bitsPerSecond is the playback rate XO-CHIP defines for a pitch, 4000*2^((pitch-64)/48)
+func bitsPerSecond(pitch byte) float64 {
+	return 4000 * math.Pow(2, (float64(pitch)-64)/48)
+}
+
+// Stream fills samples with the pattern while it's playing and silence otherwise. It never runs out.
+func (p *patternPlayer) Stream(samples [][2]float64) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	step := bitsPerSecond(p.pitch) / float64(beepSampleRate)
+	for i := range samples {
+		v := 0.0
+		if p.playing {
+			bit := int(p.pos) % 128
+			v = -patternVolume
+			if p.pattern[bit/8]&(0x80>>(bit%8)) != 0 {
+				v = patternVolume
+			}
+			p.pos = math.Mod(p.pos+step, 128)
+		}
+		samples[i] = [2]float64{v, v}
+	}
+	return len(samples), true
+}
+
+func (p *patternPlayer) Err() error { return nil }
+
+// syncPattern hands the VM's audio pattern, pitch, and whether the sound timer is running to the player
+func (vm *VM) syncPattern(playing bool) {
+	if vm.pattern != nil {
+		vm.pattern.set(vm.soundBuffer, vm.pitch, playing && !vm.muted)
+	}
+}
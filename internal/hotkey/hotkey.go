@@ -0,0 +1,62 @@
+// Package hotkey names the emulator's own controls, like pausing or quick saving, which displays bind to host
+// keys alongside the CHIP-8 keypad. Displays report them as a set of Actions each time they're polled.
+package hotkey
+
+// Action is one of the emulator's controls
+type Action uint8
+
+const (
+	// Step advances past a paused sprite draw, and runs a single instruction in the step debugger
+	Step Action = iota
+
+	// Continue resumes the step debugger
+	Continue
+
+	// Pause pauses and resumes the VM
+	Pause
+
+	// Reset restarts the ROM
+	Reset
+
+	// SaveState and LoadState quick save and quick load
+	SaveState
+	LoadState
+
+	// Fullscreen toggles fullscreen
+	Fullscreen
+
+	// Screenshot saves a screenshot
+	Screenshot
+
+	// Speed shows and hides the speed overlay
+	Speed
+
+	// SpeedUp and SlowDown double and halve the clock speed
+	SpeedUp
+	SlowDown
+
+	// Mute mutes and unmutes the sound
+	Mute
+
+	// Next moves on to a playlist's next ROM
+	Next
+
+	// Rewind steps the VM back through its recent states, and FastForward runs it faster, for as long as
+	// their keys are held
+	Rewind
+	FastForward
+)
+
+// Actions is a set of Actions: the ones whose keys were pressed since a display's last poll, along with
+// Rewind and FastForward while their keys are held down
+type Actions uint32
+
+// Has reports whether a is in the set
+func (as Actions) Has(a Action) bool {
+	return as&(1<<a) != 0
+}
+
+// With returns the set with a added
+func (as Actions) With(a Action) Actions {
+	return as | 1<<a
+}
@@ -0,0 +1,31 @@
+package pixel
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ParseHexColor parses a color written as "#RRGGBB", e.g. "#00FF00". The # is optional.
+func ParseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected a hex color like #00FF00", s)
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected a hex color like #00FF00", s)
+	}
+	return color.RGBA{R: byte(rgb >> 16), G: byte(rgb >> 8), B: byte(rgb), A: 0xFF}, nil
+}
+
+// mix blends from a to b, returning a when t is 0 and b when t is 1
+func mix(a, b color.Color, t float64) color.RGBA {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	lerp := func(x, y uint32) byte {
+		return byte((float64(x) + (float64(y)-float64(x))*t) / 0x101)
+	}
+	return color.RGBA{R: lerp(ar, br), G: lerp(ag, bg), B: lerp(ab, bb), A: 0xFF}
+}
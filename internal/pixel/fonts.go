@@ -0,0 +1,133 @@
+package pixel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FontSet found in http://www.multigesture.net/articles/how-to-write-an-emulator-chip-8-interpreter
+var FontSet = [80]byte{
+	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
+	0x20, 0x60, 0x20, 0x20, 0x70, // 1
+	0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
+	0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
+	0x90, 0x90, 0xF0, 0x10, 0x10, // 4
+	0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
+	0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
+	0xF0, 0x10, 0x20, 0x40, 0x40, // 7
+	0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
+	0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
+	0xF0, 0x90, 0xF0, 0x90, 0x90, // A
+	0xE0, 0x90, 0xE0, 0x90, 0xE0, // B
+	0xF0, 0x80, 0x80, 0x80, 0xF0, // C
+	0xE0, 0x90, 0x90, 0x90, 0xE0, // D
+	0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
+	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
+}
+
+// LargeFontSet is SUPER-CHIP's 8x10 font, with XO-CHIP's A-F, that FX30 points the index register at
+var LargeFontSet = [160]byte{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
+// DefaultFont is the name of FontSet among Fonts, the font ROMs get unless another is picked
+const DefaultFont = "classic"
+
+// Fonts are the built in font sets for FX29's 0-F glyphs, by name
+var Fonts = map[string][80]byte{
+	DefaultFont: FontSet,
+
+	// The COSMAC VIP's own font, from its interpreter
+	"vip": {
+		0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
+		0x60, 0x20, 0x20, 0x20, 0x70, // 1
+		0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
+		0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
+		0xA0, 0xA0, 0xF0, 0x20, 0x20, // 4
+		0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
+		0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
+		0xF0, 0x10, 0x10, 0x10, 0x10, // 7
+		0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
+		0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
+		0xF0, 0x90, 0xF0, 0x90, 0x90, // A
+		0xF0, 0x50, 0x70, 0x50, 0xF0, // B
+		0xF0, 0x80, 0x80, 0x80, 0xF0, // C
+		0xF0, 0x50, 0x50, 0x50, 0xF0, // D
+		0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
+		0xF0, 0x80, 0xF0, 0x80, 0x80, // F
+	},
+
+	// SUPER-CHIP's narrower, rounder small font
+	"schip": {
+		0x60, 0xA0, 0xA0, 0xA0, 0xC0, // 0
+		0x40, 0xC0, 0x40, 0x40, 0xE0, // 1
+		0xC0, 0x20, 0x40, 0x80, 0xE0, // 2
+		0xC0, 0x20, 0x40, 0x20, 0xC0, // 3
+		0x20, 0xA0, 0xE0, 0x20, 0x20, // 4
+		0xE0, 0x80, 0xC0, 0x20, 0xC0, // 5
+		0x40, 0x80, 0xC0, 0xA0, 0x40, // 6
+		0xE0, 0x20, 0x60, 0x40, 0x40, // 7
+		0x40, 0xA0, 0x40, 0xA0, 0x40, // 8
+		0x40, 0xA0, 0x60, 0x20, 0x40, // 9
+		0x40, 0xA0, 0xE0, 0xA0, 0xA0, // A
+		0xC0, 0xA0, 0xC0, 0xA0, 0xC0, // B
+		0x60, 0x80, 0x80, 0x80, 0x60, // C
+		0xC0, 0xA0, 0xA0, 0xA0, 0xC0, // D
+		0xE0, 0x80, 0xC0, 0x80, 0xE0, // E
+		0xE0, 0x80, 0xC0, 0x80, 0x80, // F
+	},
+}
+
+// FontNames lists the built in font sets' names in order
+func FontNames() []string {
+	names := make([]string, 0, len(Fonts))
+	for name := range Fonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFont reads a font file: 80 bytes of 0-F glyphs laid out like FontSet, 160 bytes of large glyphs laid
+// out like LargeFontSet, or the two one after the other. Whichever part the file leaves out is nil.
+func ParseFont(data []byte) (*[80]byte, *[160]byte, error) {
+	var small *[80]byte
+	var large *[160]byte
+	switch len(data) {
+	case len(FontSet):
+		small = (*[80]byte)(data)
+	case len(LargeFontSet):
+		large = (*[160]byte)(data)
+	case len(FontSet) + len(LargeFontSet):
+		small = (*[80]byte)(data[:len(FontSet)])
+		large = (*[160]byte)(data[len(FontSet):])
+	default:
+		return nil, nil, fmt.Errorf("font is %d bytes, it should be 80 for the 0-F glyphs, 160 for the large glyphs, or 240 for both", len(data))
+	}
+	return small, large, nil
+}
+
+// LookupFont returns the built in font set with the given name
+func LookupFont(name string) (*[80]byte, error) {
+	font, ok := Fonts[name]
+	if !ok {
+		return nil, fmt.Errorf("no font named %q, try one of: %s, or the path to a font file", name, strings.Join(FontNames(), ", "))
+	}
+	return &font, nil
+}
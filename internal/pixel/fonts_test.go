@@ -0,0 +1,111 @@
+package pixel
+
+import "testing"
+
+// glyph draws the 4x5 glyph for digit g of font, one string per row, with # for on pixels and . for off
+func glyph(font [80]byte, g int) [5]string {
+	var rows [5]string
+	for row := range rows {
+		b := font[g*5+row]
+		for bit := 7; bit >= 4; bit-- {
+			if b&(1<<bit) != 0 {
+				rows[row] += "#"
+			} else {
+				rows[row] += "."
+			}
+		}
+		// Glyphs only use the high nibble, anything in the low one is a transcription error
+		if b&0x0F != 0 {
+			rows[row] += "?"
+		}
+	}
+	return rows
+}
+
+// checkGlyphs compares every glyph of font against want, a golden for each of 0-F
+func checkGlyphs(t *testing.T, font [80]byte, want [16][5]string) {
+	t.Helper()
+	for g := range want {
+		if got := glyph(font, g); got != want[g] {
+			t.Errorf("glyph %X = %q, want %q", g, got, want[g])
+		}
+	}
+}
+
+func TestFontSetGlyphs(t *testing.T) {
+	checkGlyphs(t, FontSet, [16][5]string{
+		0x0: {"####", "#..#", "#..#", "#..#", "####"},
+		0x1: {"..#.", ".##.", "..#.", "..#.", ".###"},
+		0x2: {"####", "...#", "####", "#...", "####"},
+		0x3: {"####", "...#", "####", "...#", "####"},
+		0x4: {"#..#", "#..#", "####", "...#", "...#"},
+		0x5: {"####", "#...", "####", "...#", "####"},
+		0x6: {"####", "#...", "####", "#..#", "####"},
+		0x7: {"####", "...#", "..#.", ".#..", ".#.."},
+		0x8: {"####", "#..#", "####", "#..#", "####"},
+		0x9: {"####", "#..#", "####", "...#", "####"},
+		0xA: {"####", "#..#", "####", "#..#", "#..#"},
+		0xB: {"###.", "#..#", "###.", "#..#", "###."},
+		0xC: {"####", "#...", "#...", "#...", "####"},
+		0xD: {"###.", "#..#", "#..#", "#..#", "###."},
+		0xE: {"####", "#...", "####", "#...", "####"},
+		0xF: {"####", "#...", "####", "#...", "#..."},
+	})
+}
+
+func TestFontsGlyphs(t *testing.T) {
+	want := map[string][16][5]string{
+		"vip": {
+			0x0: {"####", "#..#", "#..#", "#..#", "####"},
+			0x1: {".##.", "..#.", "..#.", "..#.", ".###"},
+			0x2: {"####", "...#", "####", "#...", "####"},
+			0x3: {"####", "...#", "####", "...#", "####"},
+			0x4: {"#.#.", "#.#.", "####", "..#.", "..#."},
+			0x5: {"####", "#...", "####", "...#", "####"},
+			0x6: {"####", "#...", "####", "#..#", "####"},
+			0x7: {"####", "...#", "...#", "...#", "...#"},
+			0x8: {"####", "#..#", "####", "#..#", "####"},
+			0x9: {"####", "#..#", "####", "...#", "####"},
+			0xA: {"####", "#..#", "####", "#..#", "#..#"},
+			0xB: {"####", ".#.#", ".###", ".#.#", "####"},
+			0xC: {"####", "#...", "#...", "#...", "####"},
+			0xD: {"####", ".#.#", ".#.#", ".#.#", "####"},
+			0xE: {"####", "#...", "####", "#...", "####"},
+			0xF: {"####", "#...", "####", "#...", "#..."},
+		},
+		"schip": {
+			0x0: {".##.", "#.#.", "#.#.", "#.#.", "##.."},
+			0x1: {".#..", "##..", ".#..", ".#..", "###."},
+			0x2: {"##..", "..#.", ".#..", "#...", "###."},
+			0x3: {"##..", "..#.", ".#..", "..#.", "##.."},
+			0x4: {"..#.", "#.#.", "###.", "..#.", "..#."},
+			0x5: {"###.", "#...", "##..", "..#.", "##.."},
+			0x6: {".#..", "#...", "##..", "#.#.", ".#.."},
+			0x7: {"###.", "..#.", ".##.", ".#..", ".#.."},
+			0x8: {".#..", "#.#.", ".#..", "#.#.", ".#.."},
+			0x9: {".#..", "#.#.", ".##.", "..#.", ".#.."},
+			0xA: {".#..", "#.#.", "###.", "#.#.", "#.#."},
+			0xB: {"##..", "#.#.", "##..", "#.#.", "##.."},
+			0xC: {".##.", "#...", "#...", "#...", ".##."},
+			0xD: {"##..", "#.#.", "#.#.", "#.#.", "##.."},
+			0xE: {"###.", "#...", "##..", "#...", "###."},
+			0xF: {"###.", "#...", "##..", "#...", "#..."},
+		},
+	}
+	for _, name := range FontNames() {
+		t.Run(name, func(t *testing.T) {
+			font := Fonts[name]
+			if name == DefaultFont {
+				if font != FontSet {
+					t.Errorf("Fonts[%q] isn't FontSet", name)
+				}
+				return
+			}
+			golden, ok := want[name]
+			if !ok {
+				t.Fatalf("no golden for font %q", name)
+			}
+			checkGlyphs(t, font, golden)
+		})
+	}
+}
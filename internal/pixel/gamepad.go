@@ -0,0 +1,52 @@
+//go:build !js
+
+package pixel
+
+import "github.com/faiface/pixel/pixelgl"
+
+// DefaultGamepadMap returns the built in mapping of CHIP-8 hex keys to gamepad buttons. The d-pad is
+// bound to 2/4/6/8, which most ROMs move with, and A to 5. A gamepad has one button too few, so F is
+// left to the keyboard.
+func DefaultGamepadMap() map[uint16]pixelgl.GamepadButton {
+	return map[uint16]pixelgl.GamepadButton{
+		0x2: pixelgl.ButtonDpadUp, 0x8: pixelgl.ButtonDpadDown,
+		0x4: pixelgl.ButtonDpadLeft, 0x6: pixelgl.ButtonDpadRight,
+		0x5: pixelgl.ButtonA, 0x0: pixelgl.ButtonB,
+		0x7: pixelgl.ButtonX, 0x9: pixelgl.ButtonY,
+		0x1: pixelgl.ButtonLeftBumper, 0xC: pixelgl.ButtonRightBumper,
+		0x3: pixelgl.ButtonLeftThumb, 0xD: pixelgl.ButtonRightThumb,
+		0xA: pixelgl.ButtonBack, 0xB: pixelgl.ButtonStart,
+		0xE: pixelgl.ButtonGuide,
+	}
+}
+
+// gamepad returns the first connected joystick, if any
+func (w *Window) gamepad() (pixelgl.Joystick, bool) {
+	for js := pixelgl.Joystick1; js <= pixelgl.JoystickLast; js++ {
+		if w.JoystickPresent(js) {
+			return js, true
+		}
+	}
+	return 0, false
+}
+
+// gamepadJustPressed reports whether the gamepad button bound to the CHIP-8 hex key was pressed since
+// the last update. It's always false with no GamepadMap or no gamepad connected.
+func (w *Window) gamepadJustPressed(key byte) bool {
+	b, ok := w.GamepadMap[uint16(key)]
+	if !ok {
+		return false
+	}
+	js, ok := w.gamepad()
+	return ok && w.JoystickJustPressed(js, b)
+}
+
+// gamepadJustReleased reports whether the gamepad button bound to the CHIP-8 hex key was released since the last update
+func (w *Window) gamepadJustReleased(key byte) bool {
+	b, ok := w.GamepadMap[uint16(key)]
+	if !ok {
+		return false
+	}
+	js, ok := w.gamepad()
+	return ok && w.JoystickJustReleased(js, b)
+}
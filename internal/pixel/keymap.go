@@ -0,0 +1,60 @@
+//go:build !js
+
+package pixel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// LoadKeyMap reads a keymap written as a JSON object of CHIP-8 hex keys to key names, e.g.
+// {"1": "1", "2": "2", "3": "3", "C": "4", "4": "Q", ...}. Key names are pixelgl's, like "Q", "Space",
+// or "KP7", matched ignoring case. All 16 hex keys must be bound, each to a different key.
+func LoadKeyMap(r io.Reader) (map[uint16]pixelgl.Button, error) {
+	var names map[string]string
+	if err := json.NewDecoder(r).Decode(&names); err != nil {
+		return nil, fmt.Errorf("error reading keymap: %v", err)
+	}
+
+	km := make(map[uint16]pixelgl.Button, 16)
+	bound := make(map[pixelgl.Button]string, 16)
+	for hex, name := range names {
+		key, err := strconv.ParseUint(hex, 16, 4)
+		if err != nil || len(hex) != 1 {
+			return nil, fmt.Errorf("invalid keymap: %q isn't a hex key 0-F", hex)
+		}
+		b, ok := ParseButton(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid keymap: unknown key name %q for %s", name, hex)
+		}
+		if other, ok := bound[b]; ok {
+			return nil, fmt.Errorf("invalid keymap: %s is bound to both %s and %s", b, other, hex)
+		}
+		bound[b] = hex
+		km[uint16(key)] = b
+	}
+	for key := uint16(0); key < 16; key++ {
+		if _, ok := km[key]; !ok {
+			return nil, fmt.Errorf("invalid keymap: no key bound to %X", key)
+		}
+	}
+	return km, nil
+}
+
+// ParseButton looks up a keyboard key by its pixelgl name, ignoring case
+func ParseButton(name string) (pixelgl.Button, bool) {
+	for b := pixelgl.Button(0); b <= pixelgl.KeyLast; b++ {
+		if b == pixelgl.KeyUnknown {
+			continue
+		}
+		if s := b.String(); s != "Invalid" && strings.EqualFold(s, name) {
+			return b, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,67 @@
+//go:build !js
+
+package pixel
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// KeypadLayout is the order of the hex keys on the original 4x4 CHIP-8 keypad
+var KeypadLayout = [4][4]uint16{
+	{0x1, 0x2, 0x3, 0xC},
+	{0x4, 0x5, 0x6, 0xD},
+	{0x7, 0x8, 0x9, 0xE},
+	{0xA, 0x0, 0xB, 0xF},
+}
+
+const (
+	keyCellSize = 64
+	keyCellGap  = 4
+)
+
+// KeypadImage renders a keymap as the CHIP-8 keypad, labeling each key with its
+// hex value and the host key bound to it
+func KeypadImage(km map[uint16]pixelgl.Button) *image.RGBA {
+	size := 4*keyCellSize + 5*keyCellGap
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colornames.Black), image.Point{}, draw.Src)
+
+	for row, keys := range KeypadLayout {
+		for col, key := range keys {
+			x := keyCellGap + col*(keyCellSize+keyCellGap)
+			y := keyCellGap + row*(keyCellSize+keyCellGap)
+			cell := image.Rect(x, y, x+keyCellSize, y+keyCellSize)
+			draw.Draw(img, cell, image.NewUniform(colornames.Dimgray), image.Point{}, draw.Src)
+
+			bound := "-"
+			if b, ok := km[key]; ok {
+				bound = b.String()
+			}
+			drawLabel(img, cell, cell.Min.Y+keyCellSize/2-4, fmt.Sprintf("%X", key), colornames.White)
+			drawLabel(img, cell, cell.Max.Y-8, bound, colornames.Lightgray)
+		}
+	}
+
+	return img
+}
+
+// drawLabel writes text horizontally centered in cell with its baseline at y
+func drawLabel(img draw.Image, cell image.Rectangle, y int, text string, c color.Color) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+	}
+	x := cell.Min.X + (cell.Dx()-d.MeasureString(text).Ceil())/2
+	d.Dot = fixed.P(x, y)
+	d.DrawString(text)
+}
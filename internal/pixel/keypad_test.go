@@ -0,0 +1,59 @@
+//go:build !js
+
+package pixel
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/colornames"
+)
+
+func TestKeypadImage(t *testing.T) {
+	img := KeypadImage(DefaultKeyMap())
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 cells of 64 pixels a side, with a 4 pixel gap around and between them
+	if got, want := decoded.Bounds(), image.Rect(0, 0, 276, 276); got != want {
+		t.Fatalf("image bounds = %v, want %v", got, want)
+	}
+
+	for row := range KeypadLayout {
+		for col, key := range KeypadLayout[row] {
+			x := keyCellGap + col*(keyCellSize+keyCellGap)
+			y := keyCellGap + row*(keyCellSize+keyCellGap)
+			cell := image.Rect(x, y, x+keyCellSize, y+keyCellSize)
+			if got := img.RGBAAt(x, y); got != colornames.Dimgray {
+				t.Errorf("key %X's cell corner is %v, want %v", key, got, colornames.Dimgray)
+			}
+			if !hasColor(img, cell, colornames.White) || !hasColor(img, cell, colornames.Lightgray) {
+				t.Errorf("key %X's cell is missing its hex value or bound key", key)
+			}
+		}
+	}
+	if got := img.RGBAAt(0, 0); got != colornames.Black {
+		t.Errorf("gap is %v, want %v", got, colornames.Black)
+	}
+}
+
+// hasColor reports whether any pixel in r of img is c
+func hasColor(img *image.RGBA, r image.Rectangle, c color.RGBA) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if img.RGBAAt(x, y) == c {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+//go:build !js
+
+package pixel
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"golang.org/x/image/colornames"
+)
+
+// memoryViewSize is the width and height of the memory viewer window
+const memoryViewSize = 512
+
+// MemoryWindow is a second window that draws the VM's memory as a square grid of bytes, each as bright
+// as its value, starting from address 0 in the top left corner. The bytes the program counter and index
+// register point at, and the calls on the stack, are picked out in color.
+type MemoryWindow struct {
+	*pixelgl.Window
+
+	// imd batches up each frame's rectangles. It's kept between frames so its buffers are reused.
+	imd *imdraw.IMDraw
+}
+
+// NewMemoryWindow opens a memory viewer window with the given title. It doesn't wait on vsync, so drawing
+// to it doesn't slow down the main window.
+func NewMemoryWindow(title string) (*MemoryWindow, error) {
+	w, err := pixelgl.NewWindow(pixelgl.WindowConfig{
+		Title:  title,
+		Bounds: pixel.R(0, 0, memoryViewSize, memoryViewSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating memory viewer window: %v", err)
+	}
+	return &MemoryWindow{Window: w, imd: imdraw.New(nil)}, nil
+}
+
+// DrawMemory draws memory, marking the 2 bytes of the instruction at pc green, the byte at i yellow, and the
+// 2NNN calls waiting on the stack, whose addresses it holds, blue
+func (w *MemoryWindow) DrawMemory(memory []byte, pc, i uint16, stack []uint16) {
+	w.Clear(colornames.Black)
+	w.imd.Clear()
+
+	// The grid is the smallest power of two columns wide that makes it square, 64x64 for 4K
+	cols := 1
+	for cols*cols < len(memory) {
+		cols *= 2
+	}
+	rows := (len(memory) + cols - 1) / cols
+	g := fitGrid(w.Bounds(), cols, rows)
+
+	// Neighboring bytes on a row with the same value are drawn as a single rectangle
+	for row := 0; row*cols < len(memory); row++ {
+		line := memory[row*cols : min((row+1)*cols, len(memory))]
+		for col := 0; col < len(line); {
+			n := 1
+			for col+n < len(line) && line[col+n] == line[col] {
+				n++
+			}
+			if line[col] != 0 {
+				w.imd.Color = color.Gray{Y: line[col]}
+				g.fill(w.imd, col, rows-1-row, n)
+			}
+			col += n
+		}
+	}
+
+	mark := func(addr uint16, n int, c color.Color) {
+		w.imd.Color = c
+		for a := int(addr); a < int(addr)+n; a++ {
+			a := a % len(memory)
+			g.fill(w.imd, a%cols, rows-1-a/cols, 1)
+		}
+	}
+	for _, ret := range stack {
+		mark(ret, 2, colornames.Dodgerblue)
+	}
+	mark(i, 1, colornames.Yellow)
+	mark(pc, 2, colornames.Limegreen)
+
+	w.imd.Draw(w)
+	w.Update()
+}
@@ -1,67 +1,81 @@
+//go:build !js
+
 package pixel
 
 import (
 	"fmt"
+	"image/color"
+	"path/filepath"
 	"time"
 
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
 	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
 )
 
 // The GetGraphics system: The chip 8 has one instruction that draws sprite to the screen.
 // Drawing is done in XOR mode and if a pixel is turned off as a result of drawing, the VF register is set.
 // This is used for collision detection.
 
-// FontSet found in http://www.multigesture.net/articles/how-to-write-an-emulator-chip-8-interpreter
-var FontSet = [80]byte{
-	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
-	0x20, 0x60, 0x20, 0x20, 0x70, // 1
-	0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
-	0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
-	0x90, 0x90, 0xF0, 0x10, 0x10, // 4
-	0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
-	0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
-	0xF0, 0x10, 0x20, 0x40, 0x40, // 7
-	0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
-	0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
-	0xF0, 0x90, 0xF0, 0x90, 0x90, // A
-	0xE0, 0x90, 0xe0, 0x90, 0xE0, // B
-	0xF0, 0x80, 0x80, 0x80, 0x80, // C
-	0xF0, 0x90, 0x90, 0x90, 0xE0, // D
-	0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
-	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
-}
+// DefaultScale is how many window pixels a CHIP-8 pixel is drawn with by default, making a 1024x512 window
+const DefaultScale = 16
 
 const (
-	winX         float64 = 64
-	winY         float64 = 32
-	screenWidth  float64 = 1024
-	screenHeight float64 = 768
+	// With fading on, how much of its brightness an unlit pixel keeps each frame, and the brightness it goes dark below
+	fadeDecay = 0.6
+	fadeFloor = 0.05
+
+	// How often a fading screen is redrawn while the VM isn't presenting frames
+	fadeInterval = time.Second / 60
 )
 
-// Window embeds a pixelgl window, holds a keymapping of hex -> pixelgl.Button,
-// and an array of tickers for keeping tracking of keys down
+// Window embeds a pixelgl window and holds a keymapping of hex -> pixelgl.Button
 type Window struct {
 	*pixelgl.Window
-	KeyMap   map[uint16]pixelgl.Button
-	KeysDown [16]*time.Ticker
+	KeyMap map[uint16]pixelgl.Button
+
+	// GamepadMap binds hex keys to the buttons of the first connected gamepad. Nil reads the keyboard only.
+	GamepadMap map[uint16]pixelgl.GamepadButton
+
+	// Colors lit pixels and the background are drawn in, white on black by default
+	Foreground, Background color.Color
+
+	// Fade turns pixels off gradually over a few frames, like a phosphor screen, to smooth out the
+	// flicker of sprites being erased and redrawn every frame
+	Fade bool
+
+	// PixelGap leaves a gap between pixels, as a fraction of a pixel's width from 0 to 1, so the screen
+	// shows the grid of a real display's cells. Zero draws neighboring pixels as one solid block.
+	PixelGap float64
+
+	// The window's size when it isn't fullscreen
+	width, height float64
+
+	// imd batches up each frame's rectangles. It's kept between frames so its buffers are reused.
+	imd *imdraw.IMDraw
+
+	// With fading on, how lit each pixel is from 0 to 1, and the last frame drawn so it can keep fading
+	// when the VM doesn't present a new one
+	brightness []float64
+	lastGfx    []byte
+	lastCols   int
+	lastDraw   time.Time
+
+	// overlay is text drawn over the screen until overlayUntil
+	overlay      *text.Text
+	overlayUntil time.Time
+
+	// speed is the frames and instructions per second drawn in the top left corner, nil when hidden
+	speed *text.Text
 }
 
-// NewWindow handles creating a new pixelgl window config, initializing the window,
-// and returning a pointer a Window with an embedded *pixelgl.Window
-func NewWindow() (*Window, error) {
-	cfg := pixelgl.WindowConfig{
-		Title:  "chippy",
-		Bounds: pixel.R(0, 0, 1024, 768),
-		VSync:  true,
-	}
-	w, err := pixelgl.NewWindow(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("error creating new window: %v", err)
-	}
-	km := map[uint16]pixelgl.Button{
+// DefaultKeyMap returns the built in mapping of CHIP-8 hex keys to the left side of a QWERTY keyboard
+func DefaultKeyMap() map[uint16]pixelgl.Button {
+	return map[uint16]pixelgl.Button{
 		0x1: pixelgl.Key1, 0x2: pixelgl.Key2,
 		0x3: pixelgl.Key3, 0xC: pixelgl.Key4,
 		0x4: pixelgl.KeyQ, 0x5: pixelgl.KeyW,
@@ -71,33 +85,291 @@ func NewWindow() (*Window, error) {
 		0xA: pixelgl.KeyZ, 0x0: pixelgl.KeyX,
 		0xB: pixelgl.KeyC, 0xF: pixelgl.KeyV,
 	}
+}
+
+// WindowTitle builds a window title naming the ROM at romPath, e.g. "chippy — PONG.ch8"
+func WindowTitle(romPath string) string {
+	name := filepath.Base(romPath)
+	if romPath == "" || name == "." || name == string(filepath.Separator) {
+		return "chippy"
+	}
+	return "chippy — " + name
+}
+
+// NewWindow handles creating a new pixelgl window config, initializing the window,
+// and returning a pointer a Window with an embedded *pixelgl.Window. The window is sized
+// to draw each pixel of the 64x32 screen as a scale x scale square, or covers the primary
+// monitor when fullscreen is set.
+func NewWindow(title string, scale int, fullscreen bool) (*Window, error) {
+	width, height := float64(64*scale), float64(32*scale)
+	cfg := pixelgl.WindowConfig{
+		Title:  title,
+		Bounds: pixel.R(0, 0, width, height),
+		VSync:  true,
+	}
+	if m := pixelgl.PrimaryMonitor(); fullscreen && m != nil {
+		cfg.Monitor = m
+		cfg.Bounds = monitorBounds(m)
+	}
+	w, err := pixelgl.NewWindow(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating new window: %v", err)
+	}
 	return &Window{
-		Window:   w,
-		KeyMap:   km,
-		KeysDown: [16]*time.Ticker{},
+		Window:     w,
+		KeyMap:     DefaultKeyMap(),
+		Foreground: colornames.White,
+		Background: colornames.Black,
+		width:      width,
+		height:     height,
+		imd:        imdraw.New(nil),
 	}, nil
 }
 
-// DrawGraphics clears the window and draws a new one based on what pixels in the VM's gfx are turned on
-func (w *Window) DrawGraphics(gfx [64 * 32]byte) {
-	w.Clear(colornames.Black)
-	imDraw := imdraw.New(nil)
-	imDraw.Color = pixel.RGB(1, 1, 1)
-	width, height := screenWidth/winX, screenHeight/winY
+// KeyJustPressed reports whether the key or gamepad button bound to the CHIP-8 hex key was pressed since the last update
+func (w *Window) KeyJustPressed(key byte) bool {
+	b, ok := w.KeyMap[uint16(key)]
+	return ok && w.JustPressed(b) || w.gamepadJustPressed(key)
+}
+
+// KeyJustReleased reports whether the key or gamepad button bound to the CHIP-8 hex key was released since the last update
+func (w *Window) KeyJustReleased(key byte) bool {
+	b, ok := w.KeyMap[uint16(key)]
+	return ok && w.JustReleased(b) || w.gamepadJustReleased(key)
+}
+
+// ShowOverlay draws msg along the bottom of the window for the next d
+func (w *Window) ShowOverlay(msg string, d time.Duration) {
+	w.overlay = text.New(pixel.V(8, 8), text.NewAtlas(basicfont.Face7x13, text.ASCII))
+	w.overlay.Color = colornames.Yellow
+	fmt.Fprint(w.overlay, msg)
+	w.overlayUntil = time.Now().Add(d)
+}
+
+// ShowSpeed draws text in the top left corner of the window from the next frame on, or stops drawing it when empty
+func (w *Window) ShowSpeed(msg string) {
+	if msg == "" {
+		w.speed = nil
+		return
+	}
+	if w.speed == nil {
+		w.speed = text.New(pixel.ZV, text.NewAtlas(basicfont.Face7x13, text.ASCII))
+		w.speed.Color = colornames.Yellow
+	}
+	w.speed.Clear()
+	fmt.Fprint(w.speed, msg)
+}
+
+// DrawGraphics clears the window and draws a new one based on what pixels in the VM's gfx are turned on,
+// tinting the pixels the last sprite drew green and the collided ones red. gfx holds cols pixels a row, so
+// the cells are scaled to fill the window at either 64x32 or SUPER-CHIP's 128x64.
+func (w *Window) DrawGraphics(gfx []byte, cols int, collided, drawn []uint16) {
+	w.Clear(w.Background)
+	if w.Fade {
+		w.fade(gfx, cols)
+	}
+	w.batch(gfx, cols, w.grid(cols, len(gfx)/cols), collided, drawn)
+
+	w.imd.Draw(w)
+	if w.overlay != nil && time.Now().Before(w.overlayUntil) {
+		w.overlay.Draw(w, pixel.IM.Scaled(pixel.ZV, 2))
+	}
+	if w.speed != nil {
+		top := w.Bounds().H() - 8 - 2*w.speed.LineHeight
+		w.speed.Draw(w, pixel.IM.Scaled(pixel.ZV, 2).Moved(pixel.V(16, top)))
+	}
+	w.Update()
+}
+
+// batch fills imd with the rectangles of a frame laid out on g, its lit pixels and then the tinted ones
+func (w *Window) batch(gfx []byte, cols int, g grid, collided, drawn []uint16) {
+	w.imd.Clear()
+	rows := len(gfx) / cols
 
-	for i := 0; i < 64; i++ {
-		for j := 0; j < 32; j++ {
-			// If the gfx byte in question is turned off,
-			// continue and skip drawing the rectangle
-			if gfx[(31-j)*64+i] == 0 {
-				continue
+	// Neighboring pixels on a row that are lit the same are drawn as a single rectangle
+	for j := 0; j < rows; j++ {
+		row := (rows - 1 - j) * cols
+		for i := 0; i < cols; {
+			level, n := w.level(gfx, row+i), 1
+			for i+n < cols && w.level(gfx, row+i+n) == level {
+				n++
 			}
-			imDraw.Push(pixel.V(width*float64(i), height*float64(j)))
-			imDraw.Push(pixel.V(width*float64(i)+width, height*float64(j)+height))
-			imDraw.Rectangle(0)
+			if level > 0 {
+				w.imd.Color = w.Foreground
+				if level < 1 {
+					w.imd.Color = mix(w.Background, w.Foreground, level)
+				}
+				g.fill(w.imd, i, j, n)
+			}
+			i += n
 		}
 	}
 
-	imDraw.Draw(w)
-	w.Update()
+	tintPixels(w.imd, g, cols, rows, drawn, colornames.Limegreen)
+	tintPixels(w.imd, g, cols, rows, collided, colornames.Red)
+}
+
+// level is how lit the pixel at ind is, from 0 for off to 1 for fully on. Only fading pixels are in between.
+func (w *Window) level(gfx []byte, ind int) float64 {
+	switch {
+	case w.Fade:
+		return w.brightness[ind]
+	case gfx[ind] != 0:
+		return 1
+	}
+	return 0
+}
+
+// fade lights the pixels on in gfx and dims the rest a step, remembering the frame so UpdateInput can keep
+// it fading. The brightness starts over when the resolution changes.
+func (w *Window) fade(gfx []byte, cols int) {
+	if len(w.brightness) != len(gfx) {
+		w.brightness = make([]float64, len(gfx))
+	}
+	for i, p := range gfx {
+		switch {
+		case p != 0:
+			w.brightness[i] = 1
+		case w.brightness[i]*fadeDecay < fadeFloor:
+			w.brightness[i] = 0
+		default:
+			w.brightness[i] *= fadeDecay
+		}
+	}
+	w.lastGfx = append(w.lastGfx[:0], gfx...)
+	w.lastCols = cols
+	w.lastDraw = time.Now()
+}
+
+// fading reports whether any unlit pixel is still dimming
+func (w *Window) fading() bool {
+	for i, b := range w.brightness {
+		if b > 0 && w.lastGfx[i] == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateInput polls for input. With fading on, it redraws the last frame instead while pixels are still
+// dimming, since the VM only presents frames when the screen changes.
+func (w *Window) UpdateInput() {
+	if w.Fade && time.Since(w.lastDraw) >= fadeInterval && w.fading() {
+		w.DrawGraphics(w.lastGfx, w.lastCols, nil, nil)
+		return
+	}
+	w.Window.UpdateInput()
+}
+
+// tintPixels fills the CHIP-8 pixels at the given gfx indices with c
+func tintPixels(imDraw *imdraw.IMDraw, g grid, cols, rows int, indices []uint16, c color.Color) {
+	imDraw.Color = c
+	for _, ind := range indices {
+		g.fill(imDraw, int(ind)%cols, rows-1-int(ind)/cols, 1)
+	}
+}
+
+// grid is where the CHIP-8 screen's square cells are drawn: the bottom left corner of the screen, the side
+// of a cell, and the fraction of it left as a gap between cells
+type grid struct {
+	origin pixel.Vec
+	cell   float64
+	gap    float64
+}
+
+// grid fits a cols x rows screen in the window's current bounds with the largest cells that keep its
+// aspect ratio, centered, so it fills as much of a fullscreen monitor as it can
+func (w *Window) grid(cols, rows int) grid {
+	g := fitGrid(w.Bounds(), cols, rows)
+	g.gap = w.PixelGap
+	return g
+}
+
+// fitGrid centers the largest square cells that fit a cols x rows grid in bounds
+func fitGrid(bounds pixel.Rect, cols, rows int) grid {
+	width, height := bounds.W(), bounds.H()
+	cell := min(width/float64(cols), height/float64(rows))
+	return grid{
+		origin: pixel.V((width-cell*float64(cols))/2, (height-cell*float64(rows))/2),
+		cell:   cell,
+	}
+}
+
+// fill draws n cells along the row from column i of row j, counting rows up from the bottom. With a gap
+// each cell is drawn on its own, shrunk by half the gap on every side.
+func (g grid) fill(imDraw *imdraw.IMDraw, i, j, n int) {
+	corner := g.origin.Add(pixel.V(g.cell*float64(i), g.cell*float64(j)))
+	if g.gap == 0 {
+		imDraw.Push(corner, corner.Add(pixel.V(g.cell*float64(n), g.cell)))
+		imDraw.Rectangle(0)
+		return
+	}
+	inset, side := g.cell*g.gap/2, g.cell*(1-g.gap)
+	for k := 0; k < n; k++ {
+		lit := corner.Add(pixel.V(g.cell*float64(k)+inset, inset))
+		imDraw.Push(lit, lit.Add(pixel.V(side, side)))
+		imDraw.Rectangle(0)
+	}
+}
+
+// The window's keys for the emulator's own controls, which act when pressed, and the ones that act while held
+var (
+	hotkeys = map[pixelgl.Button]hotkey.Action{
+		pixelgl.KeySpace:    hotkey.Step,
+		pixelgl.KeyEnter:    hotkey.Continue,
+		pixelgl.KeyP:        hotkey.Pause,
+		pixelgl.KeyF1:       hotkey.Reset,
+		pixelgl.KeyF5:       hotkey.SaveState,
+		pixelgl.KeyF9:       hotkey.LoadState,
+		pixelgl.KeyF11:      hotkey.Fullscreen,
+		pixelgl.KeyF12:      hotkey.Screenshot,
+		pixelgl.KeyF3:       hotkey.Speed,
+		pixelgl.KeyPageUp:   hotkey.SpeedUp,
+		pixelgl.KeyPageDown: hotkey.SlowDown,
+		pixelgl.KeyM:        hotkey.Mute,
+		pixelgl.KeyF2:       hotkey.Next,
+	}
+	heldHotkeys = map[pixelgl.Button]hotkey.Action{
+		pixelgl.KeyBackspace: hotkey.Rewind,
+		pixelgl.KeyTab:       hotkey.FastForward,
+	}
+)
+
+// Hotkeys reports the controls whose keys were pressed since the last update, and rewinding and
+// fast-forwarding while backspace and tab are held
+func (w *Window) Hotkeys() hotkey.Actions {
+	var keys hotkey.Actions
+	for b, a := range hotkeys {
+		if w.JustPressed(b) {
+			keys = keys.With(a)
+		}
+	}
+	for b, a := range heldHotkeys {
+		if w.Pressed(b) {
+			keys = keys.With(a)
+		}
+	}
+	return keys
+}
+
+// ToggleFullscreen switches between covering the primary monitor and the window's original size.
+// pixelgl sizes the window from its bounds when the monitor changes, so those are set first.
+func (w *Window) ToggleFullscreen() {
+	if w.Monitor() != nil {
+		w.SetBounds(pixel.R(0, 0, w.width, w.height))
+		w.SetMonitor(nil)
+		return
+	}
+	m := pixelgl.PrimaryMonitor()
+	if m == nil {
+		return
+	}
+	w.SetBounds(monitorBounds(m))
+	w.SetMonitor(m)
+}
+
+// monitorBounds is a window bounds covering all of m
+func monitorBounds(m *pixelgl.Monitor) pixel.Rect {
+	width, height := m.Size()
+	return pixel.R(0, 0, width, height)
 }
@@ -0,0 +1,83 @@
+//go:build !js
+
+package pixel
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"golang.org/x/image/colornames"
+)
+
+// benchFrames are screens to draw: sprites scattered like a game's, and a screen full of solid rows
+func benchFrames() map[string]struct {
+	gfx  []byte
+	cols int
+} {
+	rng := rand.New(rand.NewSource(1))
+	sprites := func(cols, rows int) []byte {
+		gfx := make([]byte, cols*rows)
+		for range cols * rows / 64 {
+			x, y := rng.Intn(cols-8), rng.Intn(rows-5)
+			for r := range 5 {
+				row := byte(rng.Intn(256))
+				for b := range 8 {
+					gfx[(y+r)*cols+x+b] ^= row >> (7 - b) & 1
+				}
+			}
+		}
+		return gfx
+	}
+	full := make([]byte, 64*32)
+	for i := range full {
+		full[i] = 1
+	}
+	return map[string]struct {
+		gfx  []byte
+		cols int
+	}{
+		"sprites":       {sprites(64, 32), 64},
+		"full":          {full, 64},
+		"hires-sprites": {sprites(128, 64), 128},
+	}
+}
+
+// BenchmarkDrawGraphics times building a frame's rectangles and drawing them, into a batch rather than a
+// window since there's no GL context to draw to in tests
+func BenchmarkDrawGraphics(b *testing.B) {
+	for name, f := range benchFrames() {
+		b.Run(name, func(b *testing.B) {
+			w := &Window{Foreground: colornames.White, Background: colornames.Black, imd: imdraw.New(nil)}
+			g := fitGrid(pixel.R(0, 0, 64*DefaultScale, 32*DefaultScale), f.cols, len(f.gfx)/f.cols)
+			target := pixel.NewBatch(&pixel.TrianglesData{}, nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				target.Clear()
+				w.batch(f.gfx, f.cols, g, nil, nil)
+				w.imd.Draw(target)
+			}
+		})
+	}
+}
+
+func TestWindowTitle(t *testing.T) {
+	tests := []struct {
+		romPath string
+		want    string
+	}{
+		{"roms/PONG.ch8", "chippy — PONG.ch8"},
+		{"/home/me/games/tetris.ch8", "chippy — tetris.ch8"},
+		{"invaders.ch8", "chippy — invaders.ch8"},
+		{"collection.zip", "chippy — collection.zip"},
+		{"", "chippy"},
+		{"/", "chippy"},
+	}
+	for _, tt := range tests {
+		if got := WindowTitle(tt.romPath); got != tt.want {
+			t.Errorf("WindowTitle(%q) = %q, want %q", tt.romPath, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package romdb knows about well known CHIP-8 ROMs, recognizing them by the SHA-1 of their contents
+package romdb
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// Control is one thing a ROM's keys do, e.g. hex keys 4 and 6 moving the player
+type Control struct {
+	Keys   []byte
+	Action string
+}
+
+// Entry is what the database knows about a ROM
+type Entry struct {
+	Title string
+
+	// Controls is empty for ROMs that take no input
+	Controls []Control
+}
+
+// known maps the SHA-1 of a ROM to its entry
+var known = map[string]Entry{
+	"a82ca5c53e1dcedfab4f65efef02229145771b7d": {Title: "CHIP-8 Logo"},
+	"1ba58656810b67fd131eb9af3e3987863bf26c90": {Title: "IBM Logo"},
+	"507e7dc6783565071dfe4b72154af431d4466958": {Title: "Particle Demo"},
+	"f100197f0f2f05b4f3c8c31ab9c2c3930d3e9571": {
+		Title: "Space Invaders",
+		Controls: []Control{
+			{Keys: []byte{0x4, 0x6}, Action: "move"},
+			{Keys: []byte{0x5}, Action: "fire"},
+		},
+	},
+	"a60611339661e3ab2d8af024ad1da5880a6f8665": {
+		Title: "Pong",
+		Controls: []Control{
+			{Keys: []byte{0x1, 0x4}, Action: "move the left paddle"},
+			{Keys: []byte{0xC, 0xD}, Action: "move the right paddle"},
+		},
+	},
+	"5f518084744bf3cb8733f6e5454dfd1634320563": {
+		Title: "Tetris",
+		Controls: []Control{
+			{Keys: []byte{0x5, 0x6}, Action: "move"},
+			{Keys: []byte{0x4}, Action: "rotate"},
+			{Keys: []byte{0x7}, Action: "drop"},
+		},
+	},
+}
+
+// Hash returns the hex SHA-1 of a ROM, the key the database is indexed by
+func Hash(rom []byte) string {
+	sum := sha1.Sum(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup finds a ROM in the database by its contents
+func Lookup(rom []byte) (Entry, bool) {
+	return LookupHash(Hash(rom))
+}
+
+// LookupHash finds a ROM in the database by its hex SHA-1
+func LookupHash(hash string) (Entry, bool) {
+	e, ok := known[strings.ToLower(hash)]
+	return e, ok
+}
+
+// Hints describes the ROM's controls in terms of the keys the player presses, e.g. "Q/E to move, W to fire".
+// keyName names the host key bound to a CHIP-8 hex key. Hints is empty for ROMs that take no input.
+func (e Entry) Hints(keyName func(key byte) string) string {
+	hints := make([]string, 0, len(e.Controls))
+	for _, c := range e.Controls {
+		names := make([]string, 0, len(c.Keys))
+		for _, k := range c.Keys {
+			names = append(names, keyName(k))
+		}
+		hints = append(hints, strings.Join(names, "/")+" to "+c.Action)
+	}
+	return strings.Join(hints, ", ")
+}
@@ -0,0 +1,46 @@
+package romdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bradford-hamilton/chippy/roms"
+)
+
+func TestHints(t *testing.T) {
+	// Pong's SHA-1, in upper case to check lookups ignore it
+	e, ok := LookupHash("A60611339661E3AB2D8AF024AD1DA5880A6F8665")
+	if !ok {
+		t.Fatal("Pong isn't in the database")
+	}
+	keyName := func(key byte) string { return fmt.Sprintf("K%X", key) }
+	if got, want := e.Hints(keyName), "K1/K4 to move the left paddle, KC/KD to move the right paddle"; got != want {
+		t.Errorf("Hints = %q, want %q", got, want)
+	}
+
+	e, _ = LookupHash("1ba58656810b67fd131eb9af3e3987863bf26c90")
+	if got := e.Hints(keyName); got != "" {
+		t.Errorf("Hints for the IBM logo = %q, want none", got)
+	}
+	if _, ok := Lookup([]byte{0x12, 0x00}); ok {
+		t.Error("found an unknown ROM")
+	}
+}
+
+// Every bundled ROM is in the database under its title
+func TestBundledROMs(t *testing.T) {
+	for _, name := range roms.Names() {
+		rom, _, err := roms.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, ok := Lookup(rom)
+		if !ok {
+			t.Errorf("%s isn't in the database", name)
+			continue
+		}
+		if e.Title == "" {
+			t.Errorf("%s has no title", name)
+		}
+	}
+}
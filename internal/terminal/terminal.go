@@ -0,0 +1,357 @@
+// Package terminal draws CHIP-8 screens in a terminal and reads the keypad from it, for running chippy over
+// SSH or anywhere else without a GUI. Screens are drawn with half block characters, two pixels to a character
+// cell, so the 64x32 screen takes 64x16 cells and SUPER-CHIP's 128x64 takes 128x32.
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+	"golang.org/x/term"
+)
+
+const (
+	// holdTime is how long a key counts as held after the terminal last sent it. Terminals only send keys as
+	// they're typed and repeated, never when they're let go, so a key is let go once it stops repeating.
+	holdTime = 150 * time.Millisecond
+
+	// messageDuration is how long a message written to the display is shown
+	messageDuration = 3 * time.Second
+)
+
+// DefaultKeyMap maps the keys typed on the left side of a QWERTY keyboard to the CHIP-8 hex keys, like the window's
+var DefaultKeyMap = map[byte]byte{
+	'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xC,
+	'q': 0x4, 'w': 0x5, 'e': 0x6, 'r': 0xD,
+	'a': 0x7, 's': 0x8, 'd': 0x9, 'f': 0xE,
+	'z': 0xA, 'x': 0x0, 'c': 0xB, 'v': 0xF,
+}
+
+// The keys the window binds to a function key or page up and down, named after the key the terminal sends.
+// Terminals send those as escape sequences.
+const (
+	keyF1 = "\x1bOP"
+	keyF2 = "\x1bOQ"
+	keyF3 = "\x1bOR"
+	keyF5 = "\x1b[15~"
+	keyF9 = "\x1b[20~"
+
+	keyF12      = "\x1b[24~"
+	keyPageUp   = "\x1b[5~"
+	keyPageDown = "\x1b[6~"
+)
+
+// Display is a chip8.Display drawing to a terminal in raw mode. Escape or Ctrl-C closes it. The other keys
+// are the window's: P pauses, F1 resets, space steps, enter continues, Backspace rewinds, Tab fast-forwards,
+// and so on. Sprite collisions and draws aren't tinted, fullscreen does nothing, and screenshots are saved
+// like the window's.
+type Display struct {
+	in *os.File
+
+	// out is written to by the VM's goroutine drawing frames and the audio goroutine ringing the bell
+	mu  sync.Mutex
+	out *bufio.Writer
+
+	// The terminal's state before it was put into raw mode, to restore it on Close, and nil once it is
+	restore *term.State
+
+	// KeyMap maps typed keys to CHIP-8 hex keys, DefaultKeyMap unless set
+	KeyMap map[byte]byte
+
+	// input gets what's typed on the terminal, read in the background
+	input chan []byte
+
+	// The keys down as of the last poll and when each was last sent, and the ones sent since the last poll
+	held    map[string]time.Time
+	pressed map[string]bool
+	closed  bool
+
+	// released are the keys let go at the last poll
+	released map[string]bool
+
+	title        string
+	overlay      string
+	overlayUntil time.Time
+	speed        string
+
+	// buf builds each frame, so it's written to the terminal all at once
+	buf bytes.Buffer
+
+	// logged is everything written to the display, printed in full once the terminal's restored
+	logged bytes.Buffer
+}
+
+// New puts the terminal on stdin into raw mode and starts reading keys from it, drawing to stdout.
+// Close puts the terminal back the way it was.
+func New(title string) (*Display, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("the terminal backend needs a terminal on stdin")
+	}
+	restore, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the terminal: %v", err)
+	}
+	d := &Display{
+		in:       os.Stdin,
+		out:      bufio.NewWriter(os.Stdout),
+		restore:  restore,
+		KeyMap:   DefaultKeyMap,
+		input:    make(chan []byte, 64),
+		held:     make(map[string]time.Time),
+		pressed:  make(map[string]bool),
+		released: make(map[string]bool),
+		title:    title,
+	}
+	// Clear the screen and hide the cursor
+	fmt.Fprint(d.out, "\x1b[2J\x1b[?25l")
+	go d.read()
+	return d, nil
+}
+
+// Close shows the cursor again, restores the terminal, and prints what was written to the display while it
+// was open. Closing it again does nothing.
+func (d *Display) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.restore == nil {
+		return nil
+	}
+	fmt.Fprint(d.out, "\x1b[?25h\x1b[0m\r\n")
+	d.out.Flush()
+	restore := d.restore
+	d.restore = nil
+	err := term.Restore(int(d.in.Fd()), restore)
+	os.Stdout.Write(d.logged.Bytes())
+	return err
+}
+
+// read sends what's typed to input until stdin is closed
+func (d *Display) read() {
+	for {
+		buf := make([]byte, 64)
+		n, err := d.in.Read(buf)
+		if n > 0 {
+			d.input <- buf[:n]
+		}
+		if err != nil {
+			close(d.input)
+			return
+		}
+	}
+}
+
+// UpdateInput takes in the keys typed since the last poll. Keys not sent again within holdTime are let go.
+func (d *Display) UpdateInput() {
+	clear(d.pressed)
+	clear(d.released)
+	now := time.Now()
+	for more := true; more; {
+		select {
+		case typed, ok := <-d.input:
+			if !ok {
+				d.closed = true
+				more = false
+				continue
+			}
+			for _, k := range splitKeys(typed) {
+				if _, down := d.held[k]; !down {
+					d.pressed[k] = true
+				}
+				d.held[k] = now
+			}
+		default:
+			more = false
+		}
+	}
+	for k, last := range d.held {
+		if now.Sub(last) > holdTime && !d.pressed[k] {
+			delete(d.held, k)
+			d.released[k] = true
+		}
+	}
+	if d.pressed["\x1b"] || d.pressed["\x03"] {
+		d.closed = true
+	}
+}
+
+// splitKeys splits what the terminal sent into keys: single characters, and the escape sequences function
+// and page keys send. Letters are lowercased so keys work the same with caps lock or shift.
+func splitKeys(typed []byte) []string {
+	var keys []string
+	for i := 0; i < len(typed); {
+		n := 1
+		if typed[i] == 0x1b && i+2 < len(typed) {
+			switch typed[i+1] {
+			case 'O':
+				n = 3
+			case '[':
+				// CSI sequences end in a letter or ~
+				for n = 2; i+n < len(typed); n++ {
+					if c := typed[i+n]; c == '~' || c >= 'A' && c <= 'Z' {
+						n++
+						break
+					}
+				}
+			}
+		}
+		k := string(typed[i : i+n])
+		if n == 1 {
+			k = strings.ToLower(k)
+		}
+		keys = append(keys, k)
+		i += n
+	}
+	return keys
+}
+
+// DrawGraphics draws the frame with half blocks, then the title and any overlay and speed text under it,
+// and polls for input
+func (d *Display) DrawGraphics(gfx []byte, cols int, collided, drawn []uint16) {
+	rows := len(gfx) / cols
+	d.buf.Reset()
+	d.buf.WriteString("\x1b[H")
+	for y := 0; y < rows; y += 2 {
+		for x := 0; x < cols; x++ {
+			top := gfx[y*cols+x] != 0
+			bottom := y+1 < rows && gfx[(y+1)*cols+x] != 0
+			switch {
+			case top && bottom:
+				d.buf.WriteString("█")
+			case top:
+				d.buf.WriteString("▀")
+			case bottom:
+				d.buf.WriteString("▄")
+			default:
+				d.buf.WriteByte(' ')
+			}
+		}
+		d.buf.WriteString("\x1b[K\r\n")
+	}
+
+	status := d.title
+	if d.speed != "" {
+		status += "  " + d.speed
+	}
+	if d.overlay != "" && time.Now().Before(d.overlayUntil) {
+		status += "  " + d.overlay
+	}
+	d.buf.WriteString(status)
+	// Clear the rest of the screen, in case the last frame was taller
+	d.buf.WriteString("\x1b[J")
+
+	d.mu.Lock()
+	d.out.Write(d.buf.Bytes())
+	d.out.Flush()
+	d.mu.Unlock()
+	d.UpdateInput()
+}
+
+// Write shows the last line of p under the screen for a few seconds, so the VM's messages can be logged
+// to the display rather than scrolling the screen away. All of p is printed once Close restores the terminal,
+// and straight to stdout after.
+func (d *Display) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.restore == nil {
+		return os.Stdout.Write(p)
+	}
+	d.logged.Write(p)
+	lines := strings.Split(strings.TrimSpace(string(p)), "\n")
+	d.ShowOverlay(lines[len(lines)-1], messageDuration)
+	return len(p), nil
+}
+
+// Bell rings the terminal's bell, in place of the beep
+func (d *Display) Bell() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.out.WriteByte('\a')
+	d.out.Flush()
+}
+
+// Closed reports whether escape or Ctrl-C was pressed, or stdin closed
+func (d *Display) Closed() bool { return d.closed }
+
+// SetClosed takes back the display being closed, or closes it
+func (d *Display) SetClosed(closed bool) { d.closed = closed }
+
+// SetTitle sets the title shown under the screen
+func (d *Display) SetTitle(title string) { d.title = title }
+
+// KeyJustPressed reports whether the key mapped to the CHIP-8 hex key was pressed since the last poll
+func (d *Display) KeyJustPressed(key byte) bool {
+	for k, hex := range d.KeyMap {
+		if hex == key && d.pressed[string(k)] {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyJustReleased reports whether the key mapped to the CHIP-8 hex key was let go at the last poll
+func (d *Display) KeyJustReleased(key byte) bool {
+	for k, hex := range d.KeyMap {
+		if hex == key && d.released[string(k)] {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowOverlay shows msg under the screen for d
+func (d *Display) ShowOverlay(msg string, dur time.Duration) {
+	d.overlay, d.overlayUntil = strings.ReplaceAll(msg, "\n", "  "), time.Now().Add(dur)
+}
+
+// ShowSpeed shows text under the screen, or stops showing it when empty
+func (d *Display) ShowSpeed(text string) { d.speed = text }
+
+// ToggleFullscreen does nothing, since a terminal has no fullscreen to toggle
+func (d *Display) ToggleFullscreen() {}
+
+// The keys for the emulator's own controls, by what the terminal sends for them, which act when pressed,
+// and the ones that act while held. Backspace is sent as DEL.
+var (
+	hotkeys = map[string]hotkey.Action{
+		" ":         hotkey.Step,
+		"\r":        hotkey.Continue,
+		"p":         hotkey.Pause,
+		keyF1:       hotkey.Reset,
+		keyF5:       hotkey.SaveState,
+		keyF9:       hotkey.LoadState,
+		keyF12:      hotkey.Screenshot,
+		keyF3:       hotkey.Speed,
+		keyPageUp:   hotkey.SpeedUp,
+		keyPageDown: hotkey.SlowDown,
+		"m":         hotkey.Mute,
+		keyF2:       hotkey.Next,
+	}
+	heldHotkeys = map[string]hotkey.Action{
+		"\x7f": hotkey.Rewind,
+		"\t":   hotkey.FastForward,
+	}
+)
+
+// Hotkeys reports the controls whose keys were pressed since the last poll, and rewinding and
+// fast-forwarding while Backspace and Tab are held
+func (d *Display) Hotkeys() hotkey.Actions {
+	var keys hotkey.Actions
+	for k, a := range hotkeys {
+		if d.pressed[k] {
+			keys = keys.With(a)
+		}
+	}
+	for k, a := range heldHotkeys {
+		if _, ok := d.held[k]; ok {
+			keys = keys.With(a)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,316 @@
+//go:build js && wasm
+
+// Package web draws CHIP-8 screens to an HTML canvas and reads the keypad from the page's keyboard events,
+// for running chippy in a browser as WebAssembly. See web/index.html for a page that runs it.
+package web
+
+import (
+	"image/color"
+	"strings"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/bradford-hamilton/chippy/internal/hotkey"
+)
+
+const (
+	// messageDuration is how long a message written to the display is shown
+	messageDuration = 3 * time.Second
+
+	// The beep's pitch and how long it plays, since the bell doesn't know how long the sound timer runs
+	beepHz  = 440
+	beepDur = 0.1
+)
+
+// DefaultKeyMap maps the keys on the left side of the keyboard to the CHIP-8 hex keys, like the window's.
+// Keys are named by their KeyboardEvent.code, so they're in the same place whatever the keyboard layout.
+var DefaultKeyMap = map[string]byte{
+	"Digit1": 0x1, "Digit2": 0x2, "Digit3": 0x3, "Digit4": 0xC,
+	"KeyQ": 0x4, "KeyW": 0x5, "KeyE": 0x6, "KeyR": 0xD,
+	"KeyA": 0x7, "KeyS": 0x8, "KeyD": 0x9, "KeyF": 0xE,
+	"KeyZ": 0xA, "KeyX": 0x0, "KeyC": 0xB, "KeyV": 0xF,
+}
+
+// Display is a chip8.Display drawing to a canvas, one canvas pixel to a CHIP-8 pixel, so the page scales
+// it up with CSS. The keys are the window's: P pauses, F1 resets, space steps, enter continues, Backspace
+// rewinds, Tab fast-forwards, and so on. There are no files in the browser for quick saves and screenshots,
+// and sprite collisions and draws aren't tinted.
+type Display struct {
+	canvas, ctx js.Value
+
+	// status shows the title, the speed, and any overlay under the canvas. It may be null.
+	status js.Value
+
+	// img is the ImageData each frame is drawn into, and pixels its RGBA bytes on the Go side
+	img        js.Value
+	pixels     []byte
+	cols, rows int
+
+	// KeyMap maps KeyboardEvent.codes to CHIP-8 hex keys, DefaultKeyMap unless set
+	KeyMap map[string]byte
+
+	// Foreground and Background color lit and unlit pixels, white on black unless set
+	Foreground, Background color.RGBA
+
+	// The page's keyboard listeners, removed on Close
+	onKeyDown, onKeyUp js.Func
+
+	// mu guards the key state and closed, since key events come in on the browser's event loop
+	mu sync.Mutex
+
+	// The keys held down, the ones that went down and up since the last poll, and the ones that did
+	// as of the last poll
+	held              map[string]bool
+	downs, ups        map[string]bool
+	pressed, released map[string]bool
+	closed            bool
+
+	// audio is the AudioContext the bell rings on, made on the first beep
+	audio js.Value
+
+	title        string
+	overlay      string
+	overlayUntil time.Time
+	speed        string
+}
+
+// New draws to canvas, shows the title and messages in status, which may be null, and starts listening
+// for keys on the page. Close stops listening.
+func New(canvas, status js.Value, title string) *Display {
+	d := &Display{
+		canvas:     canvas,
+		ctx:        canvas.Call("getContext", "2d"),
+		status:     status,
+		KeyMap:     DefaultKeyMap,
+		Foreground: color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+		Background: color.RGBA{A: 0xFF},
+		held:       make(map[string]bool),
+		downs:      make(map[string]bool),
+		ups:        make(map[string]bool),
+		pressed:    make(map[string]bool),
+		released:   make(map[string]bool),
+	}
+	d.onKeyDown = js.FuncOf(func(this js.Value, args []js.Value) any {
+		d.key(args[0], true)
+		return nil
+	})
+	d.onKeyUp = js.FuncOf(func(this js.Value, args []js.Value) any {
+		d.key(args[0], false)
+		return nil
+	})
+	doc := js.Global().Get("document")
+	doc.Call("addEventListener", "keydown", d.onKeyDown)
+	doc.Call("addEventListener", "keyup", d.onKeyUp)
+	d.SetTitle(title)
+	return d
+}
+
+// Close stops listening for keys and closes the display, so the VM drawing to it stops
+func (d *Display) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	doc := js.Global().Get("document")
+	doc.Call("removeEventListener", "keydown", d.onKeyDown)
+	doc.Call("removeEventListener", "keyup", d.onKeyUp)
+	d.onKeyDown.Release()
+	d.onKeyUp.Release()
+}
+
+// key records a key going down or up. Keys chippy uses don't scroll the page, reload it, or move the focus.
+func (d *Display) key(event js.Value, down bool) {
+	code := event.Get("code").String()
+	_, keypad := d.KeyMap[code]
+	_, pressed := hotkeys[code]
+	_, held := heldHotkeys[code]
+	if keypad || pressed || held {
+		event.Call("preventDefault")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case down && !d.held[code]:
+		d.held[code], d.downs[code] = true, true
+	case !down && d.held[code]:
+		delete(d.held, code)
+		d.ups[code] = true
+	}
+}
+
+// UpdateInput takes in the keys that went down and up since the last poll
+func (d *Display) UpdateInput() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pressed, d.downs = d.downs, d.pressed
+	d.released, d.ups = d.ups, d.released
+	clear(d.downs)
+	clear(d.ups)
+}
+
+// DrawGraphics draws the frame to the canvas, resizing it when the resolution changes, updates the status
+// text, and polls for input
+func (d *Display) DrawGraphics(gfx []byte, cols int, collided, drawn []uint16) {
+	if d.Closed() {
+		return
+	}
+	rows := len(gfx) / cols
+	if cols != d.cols || rows != d.rows {
+		d.cols, d.rows = cols, rows
+		d.canvas.Set("width", cols)
+		d.canvas.Set("height", rows)
+		d.img = d.ctx.Call("createImageData", cols, rows)
+		d.pixels = make([]byte, len(gfx)*4)
+	}
+	for i, px := range gfx {
+		c := d.Background
+		if px != 0 {
+			c = d.Foreground
+		}
+		d.pixels[i*4], d.pixels[i*4+1], d.pixels[i*4+2], d.pixels[i*4+3] = c.R, c.G, c.B, c.A
+	}
+	js.CopyBytesToJS(d.img.Get("data"), d.pixels)
+	d.ctx.Call("putImageData", d.img, 0, 0)
+	d.showStatus()
+	d.UpdateInput()
+}
+
+// showStatus shows the title, then the speed and overlay if there are any, under the canvas
+func (d *Display) showStatus() {
+	if d.status.IsNull() || d.status.IsUndefined() {
+		return
+	}
+	status := d.title
+	if d.speed != "" {
+		status += "  " + d.speed
+	}
+	if d.overlay != "" && time.Now().Before(d.overlayUntil) {
+		status += "  " + d.overlay
+	}
+	d.status.Set("textContent", status)
+}
+
+// Write shows the last line of p under the canvas for a few seconds, so the VM's messages can be logged
+// to the page
+func (d *Display) Write(p []byte) (int, error) {
+	lines := strings.Split(strings.TrimSpace(string(p)), "\n")
+	d.ShowOverlay(lines[len(lines)-1], messageDuration)
+	d.showStatus()
+	return len(p), nil
+}
+
+// Bell plays a short tone, in place of the beep. Browsers only allow sound once the page has been
+// interacted with, which loading a ROM is.
+func (d *Display) Bell() {
+	if d.audio.IsUndefined() {
+		ctor := js.Global().Get("AudioContext")
+		if ctor.IsUndefined() {
+			return
+		}
+		d.audio = ctor.New()
+	}
+	osc := d.audio.Call("createOscillator")
+	osc.Get("frequency").Set("value", beepHz)
+	osc.Call("connect", d.audio.Get("destination"))
+	now := d.audio.Get("currentTime").Float()
+	osc.Call("start", now)
+	osc.Call("stop", now+beepDur)
+}
+
+// Closed reports whether Close was called
+func (d *Display) Closed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// SetClosed does nothing, since only the page closes the display
+func (d *Display) SetClosed(closed bool) {}
+
+// SetTitle sets the title shown under the canvas and the page's title
+func (d *Display) SetTitle(title string) {
+	d.title = title
+	js.Global().Get("document").Set("title", title)
+	d.showStatus()
+}
+
+// KeyJustPressed reports whether the key mapped to the CHIP-8 hex key went down since the last poll
+func (d *Display) KeyJustPressed(key byte) bool {
+	for code, hex := range d.KeyMap {
+		if hex == key && d.pressed[code] {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyJustReleased reports whether the key mapped to the CHIP-8 hex key went up since the last poll
+func (d *Display) KeyJustReleased(key byte) bool {
+	for code, hex := range d.KeyMap {
+		if hex == key && d.released[code] {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowOverlay shows msg under the canvas for dur
+func (d *Display) ShowOverlay(msg string, dur time.Duration) {
+	d.overlay, d.overlayUntil = strings.ReplaceAll(msg, "\n", "  "), time.Now().Add(dur)
+}
+
+// ShowSpeed shows text under the canvas, or stops showing it when empty
+func (d *Display) ShowSpeed(text string) { d.speed = text }
+
+// ToggleFullscreen makes the canvas fill the screen, or leaves fullscreen
+func (d *Display) ToggleFullscreen() {
+	doc := js.Global().Get("document")
+	if el := doc.Get("fullscreenElement"); !el.IsNull() && !el.IsUndefined() {
+		doc.Call("exitFullscreen")
+		return
+	}
+	d.canvas.Call("requestFullscreen")
+}
+
+// The keys for the emulator's own controls, by their KeyboardEvent code, which act when pressed, and the ones
+// that act while held. Quick saves and screenshots have no keys, since there are no files in the browser to save to.
+var (
+	hotkeys = map[string]hotkey.Action{
+		"Space":    hotkey.Step,
+		"Enter":    hotkey.Continue,
+		"KeyP":     hotkey.Pause,
+		"F1":       hotkey.Reset,
+		"F11":      hotkey.Fullscreen,
+		"F3":       hotkey.Speed,
+		"PageUp":   hotkey.SpeedUp,
+		"PageDown": hotkey.SlowDown,
+		"KeyM":     hotkey.Mute,
+		"F2":       hotkey.Next,
+	}
+	heldHotkeys = map[string]hotkey.Action{
+		"Backspace": hotkey.Rewind,
+		"Tab":       hotkey.FastForward,
+	}
+)
+
+// Hotkeys reports the controls whose keys were pressed since the last poll, and rewinding and
+// fast-forwarding while Backspace and Tab are held
+func (d *Display) Hotkeys() hotkey.Actions {
+	var keys hotkey.Actions
+	for k, a := range hotkeys {
+		if d.pressed[k] {
+			keys = keys.With(a)
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for k, a := range heldHotkeys {
+		if d.held[k] {
+			keys = keys.With(a)
+		}
+	}
+	return keys
+}
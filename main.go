@@ -1,15 +1,13 @@
+//go:build !js
+
 package main
 
 import (
 	"github.com/bradford-hamilton/chippy/cmd"
-	"github.com/faiface/pixel/pixelgl"
 )
 
-// pixelgl needs access to the main thread
+// Commands that open a window hand the main thread to pixelgl themselves,
+// so the ones that don't can run without a display
 func main() {
-	pixelgl.Run(runChippy)
-}
-
-func runChippy() {
 	cmd.Execute()
 }
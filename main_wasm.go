@@ -0,0 +1,55 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"syscall/js"
+
+	"github.com/bradford-hamilton/chippy/internal/chip8"
+	"github.com/bradford-hamilton/chippy/internal/romdb"
+	"github.com/bradford-hamilton/chippy/internal/web"
+)
+
+// In the browser chippy runs whatever ROM the page hands to chippyLoad, on the canvas with the id "screen",
+// showing its title and messages in the element with the id "status". See web/index.html.
+func main() {
+	doc := js.Global().Get("document")
+	canvas := doc.Call("getElementById", "screen")
+	status := doc.Call("getElementById", "status")
+
+	var current *web.Display
+	js.Global().Set("chippyLoad", js.FuncOf(func(this js.Value, args []js.Value) any {
+		rom := make([]byte, args[0].Get("length").Int())
+		js.CopyBytesToGo(rom, args[0])
+		title := args[1].String()
+		if e, ok := romdb.Lookup(rom); ok {
+			title = e.Title
+		}
+
+		// Loading a ROM stops the last one, which sees its display closed
+		if current != nil {
+			current.Close()
+		}
+		current = web.New(canvas, status, title)
+		go run(rom, current)
+		return nil
+	}))
+	select {}
+}
+
+// run runs rom on d until d is closed
+func run(rom []byte, d *web.Display) {
+	vm, err := chip8.NewVM(bytes.NewReader(rom), chip8.DefaultClockSpeed, chip8.Config{
+		Display: d,
+		Logger:  chip8.TextLogger{W: d},
+	})
+	if err != nil {
+		fmt.Fprintf(d, "error creating a new chip-8 VM: %v", err)
+		return
+	}
+	go vm.RingBell(d.Bell)
+	go vm.Run()
+	<-vm.ShutdownC
+}
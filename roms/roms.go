@@ -0,0 +1,53 @@
+// Package roms bundles the public domain ROMs in this directory into the chippy binary, so there's
+// always something to run
+package roms
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed *.ch8
+var files embed.FS
+
+// ext is the extension of the bundled ROMs, left off their names
+const ext = ".ch8"
+
+// Names lists the bundled ROMs in order, by file name without the extension, e.g. "ibm_logo"
+func Names() []string {
+	entries, _ := fs.ReadDir(files, ".")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ext))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open returns the bundled ROM with the given name, which can be shortened to any prefix that only one
+// ROM's name starts with, e.g. "ibm" for "ibm_logo". The name returned is the ROM's file name.
+func Open(name string) ([]byte, string, error) {
+	var matches []string
+	for _, n := range Names() {
+		if n == name {
+			matches = []string{n}
+			break
+		}
+		if strings.HasPrefix(n, name) {
+			matches = append(matches, n)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, "", fmt.Errorf("no demo named %q, try one of: %s", name, strings.Join(Names(), ", "))
+	case 1:
+		file := matches[0] + ext
+		rom, err := files.ReadFile(file)
+		return rom, file, err
+	default:
+		return nil, "", fmt.Errorf("demo %q is ambiguous, it could be any of: %s", name, strings.Join(matches, ", "))
+	}
+}